@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"alexis-art-backend/db"
+)
+
+const (
+	// apiKeyPrefix tells API keys apart from ADMIN_TOKEN and access tokens.
+	apiKeyPrefix        = "abk_"
+	maxAPIKeyNameLength = 100
+	maxAPIKeyTTLDays    = 365
+)
+
+// API key scopes and the permission each one grants.
+const (
+	scopeReadDrafts    = "read:drafts"
+	scopeWriteMedia    = "write:media"
+	scopeWriteMetadata = "write:metadata"
+)
+
+var apiKeyScopes = []string{scopeReadDrafts, scopeWriteMedia, scopeWriteMetadata}
+
+var scopePermissions = map[string]permission{
+	scopeReadDrafts:    permDraftsRead,
+	scopeWriteMedia:    permMediaUpload,
+	scopeWriteMetadata: permArtworksWrite,
+}
+
+type APIKeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"createdBy"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	LastUsedIP string     `json:"lastUsedIp,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	RevokedBy  string     `json:"revokedBy,omitempty"`
+	Active     bool       `json:"active"`
+	// The key itself; only returned when it is created.
+	Key string `json:"key,omitempty"`
+}
+
+type APIKeyListResponse struct {
+	Keys  []APIKeyResponse `json:"keys"`
+	Total int              `json:"total"`
+}
+
+type adminAPIKeyCreate struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// 0 means the key does not expire.
+	ExpiresInDays int `json:"expiresInDays"`
+}
+
+func (p *adminAPIKeyCreate) normalize() {
+	p.Name = strings.TrimSpace(p.Name)
+	scopes := make([]string, 0, len(p.Scopes))
+	for _, s := range p.Scopes {
+		if s = strings.TrimSpace(s); s != "" && !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	p.Scopes = scopes
+}
+
+func (p adminAPIKeyCreate) validate() error {
+	var errs validationErrors
+	if p.Name == "" {
+		errs.add("name", codeRequired, "is required")
+	}
+	errs.checkLength("name", p.Name, maxAPIKeyNameLength)
+	if len(p.Scopes) == 0 {
+		errs.add("scopes", codeRequired, "is required")
+	}
+	for _, s := range p.Scopes {
+		if !slices.Contains(apiKeyScopes, s) {
+			errs.add("scopes", codeInvalidValue, "must be some of "+strings.Join(apiKeyScopes, ", "))
+			break
+		}
+	}
+	if p.ExpiresInDays < 0 || p.ExpiresInDays > maxAPIKeyTTLDays {
+		errs.add("expiresInDays", codeInvalidValue, "must be between 0 (no expiry) and "+strconv.Itoa(maxAPIKeyTTLDays))
+	}
+	return errs.err()
+}
+
+// scopesPermissions lists what a set of scopes allows.
+func scopesPermissions(scopes []string) []permission {
+	perms := []permission{}
+	for _, s := range scopes {
+		if p, ok := scopePermissions[s]; ok && !slices.Contains(perms, p) {
+			perms = append(perms, p)
+		}
+	}
+	return perms
+}
+
+// authenticateAPIKey resolves an API key and records its use.
+func authenticateAPIKey(ctx context.Context, key, ip string) (*adminIdentity, error) {
+	if pgPool == nil {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	row, err := db.UseAPIKey(ctx, pgPool, hashToken(key), ip)
+	if err != nil || row == nil {
+		return nil, err
+	}
+	return &adminIdentity{APIKeyID: row.ID, Name: row.Name, Scopes: row.Scopes}, nil
+}
+
+func apiKeyResponse(k db.APIKeyRow) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		CreatedBy:  k.CreatedBy,
+		CreatedAt:  k.CreatedAt,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		LastUsedIP: k.LastUsedIP,
+		RevokedAt:  k.RevokedAt,
+		RevokedBy:  k.RevokedBy,
+		Active:     k.RevokedAt == nil && (k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt)),
+	}
+}
+
+func adminListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	rows, err := db.ListAPIKeys(ctx, pgPool)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list API keys")
+		return
+	}
+	keys := make([]APIKeyResponse, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, apiKeyResponse(row))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIKeyListResponse{Keys: keys, Total: len(keys)})
+}
+
+// adminCreateAPIKey issues a key. The response is the only time the key is
+// shown.
+func adminCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	var payload adminAPIKeyCreate
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	payload.normalize()
+	if err := payload.validate(); err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+
+	key := apiKeyPrefix + randomToken(32)
+	row := db.APIKeyRow{
+		ID:        generateArtworkID(),
+		Name:      payload.Name,
+		Prefix:    key[:len(apiKeyPrefix)+6],
+		KeyHash:   hashToken(key),
+		Scopes:    payload.Scopes,
+		CreatedBy: actorFromContext(r.Context()),
+	}
+	if payload.ExpiresInDays > 0 {
+		expires := time.Now().Add(time.Duration(payload.ExpiresInDays) * 24 * time.Hour).Truncate(time.Second)
+		row.ExpiresAt = &expires
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	saved, err := db.InsertAPIKey(ctx, pgPool, row)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+	resp := apiKeyResponse(*saved)
+	resp.Key = key
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func adminRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	keyID := mux.Vars(r)["keyId"]
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	revoked, err := db.RevokeAPIKey(ctx, pgPool, keyID, actorFromContext(r.Context()))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke API key")
+		return
+	}
+	if !revoked {
+		respondWithError(w, http.StatusNotFound, "API key not found or already revoked")
+		return
+	}
+
+	key, err := db.GetAPIKey(ctx, pgPool, keyID)
+	if err != nil || key == nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to re-read API key")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(apiKeyResponse(*key))
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+
+	"alexis-art-backend/media"
+)
+
+// fileChange is a pending write of one artwork file; a nil content removes it.
+type fileChange struct {
+	name    string
+	content []byte
+}
+
+// appliedChange remembers what a file held before a change, for rollback.
+type appliedChange struct {
+	name     string
+	previous []byte
+	existed  bool
+}
+
+// applyFileChanges writes changes for an artwork in order. If any change
+// fails, the ones already applied are reverted and an *apiError is returned.
+// On success the returned undo func restores the previous state, so callers
+// can compensate when a later step (e.g. the DB commit) fails.
+func applyFileChanges(ctx context.Context, id string, changes []fileChange) (undo func(), err error) {
+	var applied []appliedChange
+	undo = func() {
+		// The request context may already be cancelled; restore regardless.
+		ctxUndo, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for i := len(applied) - 1; i >= 0; i-- {
+			a := applied[i]
+			var err error
+			if a.existed {
+				err = putArtworkFile(ctxUndo, id, a.name, a.previous)
+			} else {
+				err = removeArtworkFile(ctxUndo, id, a.name)
+			}
+			if err != nil {
+				log.Printf("Rollback of %s/%s failed: %v", id, a.name, err)
+			}
+		}
+	}
+
+	for _, c := range changes {
+		previous, existed, err := readArtworkFile(ctx, id, c.name)
+		if err != nil {
+			undo()
+			return nil, &apiError{Code: http.StatusInternalServerError, Message: "Failed to read " + c.name}
+		}
+		if c.content == nil {
+			if !existed {
+				continue
+			}
+			err = removeArtworkFile(ctx, id, c.name)
+		} else {
+			if existed && bytes.Equal(previous, c.content) {
+				continue
+			}
+			err = putArtworkFile(ctx, id, c.name, c.content)
+		}
+		if err != nil {
+			undo()
+			return nil, &apiError{Code: http.StatusInternalServerError, Message: "Failed to write " + c.name}
+		}
+		applied = append(applied, appliedChange{name: c.name, previous: previous, existed: existed})
+	}
+	return undo, nil
+}
+
+// readArtworkFile returns the current content of an artwork file and whether
+// it exists at all.
+func readArtworkFile(ctx context.Context, id, name string) ([]byte, bool, error) {
+	if s3Store != nil {
+		b, err := s3Store.getObjectBytes(ctx, id+"/"+name, 0)
+		if err != nil {
+			var re *awshttp.ResponseError
+			if errors.As(err, &re) && re.HTTPStatusCode() == http.StatusNotFound {
+				return nil, false, nil
+			}
+			return nil, false, err
+		}
+		return b, true, nil
+	}
+	b, err := os.ReadFile(filepath.Join(artworksDir, id, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+func putArtworkFile(ctx context.Context, id, name string, content []byte) error {
+	if s3Store != nil {
+		return s3Store.putObject(ctx, id+"/"+name, bytes.NewReader(content), media.ContentType(name))
+	}
+	return writeFileAtomic(filepath.Join(artworksDir, id, name), content, 0644)
+}
+
+func removeArtworkFile(ctx context.Context, id, name string) error {
+	if s3Store != nil {
+		return s3Store.deleteObject(ctx, id+"/"+name)
+	}
+	if err := os.Remove(filepath.Join(artworksDir, id, name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temp file in the same directory and renames
+// it over path, so readers never observe a partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"alexis-art-backend/db"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 500
+	adminPathPrefix   = "/api/v1/admin"
+)
+
+type AuditEntryResponse struct {
+	ID         int64           `json:"id"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	Actor      string          `json:"actor"`
+	IP         string          `json:"ip"`
+	UserAgent  string          `json:"userAgent"`
+	Action     string          `json:"action"`
+	Path       string          `json:"path"`
+	ArtworkID  string          `json:"artworkId,omitempty"`
+	Status     int             `json:"status"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+}
+
+type AuditListResponse struct {
+	Entries []AuditEntryResponse `json:"entries"`
+	Total   int                  `json:"total"`
+	Limit   int                  `json:"limit"`
+	Offset  int                  `json:"offset"`
+}
+
+// auditRecord travels in the context of an admin mutation so handlers can
+// add what the URL does not say (e.g. the id of a new artwork) and the state
+// of the artwork around the change.
+type auditRecord struct {
+	ArtworkID string
+	Before    []byte
+	After     []byte
+}
+
+// auditArtwork sets the artwork an admin mutation is about.
+func auditArtwork(ctx context.Context, id string) {
+	if rec, ok := ctx.Value(auditContextKey).(*auditRecord); ok {
+		rec.ArtworkID = id
+	}
+}
+
+// auditSnapshots sets the state of artwork id before and after the mutation
+// (before is nil for a new artwork). Handlers call it once the change is
+// stored and while they still hold the artwork lock, so the pair shows exactly
+// that change; they reuse the snapshots of the revision, so the audit costs no
+// extra read.
+func auditSnapshots(ctx context.Context, id string, before, after any) {
+	rec, ok := ctx.Value(auditContextKey).(*auditRecord)
+	if !ok || rec.ArtworkID != id {
+		return
+	}
+	if before != nil {
+		rec.Before, _ = json.Marshal(before)
+	}
+	rec.After, _ = json.Marshal(after)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// auditMiddleware records every admin mutation, allowed or not, with the
+// artwork before and after when the handler changed one (see
+// auditSnapshots). Reads are not recorded, and nothing is without Postgres.
+func auditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if pgPool == nil || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rec := &auditRecord{}
+		if id := mux.Vars(r)["id"]; isSafeArtworkID(id) {
+			rec.ArtworkID = id
+		}
+
+		sw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), auditContextKey, rec)))
+
+		if sw.status >= http.StatusBadRequest {
+			rec.Before, rec.After = nil, nil
+		}
+		template := r.URL.Path
+		if route := mux.CurrentRoute(r); route != nil {
+			if t, err := route.GetPathTemplate(); err == nil {
+				template = t
+			}
+		}
+		entry := db.AuditRow{
+			Actor:     actorFromContext(r.Context()),
+			IP:        clientIP(r),
+			UserAgent: r.UserAgent(),
+			Method:    r.Method,
+			Action:    r.Method + " " + strings.TrimPrefix(template, adminPathPrefix),
+			Path:      r.URL.Path,
+			ArtworkID: rec.ArtworkID,
+			Status:    sw.status,
+			Before:    rec.Before,
+			After:     rec.After,
+		}
+		// The request context may already be cancelled.
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		if err := db.InsertAudit(ctx, pgPool, entry); err != nil {
+			log.Printf("Recording audit entry for %s %s failed: %v", r.Method, r.URL.Path, err)
+		}
+	})
+}
+
+// parseAuditTime reads a date (YYYY-MM-DD) or an RFC 3339 timestamp. A date
+// used as the end of a range includes that whole day.
+func parseAuditTime(value string, end bool) (*time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, true
+	}
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return nil, false
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, true
+}
+
+// adminListAudit lists audit entries, newest first. Filters: artworkId,
+// actor, action, from and to (dates or timestamps), plus limit and offset.
+func adminListAudit(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	q := r.URL.Query()
+	filter := db.AuditFilter{
+		ArtworkID: strings.TrimSpace(q.Get("artworkId")),
+		Actor:     strings.TrimSpace(q.Get("actor")),
+		Action:    strings.TrimSpace(q.Get("action")),
+		Limit:     defaultAuditLimit,
+	}
+	var ok bool
+	if filter.From, ok = parseAuditTime(q.Get("from"), false); !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid from: use YYYY-MM-DD or an RFC 3339 timestamp")
+		return
+	}
+	if filter.To, ok = parseAuditTime(q.Get("to"), true); !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid to: use YYYY-MM-DD or an RFC 3339 timestamp")
+		return
+	}
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxAuditLimit {
+			respondWithError(w, http.StatusBadRequest, "Invalid limit: must be between 1 and "+strconv.Itoa(maxAuditLimit))
+			return
+		}
+		filter.Limit = n
+	}
+	if s := q.Get("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			respondWithError(w, http.StatusBadRequest, "Invalid offset")
+			return
+		}
+		filter.Offset = n
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	rows, total, err := db.ListAudit(ctx, pgPool, filter)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list audit entries")
+		return
+	}
+	entries := make([]AuditEntryResponse, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, AuditEntryResponse{
+			ID:         row.ID,
+			OccurredAt: row.OccurredAt,
+			Actor:      row.Actor,
+			IP:         row.IP,
+			UserAgent:  row.UserAgent,
+			Action:     row.Action,
+			Path:       row.Path,
+			ArtworkID:  row.ArtworkID,
+			Status:     row.Status,
+			Before:     row.Before,
+			After:      row.After,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AuditListResponse{Entries: entries, Total: total, Limit: filter.Limit, Offset: filter.Offset})
+}
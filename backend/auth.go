@@ -0,0 +1,494 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+
+	"alexis-art-backend/db"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+
+	// argon2id parameters for new hashes (OWASP minimum). Existing hashes
+	// keep the parameters they were made with.
+	argon2Time    = 2
+	argon2Memory  = 19 * 1024 // KiB
+	argon2Threads = 1
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+// sessionSecret signs access tokens (SESSION_SECRET, or derived from
+// ADMIN_TOKEN).
+var sessionSecret []byte
+
+// secretFromEnv returns the signing key for one purpose: the variable env
+// when set, otherwise a key derived from ADMIN_TOKEN as HMAC-SHA256 of label,
+// so that no key equals ADMIN_TOKEN or another purpose's key. It is empty
+// when neither is set.
+func secretFromEnv(env, label string) []byte {
+	if v := envAny(env); v != "" {
+		return []byte(v)
+	}
+	if adminToken == "" {
+		return nil
+	}
+	mac := hmac.New(sha256.New, []byte(adminToken))
+	mac.Write([]byte(label))
+	return mac.Sum(nil)
+}
+
+// adminIdentity is who is behind an admin request: an account (by session),
+// an API key, or ADMIN_TOKEN, which has neither and acts as an owner.
+type adminIdentity struct {
+	UserID    int64
+	Email     string
+	Name      string
+	Role      string
+	SessionID string
+	APIKeyID  string
+	Scopes    []string
+}
+
+// actor is the name recorded in history and audit fields.
+func (id adminIdentity) actor() string {
+	switch {
+	case id.APIKeyID != "":
+		return "apikey:" + id.Name
+	case id.UserID == 0:
+		return "admin"
+	}
+	return id.Email
+}
+
+type contextKey int
+
+const (
+	identityContextKey contextKey = iota
+	auditContextKey
+)
+
+func identityFromContext(ctx context.Context) (adminIdentity, bool) {
+	id, ok := ctx.Value(identityContextKey).(adminIdentity)
+	return id, ok
+}
+
+// actorFromContext returns who is performing an admin request, for history
+// and auditing.
+func actorFromContext(ctx context.Context) string {
+	if id, ok := identityFromContext(ctx); ok {
+		return id.actor()
+	}
+	return ""
+}
+
+type SessionResponse struct {
+	AccessToken      string            `json:"accessToken"`
+	TokenType        string            `json:"tokenType"`
+	ExpiresAt        time.Time         `json:"expiresAt"`
+	RefreshToken     string            `json:"refreshToken"`
+	RefreshExpiresAt time.Time         `json:"refreshExpiresAt"`
+	User             AdminUserResponse `json:"user"`
+}
+
+type loginPayload struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type refreshPayload struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// hashPassword returns an argon2id hash in the usual encoded form:
+// $argon2id$v=19$m=...,t=...,p=...$<salt>$<key>.
+func hashPassword(password string) string {
+	salt := make([]byte, argon2SaltLen)
+	rand.Read(salt)
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+// checkPassword verifies a password against a hash made by hashPassword.
+func checkPassword(encoded, password string) bool {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false
+	}
+	got := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(got, key) == 1
+}
+
+// dummyPasswordHash is checked when the email is unknown, so a failed login
+// takes as long whether or not the account exists.
+var dummyPasswordHash = sync.OnceValue(func() string { return hashPassword("not a real password") })
+
+// tokensEqual compares secrets in constant time. Hashing first hides the
+// length of the expected token too.
+func tokensEqual(a, b string) bool {
+	ha := sha256.Sum256([]byte(a))
+	hb := sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
+
+func randomToken(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// hashToken is how refresh tokens and API keys are stored: they are random,
+// so a plain SHA-256 is enough.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func sessionSignature(sessionID string, expires int64) string {
+	mac := hmac.New(sha256.New, sessionSecret)
+	fmt.Fprintf(mac, "session\n%s\n%d", sessionID, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// accessToken builds "<session id>.<expiry unix>.<signature>".
+func accessToken(sessionID string, expires time.Time) string {
+	exp := expires.Unix()
+	return sessionID + "." + strconv.FormatInt(exp, 10) + "." + sessionSignature(sessionID, exp)
+}
+
+// parseAccessToken checks the signature of an access token and returns its
+// session and expiry; callers decide whether an expired token is acceptable.
+func parseAccessToken(token string) (string, time.Time, bool) {
+	if len(sessionSecret) == 0 {
+		return "", time.Time{}, false
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] == "" {
+		return "", time.Time{}, false
+	}
+	exp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(sessionSignature(parts[0], exp))) {
+		return "", time.Time{}, false
+	}
+	return parts[0], time.Unix(exp, 0), true
+}
+
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if !strings.HasPrefix(auth, prefix) {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(auth, prefix))
+}
+
+// trustedProxies are the proxies in front of the API (TRUSTED_PROXIES), the
+// only peers whose X-Forwarded-For is believed.
+var trustedProxies []netip.Prefix
+
+// parseTrustedProxies reads a comma-separated list of IPs and CIDRs, logging
+// and skipping entries that are neither.
+func parseTrustedProxies(value string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		log.Printf("Ignoring invalid TRUSTED_PROXIES entry %q", entry)
+	}
+	return prefixes
+}
+
+func isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP is the address of the caller. X-Forwarded-For is only read when
+// the request comes from a trusted proxy, and then from the right: each proxy
+// appends the address it got the request from, so the last address that is
+// not a trusted proxy is the client (anything left of it may be forged).
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !isTrustedProxy(ip) {
+		return ip
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		ip = hop
+		if !isTrustedProxy(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// authenticate resolves the bearer token of a request: ADMIN_TOKEN, an API
+// key or the access token of an active session. It returns nil for anything
+// else.
+func authenticate(r *http.Request) (*adminIdentity, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, nil
+	}
+	if adminToken != "" && tokensEqual(token, adminToken) {
+		return &adminIdentity{Role: roleOwner}, nil
+	}
+	if strings.HasPrefix(token, apiKeyPrefix) {
+		return authenticateAPIKey(r.Context(), token, clientIP(r))
+	}
+	sessionID, expires, ok := parseAccessToken(token)
+	if !ok || time.Now().After(expires) || pgPool == nil {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+	user, err := db.GetSessionUser(ctx, pgPool, sessionID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	return &adminIdentity{UserID: user.ID, Email: user.Email, Name: user.Name, Role: user.Role, SessionID: sessionID}, nil
+}
+
+func adminAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if adminToken == "" && (pgPool == nil || len(sessionSecret) == 0) {
+			respondWithError(w, http.StatusInternalServerError, "ADMIN_TOKEN is not configured")
+			return
+		}
+		identity, err := authenticate(r)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to check session")
+			return
+		}
+		if identity == nil {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityContextKey, *identity)))
+	})
+}
+
+// sessionsAvailable reports (and responds) when accounts cannot be used.
+func sessionsAvailable(w http.ResponseWriter) bool {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return false
+	}
+	if len(sessionSecret) == 0 {
+		respondWithError(w, http.StatusInternalServerError, "SESSION_SECRET is not configured")
+		return false
+	}
+	return true
+}
+
+func respondWithSession(w http.ResponseWriter, session db.AdminSessionRow, refreshToken string, user db.AdminUserRow) {
+	expires := time.Now().Add(accessTokenTTL).Truncate(time.Second)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(SessionResponse{
+		AccessToken:      accessToken(session.ID, expires),
+		TokenType:        "Bearer",
+		ExpiresAt:        expires,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+		User:             adminUserResponse(user),
+	})
+}
+
+func authLogin(w http.ResponseWriter, r *http.Request) {
+	if !sessionsAvailable(w) {
+		return
+	}
+	var payload loginPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	payload.Email = strings.TrimSpace(payload.Email)
+	var errs validationErrors
+	if payload.Email == "" {
+		errs.add("email", codeRequired, "is required")
+	}
+	if payload.Password == "" {
+		errs.add("password", codeRequired, "is required")
+	}
+	if err := errs.err(); err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	user, err := db.GetAdminUserByEmail(ctx, pgPool, payload.Email)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to log in")
+		return
+	}
+	hash := dummyPasswordHash()
+	if user != nil {
+		hash = user.PasswordHash
+	}
+	if !checkPassword(hash, payload.Password) || user == nil || user.DisabledAt != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid email or password")
+		return
+	}
+
+	refresh := randomToken(32)
+	session := db.AdminSessionRow{
+		ID:          randomToken(16),
+		UserID:      user.ID,
+		RefreshHash: hashToken(refresh),
+		UserAgent:   r.UserAgent(),
+		IP:          clientIP(r),
+		ExpiresAt:   time.Now().Add(refreshTokenTTL).Truncate(time.Second),
+	}
+	if err := db.InsertAdminSession(ctx, pgPool, session); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to log in")
+		return
+	}
+	db.TouchAdminUserLogin(ctx, pgPool, user.ID)
+	respondWithSession(w, session, refresh, *user)
+}
+
+// authRefresh trades a refresh token for a new access token and a new refresh
+// token; the old refresh token stops working.
+func authRefresh(w http.ResponseWriter, r *http.Request) {
+	if !sessionsAvailable(w) {
+		return
+	}
+	var payload refreshPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if payload.RefreshToken == "" {
+		var errs validationErrors
+		errs.add("refreshToken", codeRequired, "is required")
+		respondWithAPIError(w, errs.err())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	refresh := randomToken(32)
+	session, err := db.RotateAdminSession(ctx, pgPool, hashToken(payload.RefreshToken), hashToken(refresh),
+		time.Now().Add(refreshTokenTTL).Truncate(time.Second))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to refresh session")
+		return
+	}
+	if session == nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired refresh token")
+		return
+	}
+	user, err := db.GetAdminUser(ctx, pgPool, session.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to refresh session")
+		return
+	}
+	if user == nil || user.DisabledAt != nil {
+		db.RevokeAdminSession(ctx, pgPool, session.ID, "")
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired refresh token")
+		return
+	}
+	respondWithSession(w, *session, refresh, *user)
+}
+
+// authLogout ends the session of the refresh token in the body or of the
+// access token in Authorization (which may already be expired).
+func authLogout(w http.ResponseWriter, r *http.Request) {
+	if !sessionsAvailable(w) {
+		return
+	}
+	var payload refreshPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	sessionID, _, _ := parseAccessToken(bearerToken(r))
+	if sessionID == "" && payload.RefreshToken == "" {
+		respondWithError(w, http.StatusBadRequest, "A refresh token or an access token is required")
+		return
+	}
+	refreshHash := ""
+	if payload.RefreshToken != "" {
+		refreshHash = hashToken(payload.RefreshToken)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	if _, err := db.RevokeAdminSession(ctx, pgPool, sessionID, refreshHash); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to log out")
+		return
+	}
+	// Logging out twice is not an error.
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+func TestClientIP(t *testing.T) {
+	prev := trustedProxies
+	trustedProxies = parseTrustedProxies("10.0.0.0/8, 192.168.1.5, not-an-ip")
+	t.Cleanup(func() { trustedProxies = prev })
+
+	tests := []struct {
+		name, remote, forwarded, want string
+	}{
+		{"direct", "203.0.113.7:5000", "", "203.0.113.7"},
+		{"forged header from untrusted peer", "203.0.113.7:5000", "1.2.3.4", "203.0.113.7"},
+		{"trusted proxy", "10.1.2.3:443", "198.51.100.9", "198.51.100.9"},
+		{"forged left entry", "10.1.2.3:443", "1.2.3.4, 198.51.100.9", "198.51.100.9"},
+		{"chain of trusted proxies", "192.168.1.5:443", "198.51.100.9, 10.0.0.2", "198.51.100.9"},
+		{"trusted proxy without header", "10.1.2.3:443", "", "10.1.2.3"},
+		{"only trusted hops", "10.1.2.3:443", "10.0.0.4", "10.0.0.4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := clientIP(r); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	hash := hashPassword("correct horse battery")
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Fatalf("hashPassword = %q, want an argon2id hash with the default parameters", hash)
+	}
+	if other := hashPassword("correct horse battery"); other == hash {
+		t.Error("hashPassword reused the salt")
+	}
+
+	parts := strings.Split(hash, "$")
+	// Older hashes keep working with the parameters they were made with.
+	cheap := func() string {
+		salt := []byte("0123456789abcdef")
+		key := argon2.IDKey([]byte("correct horse battery"), salt, 1, 8*1024, 1, 32)
+		return "$argon2id$v=19$m=8192,t=1,p=1$" + base64.RawStdEncoding.EncodeToString(salt) + "$" +
+			base64.RawStdEncoding.EncodeToString(key)
+	}()
+
+	tests := []struct {
+		name, encoded, password string
+		want                    bool
+	}{
+		{"match", hash, "correct horse battery", true},
+		{"wrong password", hash, "correct horse batterY", false},
+		{"empty password", hash, "", false},
+		{"other parameters", cheap, "correct horse battery", true},
+		{"other algorithm", strings.Replace(hash, "argon2id", "argon2i", 1), "correct horse battery", false},
+		{"other version", strings.Replace(hash, "v=19", "v=16", 1), "correct horse battery", false},
+		{"bad parameters", strings.Replace(hash, parts[3], "m=x", 1), "correct horse battery", false},
+		{"bad salt", strings.Replace(hash, parts[4], "!!", 1), "correct horse battery", false},
+		{"truncated", strings.Join(parts[:5], "$"), "correct horse battery", false},
+		{"empty key", strings.Join(parts[:5], "$") + "$", "correct horse battery", false},
+		{"plain text", "correct horse battery", "correct horse battery", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkPassword(tt.encoded, tt.password); got != tt.want {
+				t.Errorf("checkPassword = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	token := randomToken(32)
+	if len(token) != 43 {
+		t.Errorf("randomToken(32) has %d characters, want 43", len(token))
+	}
+	if randomToken(32) == token {
+		t.Error("randomToken repeated a token")
+	}
+	if hashToken(token) != hashToken(token) || hashToken(token) == hashToken(token+"x") {
+		t.Error("hashToken is not a function of the token")
+	}
+	if strings.Contains(hashToken(token), token) {
+		t.Error("hashToken contains the token")
+	}
+}
+
+func TestAccessToken(t *testing.T) {
+	prev := sessionSecret
+	sessionSecret = []byte("test-session-secret")
+	t.Cleanup(func() { sessionSecret = prev })
+
+	expires := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	token := accessToken("sess1", expires)
+	parts := strings.Split(token, ".")
+	later := strconv.FormatInt(expires.Add(time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name, token string
+		want        bool
+	}{
+		{"valid", token, true},
+		{"other session", "sess2." + parts[1] + "." + parts[2], false},
+		{"extended expiry", parts[0] + "." + later + "." + parts[2], false},
+		{"tampered signature", parts[0] + "." + parts[1] + "." + strings.Repeat("A", len(parts[2])), false},
+		{"no session", "." + parts[1] + "." + sessionSignature("", expires.Unix()), false},
+		{"malformed", parts[0] + "." + parts[1], false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, exp, ok := parseAccessToken(tt.token)
+			if ok != tt.want {
+				t.Fatalf("parseAccessToken ok = %v, want %v", ok, tt.want)
+			}
+			if ok && (id != "sess1" || !exp.Equal(expires)) {
+				t.Errorf("parseAccessToken = %q, %v, want sess1, %v", id, exp, expires)
+			}
+		})
+	}
+
+	sessionSecret = []byte("rotated-secret")
+	if _, _, ok := parseAccessToken(token); ok {
+		t.Error("parseAccessToken accepted a token signed with another secret")
+	}
+}
+
+func TestAuthenticateWithoutDatabase(t *testing.T) {
+	prevToken, prevSecret := adminToken, sessionSecret
+	adminToken = "test-admin-token"
+	sessionSecret = []byte("test-session-secret")
+	t.Cleanup(func() { adminToken, sessionSecret = prevToken, prevSecret })
+
+	tests := []struct {
+		name, authorization, wantRole string
+	}{
+		{"no header", "", ""},
+		{"not bearer", "Basic " + adminToken, ""},
+		{"admin token", "Bearer " + adminToken, roleOwner},
+		{"wrong token", "Bearer " + adminToken + "x", ""},
+		{"expired access token", "Bearer " + accessToken("sess1", time.Now().Add(-time.Minute)), ""},
+		// Sessions live in Postgres: without it no access token is accepted.
+		{"access token without database", "Bearer " + accessToken("sess1", time.Now().Add(time.Minute)), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/v1/admin/me", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			id, err := authenticate(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			role := ""
+			if id != nil {
+				role = id.Role
+			}
+			if role != tt.wantRole {
+				t.Errorf("authenticate role = %q, want %q", role, tt.wantRole)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"alexis-art-backend/db"
+)
+
+const maxBitacoraEntryLength = 20000
+
+type BitacoraMedia struct {
+	File string `json:"file"`
+	Type string `json:"type"` // image or video
+	URL  string `json:"url"`
+}
+
+// BitacoraEntry is one dated step of the making of an artwork, as embedded in
+// Artwork responses (oldest first).
+type BitacoraEntry struct {
+	ID    int64           `json:"id,omitempty"`
+	Date  string          `json:"date"`
+	Text  string          `json:"text"`
+	Media []BitacoraMedia `json:"media,omitempty"`
+	// Text in the format asked with ?format=html|text.
+	Rendered string `json:"rendered,omitempty"`
+}
+
+type BitacoraEntryResponse struct {
+	BitacoraEntry
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type BitacoraListResponse struct {
+	Entries []BitacoraEntryResponse `json:"entries"`
+	Total   int                     `json:"total"`
+}
+
+type adminBitacoraEntryPayload struct {
+	Date string `json:"date"`
+	Text string `json:"text"`
+	// Filenames of images or videos of the artwork.
+	Media []string `json:"media"`
+}
+
+func (p *adminBitacoraEntryPayload) normalize() {
+	p.Date = strings.TrimSpace(p.Date)
+	p.Text = strings.TrimSpace(p.Text)
+	media := make([]string, 0, len(p.Media))
+	for _, m := range p.Media {
+		if m = strings.TrimSpace(m); m != "" && !slices.Contains(media, m) {
+			media = append(media, m)
+		}
+	}
+	p.Media = media
+}
+
+// validate checks the entry against the files the artwork currently has.
+func (p adminBitacoraEntryPayload) validate(artwork Artwork) error {
+	var errs validationErrors
+	if p.Date == "" {
+		errs.add("date", codeRequired, "is required")
+	}
+	errs.checkDate("date", p.Date)
+	if p.Text == "" && len(p.Media) == 0 {
+		errs.add("text", codeRequired, "is required when there is no media")
+	}
+	errs.checkLength("text", p.Text, maxBitacoraEntryLength)
+	for _, m := range p.Media {
+		if !slices.Contains(artwork.Images, m) && !slices.Contains(artwork.Videos, m) {
+			errs.add("media", codeInvalidValue, "must be filenames of images or videos of the artwork")
+			break
+		}
+	}
+	return errs.err()
+}
+
+// bitacoraMedia resolves filenames against the artwork; files deleted since
+// the entry was written are left out.
+func bitacoraMedia(artwork Artwork, files []string) []BitacoraMedia {
+	var media []BitacoraMedia
+	for _, f := range files {
+		switch {
+		case slices.Contains(artwork.Images, f):
+			media = append(media, BitacoraMedia{File: f, Type: "image", URL: imageURL(artwork.ID, f)})
+		case slices.Contains(artwork.Videos, f):
+			media = append(media, BitacoraMedia{File: f, Type: "video",
+				URL: "/api/v1/artworks/" + url.PathEscape(artwork.ID) + "/videos/" + url.PathEscape(f)})
+		}
+	}
+	return media
+}
+
+func bitacoraEntry(artwork Artwork, row db.BitacoraEntryRow) BitacoraEntry {
+	return BitacoraEntry{
+		ID:    row.ID,
+		Date:  row.Date.Format(dateLayout),
+		Text:  row.Body,
+		Media: bitacoraMedia(artwork, row.Media),
+	}
+}
+
+// legacyBitacoraDate dates the old free-text bitácora: it was written while
+// the artwork was being painted.
+func legacyBitacoraDate(artwork Artwork) time.Time {
+	for _, d := range []string{artwork.StartDate, artwork.EndDate} {
+		if t, ok := parseDate(d); ok && t != nil {
+			return *t
+		}
+	}
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+// attachArtworkBitacora sets the bitácora entries from their rows. Without
+// Postgres the free-text bitácora is shown as a single entry.
+func attachArtworkBitacora(artwork *Artwork, rows []db.BitacoraEntryRow) {
+	artwork.BitacoraEntries = nil
+	if pgPool == nil {
+		if text := strings.TrimSpace(artwork.Bitacora); text != "" {
+			artwork.BitacoraEntries = []BitacoraEntry{{Date: legacyBitacoraDate(*artwork).Format(dateLayout), Text: text}}
+		}
+		return
+	}
+	for _, row := range rows {
+		artwork.BitacoraEntries = append(artwork.BitacoraEntries, bitacoraEntry(*artwork, row))
+	}
+}
+
+// importLegacyBitacoras turns the free-text bitácora of every artwork into
+// its first entry. Each artwork is imported once, so it is safe on every
+// startup and picks up bitacora.txt files added by hand.
+func importLegacyBitacoras(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	imported, err := db.ImportedBitacoras(ctx, pgPool)
+	if err != nil {
+		log.Printf("Bitacora import failed: %v", err)
+		return
+	}
+	artworks, err := scanArtworks(ctx)
+	if err != nil {
+		log.Printf("Bitacora import failed: %v", err)
+		return
+	}
+	count := 0
+	for _, a := range artworks {
+		text := strings.TrimSpace(a.Bitacora)
+		if text == "" || imported[a.ID] {
+			continue
+		}
+		tx, err := pgPool.Begin(ctx)
+		if err != nil {
+			log.Printf("Bitacora import failed: %v", err)
+			return
+		}
+		ok, err := db.ImportBitacora(ctx, tx, db.BitacoraEntryRow{
+			ArtworkID: a.ID,
+			Date:      legacyBitacoraDate(a),
+			Body:      text,
+			CreatedBy: "import",
+		})
+		if err == nil {
+			err = tx.Commit(ctx)
+		}
+		tx.Rollback(context.Background())
+		if err != nil {
+			log.Printf("Bitacora import failed for %s: %v", a.ID, err)
+			continue
+		}
+		if ok {
+			count++
+		}
+	}
+	if count > 0 {
+		bumpCatalogVersion()
+		log.Printf("Imported the bitacora of %d artworks as timeline entries", count)
+	}
+}
+
+func bitacoraEntryResponse(artwork Artwork, row db.BitacoraEntryRow) BitacoraEntryResponse {
+	return BitacoraEntryResponse{
+		BitacoraEntry: bitacoraEntry(artwork, row),
+		CreatedBy:     row.CreatedBy,
+		CreatedAt:     row.CreatedAt,
+		UpdatedAt:     row.UpdatedAt,
+	}
+}
+
+// bitacoraTarget loads the artwork of the URL and reads the entry id, if any.
+func bitacoraTarget(w http.ResponseWriter, r *http.Request) (Artwork, int64, bool) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return Artwork{}, 0, false
+	}
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if !isSafeArtworkID(id) {
+		respondWithError(w, http.StatusBadRequest, "Invalid artwork id")
+		return Artwork{}, 0, false
+	}
+	var entryID int64
+	if s, ok := vars["entryId"]; ok {
+		var err error
+		if entryID, err = strconv.ParseInt(s, 10, 64); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid entry id")
+			return Artwork{}, 0, false
+		}
+	}
+	artwork, err := getArtworkByID(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Artwork not found")
+		return Artwork{}, 0, false
+	}
+	return artwork, entryID, true
+}
+
+func adminListBitacora(w http.ResponseWriter, r *http.Request) {
+	artwork, _, ok := bitacoraTarget(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	rows, err := db.ListBitacoraEntries(ctx, pgPool, artwork.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list bitacora entries")
+		return
+	}
+	entries := make([]BitacoraEntryResponse, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, bitacoraEntryResponse(artwork, row))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BitacoraListResponse{Entries: entries, Total: len(entries)})
+}
+
+func adminCreateBitacoraEntry(w http.ResponseWriter, r *http.Request) {
+	saveBitacoraEntry(w, r, false)
+}
+
+func adminUpdateBitacoraEntry(w http.ResponseWriter, r *http.Request) {
+	saveBitacoraEntry(w, r, true)
+}
+
+func saveBitacoraEntry(w http.ResponseWriter, r *http.Request, update bool) {
+	if id := mux.Vars(r)["id"]; isSafeArtworkID(id) {
+		unlock := lockArtwork(id)
+		defer unlock()
+	}
+	artwork, entryID, ok := bitacoraTarget(w, r)
+	if !ok {
+		return
+	}
+	var before *revisionSnapshot
+	if s, err := snapshotOf(r.Context(), artwork); err == nil {
+		before = &s
+	} else {
+		log.Printf("Reading revision snapshot of %s failed: %v", artwork.ID, err)
+	}
+	var payload adminBitacoraEntryPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	payload.normalize()
+	if err := payload.validate(artwork); err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+	date, _ := parseDate(payload.Date)
+	row := db.BitacoraEntryRow{
+		ID:        entryID,
+		ArtworkID: artwork.ID,
+		Date:      *date,
+		Body:      payload.Text,
+		Media:     payload.Media,
+		CreatedBy: actorFromContext(r.Context()),
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	status := http.StatusCreated
+	if update {
+		status = http.StatusOK
+		updated, err := db.UpdateBitacoraEntry(ctx, pgPool, row)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update bitacora entry")
+			return
+		}
+		if !updated {
+			respondWithError(w, http.StatusNotFound, "Bitacora entry not found")
+			return
+		}
+	} else {
+		var err error
+		if entryID, err = db.InsertBitacoraEntry(ctx, pgPool, row); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to create bitacora entry")
+			return
+		}
+	}
+	bumpCatalogVersion()
+	recordArtworkChange(r.Context(), artwork.ID, revisionActionBitacora, before)
+
+	saved, err := db.GetBitacoraEntry(ctx, pgPool, artwork.ID, entryID)
+	if err != nil || saved == nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to re-read bitacora entry")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(bitacoraEntryResponse(artwork, *saved))
+}
+
+func adminDeleteBitacoraEntry(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if !isSafeArtworkID(id) {
+		respondWithError(w, http.StatusBadRequest, "Invalid artwork id")
+		return
+	}
+	entryID, err := strconv.ParseInt(vars["entryId"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid entry id")
+		return
+	}
+
+	unlock := lockArtwork(id)
+	defer unlock()
+	before := snapshotArtwork(r.Context(), id)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	deleted, err := db.DeleteBitacoraEntry(ctx, pgPool, id, entryID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete bitacora entry")
+		return
+	}
+	if !deleted {
+		respondWithError(w, http.StatusNotFound, "Bitacora entry not found")
+		return
+	}
+	bumpCatalogVersion()
+	recordArtworkChange(r.Context(), id, revisionActionBitacora, before)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"alexis-art-backend/db"
+)
+
+// How long a computed catalog fingerprint is reused before the storage is
+// listed again to detect changes made outside the admin API.
+const catalogFingerprintTTL = 5 * time.Second
+
+// catalogVersion is bumped by every admin mutation so this instance drops its
+// memoized fingerprint and cached responses right away.
+var catalogVersion atomic.Uint64
+
+func bumpCatalogVersion() {
+	catalogVersion.Add(1)
+}
+
+type catalogFingerprintEntry struct {
+	version      uint64
+	computedAt   time.Time
+	fingerprint  string
+	lastModified time.Time
+}
+
+var catalogFP struct {
+	mu    sync.Mutex
+	entry catalogFingerprintEntry
+}
+
+// catalogResponses caches encoded JSON bodies by ETag. It is cleared whenever
+// the catalog fingerprint changes, so it only ever holds current variants.
+var catalogResponses struct {
+	mu          sync.Mutex
+	fingerprint string
+	bodies      map[string][]byte
+}
+
+// catalogFingerprint returns a hash of everything the public catalog is
+// built from (files in storage plus the DB rows) and its last modification.
+func catalogFingerprint(ctx context.Context) (string, time.Time, error) {
+	version := catalogVersion.Load()
+
+	catalogFP.mu.Lock()
+	defer catalogFP.mu.Unlock()
+	e := catalogFP.entry
+	if e.fingerprint != "" && e.version == version && time.Since(e.computedAt) < catalogFingerprintTTL {
+		return e.fingerprint, e.lastModified, nil
+	}
+
+	h := sha256.New()
+	lastModified, err := storageFingerprint(ctx, "", h)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if pgPool != nil {
+		ctxDB, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		count, visible, updated, err := db.CatalogStamp(ctxDB, pgPool)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		fmt.Fprintf(h, "db:%d:%d:%d\n", count, visible, updated.UnixNano())
+		relationsUpdated, err := relationStamps(ctxDB, "", h)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		// Editable fields live in Postgres, so its updated_at wins.
+		if !updated.IsZero() {
+			lastModified = updated
+		}
+		if relationsUpdated.After(lastModified) {
+			lastModified = relationsUpdated
+		}
+	}
+
+	catalogFP.entry = catalogFingerprintEntry{
+		version:      version,
+		computedAt:   time.Now(),
+		fingerprint:  hex.EncodeToString(h.Sum(nil)),
+		lastModified: lastModified,
+	}
+	return catalogFP.entry.fingerprint, lastModified, nil
+}
+
+// artworkFingerprint is the single-artwork counterpart of catalogFingerprint.
+func artworkFingerprint(ctx context.Context, id string) (string, time.Time, error) {
+	h := sha256.New()
+	lastModified, err := storageFingerprint(ctx, id, h)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if pgPool != nil {
+		ctxDB, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		row, err := db.GetArtwork(ctxDB, pgPool, id)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		relationsUpdated, err := relationStamps(ctxDB, id, h)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		if row != nil {
+			visible := isVisibleStatus(row.Status, row.PublishAt, time.Now())
+			fmt.Fprintf(h, "db:%d:%t\n", row.UpdatedAt.UnixNano(), visible)
+			lastModified = row.UpdatedAt
+			if visible && row.PublishAt != nil && row.PublishAt.After(lastModified) {
+				lastModified = *row.PublishAt
+			}
+		}
+		if relationsUpdated.After(lastModified) {
+			lastModified = relationsUpdated
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), lastModified, nil
+}
+
+// relationStamps hashes the state of the tables embedded in Artwork
+// responses (tags, exhibitions, translations, bitácora entries and their
+// translations) for one artwork, or all when id is empty, and returns their
+// last change.
+func relationStamps(ctx context.Context, id string, h hash.Hash) (time.Time, error) {
+	var last time.Time
+	stamps := []struct {
+		name  string
+		stamp func(context.Context, db.Querier, string) (int, time.Time, error)
+	}{
+		{"tags", db.TagsStamp},
+		{"exhibitions", db.ExhibitionsStamp},
+		{"translations", db.TranslationsStamp},
+		{"bitacora", db.BitacoraStamp},
+		{"bitacora_translations", db.BitacoraTranslationsStamp},
+	}
+	for _, s := range stamps {
+		count, updated, err := s.stamp(ctx, pgPool, id)
+		if err != nil {
+			return last, err
+		}
+		fmt.Fprintf(h, "%s:%d:%d\n", s.name, count, updated.UnixNano())
+		if updated.After(last) {
+			last = updated
+		}
+	}
+	return last, nil
+}
+
+// storageFingerprint hashes names, sizes and timestamps of the files of one
+// artwork (or of all artworks when id is empty) without reading contents.
+func storageFingerprint(ctx context.Context, id string, h hash.Hash) (time.Time, error) {
+	if s3Store != nil {
+		prefix := ""
+		if id != "" {
+			prefix = id + "/"
+		}
+		return s3Store.fingerprint(ctx, prefix, h)
+	}
+
+	var last time.Time
+	ids := []string{id}
+	if id == "" {
+		entries, err := os.ReadDir(artworksDir)
+		if err != nil {
+			return last, fmt.Errorf("failed to read artworks directory: %v", err)
+		}
+		ids = ids[:0]
+		for _, entry := range entries {
+			if entry.IsDir() {
+				ids = append(ids, entry.Name())
+			}
+		}
+	}
+	for _, artworkID := range ids {
+		entries, err := os.ReadDir(filepath.Join(artworksDir, artworkID))
+		if err != nil {
+			return last, err
+		}
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil || info.IsDir() {
+				continue
+			}
+			fmt.Fprintf(h, "%s/%s:%d:%d\n", artworkID, info.Name(), info.Size(), info.ModTime().UnixNano())
+			if info.ModTime().After(last) {
+				last = info.ModTime()
+			}
+		}
+	}
+	return last, nil
+}
+
+// responseETag combines a content fingerprint with the request path, query
+// and locale, since they select different representations of the same data.
+func responseETag(fingerprint string, r *http.Request) string {
+	h := sha256.Sum256([]byte(fingerprint + "|" + requestLocale(r) + "|" + r.URL.Path + "?" + r.URL.Query().Encode()))
+	return `"` + hex.EncodeToString(h[:16]) + `"`
+}
+
+// cachedCatalogBody returns the cached body for etag, if any.
+func cachedCatalogBody(fingerprint, etag string) ([]byte, bool) {
+	catalogResponses.mu.Lock()
+	defer catalogResponses.mu.Unlock()
+	if catalogResponses.fingerprint != fingerprint {
+		return nil, false
+	}
+	b, ok := catalogResponses.bodies[etag]
+	return b, ok
+}
+
+func storeCatalogBody(fingerprint, etag string, body []byte) {
+	catalogResponses.mu.Lock()
+	defer catalogResponses.mu.Unlock()
+	if catalogResponses.fingerprint != fingerprint {
+		catalogResponses.fingerprint = fingerprint
+		catalogResponses.bodies = map[string][]byte{}
+	}
+	catalogResponses.bodies[etag] = body
+}
+
+// setValidators sets ETag/Last-Modified and reports whether the request's
+// conditional headers match, in which case a 304 has already been written.
+func setValidators(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	w.Header().Set("ETag", etag)
+	// Clients may cache, but must revalidate; revalidation is cheap.
+	w.Header().Set("Cache-Control", "no-cache")
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if etagMatches(inm, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil && !lastModified.Truncate(time.Second).After(t) {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// etagMatches implements the weak comparison used by If-None-Match.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchSatisfied implements the strong comparison used by If-Match.
+func ifMatchSatisfied(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
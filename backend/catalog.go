@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/gorilla/mux"
+
+	"alexis-art-backend/db"
+	"alexis-art-backend/money"
+)
+
+// artistBio is printed on the second page of generated catalogs.
+var artistBio = []string{
+	"Alexis Aníbal Barros Contreras nace el 30 de octubre de 1990 en Molina, región del Maule, Chile. " +
+		"A los 4 años se traslada junto a su familia a la comuna de Colina, en donde vive hasta el día de hoy.",
+	"Se gradúa el año 2017 de la carrera de mantenimiento en minería. Su primer acercamiento al arte va de la mano " +
+		"con el dibujo para luego especializarse en pintura al óleo de forma autodidacta hasta la actualidad.",
+	"En paralelo a su oficio, Barros ha mantenido una relación cercana al arte, realizando pinturas desde temprana edad, " +
+		"gracias a una fuerte influencia familiar ligada a distintas ramas de arte.",
+}
+
+var availabilityLabels = map[string]string{
+	availabilityAvailable:         "Disponible",
+	availabilityReserved:          "Reservada",
+	availabilitySold:              "Vendida",
+	availabilityNotForSale:        "No disponible para la venta",
+	availabilityPrivateCollection: "Colección privada",
+}
+
+var spanishMonths = []string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio",
+	"agosto", "septiembre", "octubre", "noviembre", "diciembre"}
+
+// catalogDocument is everything a printed catalog shows.
+type catalogDocument struct {
+	Title       string
+	Subtitle    string
+	Description string
+	Date        string
+	Bio         []string
+	Artworks    []catalogArtwork
+}
+
+type catalogArtwork struct {
+	Artwork
+	Year       string
+	Size       string
+	Status     string
+	PriceText  string
+	Image      []byte // JPEG thumbnail of the primary image; nil if missing
+	Paragraphs []string
+}
+
+// ImageURL embeds the image in standalone HTML.
+func (a catalogArtwork) ImageURL() template.URL {
+	return template.URL("data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(a.Image))
+}
+
+// catalogFilter selects the artworks of a catalog:
+// ?series=paisajes&availability=available&year=2020-2022&tag=azul&ids=a,b
+type catalogFilter struct {
+	sales      salesFilter
+	tags       []string
+	series     string
+	ids        []string
+	fromYear   int
+	toYear     int
+	coverTitle string
+}
+
+func parseCatalogFilter(q url.Values) (catalogFilter, string) {
+	var f catalogFilter
+	var invalid string
+	if f.sales, invalid = parseSalesFilter(q); invalid != "" {
+		return f, invalid
+	}
+	f.tags = q["tag"]
+	f.series = strings.TrimSpace(q.Get("series"))
+	for _, id := range strings.Split(q.Get("ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			if !isSafeArtworkID(id) {
+				return f, "Invalid artwork id in ids"
+			}
+			f.ids = append(f.ids, id)
+		}
+	}
+	if y := strings.TrimSpace(q.Get("year")); y != "" {
+		from, to, isRange := strings.Cut(y, "-")
+		if !isRange {
+			to = from
+		}
+		var errFrom, errTo error
+		f.fromYear, errFrom = strconv.Atoi(strings.TrimSpace(from))
+		f.toYear, errTo = strconv.Atoi(strings.TrimSpace(to))
+		if errFrom != nil || errTo != nil || f.fromYear > f.toYear {
+			return f, "Invalid year: use YYYY or YYYY-YYYY"
+		}
+	}
+	f.coverTitle = strings.TrimSpace(q.Get("title"))
+	return f, ""
+}
+
+// artworkYear is the year a work was finished, or started when unfinished.
+func artworkYear(a Artwork) string {
+	for _, d := range []string{a.EndDate, a.StartDate} {
+		if len(d) >= 4 {
+			return d[:4]
+		}
+	}
+	return ""
+}
+
+func (f catalogFilter) match(a Artwork) bool {
+	if len(f.ids) > 0 && !slices.Contains(f.ids, a.ID) {
+		return false
+	}
+	if f.fromYear != 0 {
+		year, err := strconv.Atoi(artworkYear(a))
+		if err != nil || year < f.fromYear || year > f.toYear {
+			return false
+		}
+	}
+	return f.sales.match(a) && a.hasTags(f.tags)
+}
+
+// formatAmount formats a price the Spanish way: 2.400 or 2.400,50.
+func formatAmount(v money.Amount) string {
+	whole, cents, _ := strings.Cut(v.String(), ".")
+	var b strings.Builder
+	for i, c := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(c)
+	}
+	if cents != "00" {
+		b.WriteString("," + cents)
+	}
+	return b.String()
+}
+
+// buildCatalog collects the public artworks selected by the filter. The
+// returned status and message describe why it failed.
+func buildCatalog(ctx context.Context, f catalogFilter) (*catalogDocument, int, string) {
+	now := time.Now()
+	doc := &catalogDocument{
+		Title: "Catálogo",
+		Date:  spanishMonths[now.Month()-1] + " de " + strconv.Itoa(now.Year()),
+		Bio:   artistBio,
+	}
+
+	var artworks []Artwork
+	if f.series != "" {
+		if pgPool == nil {
+			return nil, http.StatusNotFound, "Series not found"
+		}
+		ctxDB, cancel := context.WithTimeout(ctx, 3*time.Second)
+		row, err := db.GetSeries(ctxDB, pgPool, f.series)
+		cancel()
+		if err != nil {
+			return nil, http.StatusInternalServerError, "Failed to read series"
+		}
+		if row == nil {
+			return nil, http.StatusNotFound, "Series not found"
+		}
+		doc.Subtitle, doc.Description = row.Title, row.Description
+		for _, id := range row.ArtworkIDs {
+			if a, err := getArtworkByID(ctx, id); err == nil {
+				artworks = append(artworks, a)
+			}
+		}
+	} else {
+		all, err := scanArtworks(ctx)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err.Error()
+		}
+		// Newest first.
+		sort.SliceStable(all, func(i, j int) bool {
+			yi, yj := artworkYear(all[i]), artworkYear(all[j])
+			if yi != yj {
+				return yi > yj
+			}
+			return all[i].Title < all[j].Title
+		})
+		artworks = all
+	}
+	if f.coverTitle != "" {
+		doc.Subtitle = f.coverTitle
+	}
+
+	for _, a := range publicArtworks(artworks) {
+		if a = a.publicView(); !f.match(a) {
+			continue
+		}
+		entry := catalogArtwork{
+			Artwork: a,
+			Year:    artworkYear(a),
+			Size:    a.Dimensions.String(),
+			Status:  availabilityLabels[a.Availability],
+		}
+		if a.Price != nil {
+			entry.PriceText = formatAmount(a.Price.Amount) + " " + a.Price.Currency
+		}
+		for _, p := range strings.Split(strings.ReplaceAll(a.Detalle, "\r\n", "\n"), "\n\n") {
+			if p = strings.TrimSpace(p); p != "" {
+				entry.Paragraphs = append(entry.Paragraphs, p)
+			}
+		}
+		if a.PrimaryImage != "" {
+			ctxFile, cancel := context.WithTimeout(ctx, 10*time.Second)
+			if data, found, err := readArtworkFile(ctxFile, a.ID, a.PrimaryImage); err == nil && found {
+				entry.Image, _ = thumbnailJPEG(data, pdfThumbnailSize)
+			}
+			cancel()
+		}
+		doc.Artworks = append(doc.Artworks, entry)
+	}
+	if len(doc.Artworks) == 0 {
+		return nil, http.StatusNotFound, "No public artworks match the catalog filters"
+	}
+	return doc, 0, ""
+}
+
+// adminExportCatalog answers GET /admin/catalog.pdf and /admin/catalog.html
+// with a printable catalog of the public artworks selected by the filters.
+func adminExportCatalog(w http.ResponseWriter, r *http.Request) {
+	format := mux.Vars(r)["format"]
+	filter, invalid := parseCatalogFilter(r.URL.Query())
+	if invalid != "" {
+		respondWithError(w, http.StatusBadRequest, invalid)
+		return
+	}
+	doc, status, msg := buildCatalog(r.Context(), filter)
+	if doc == nil {
+		respondWithError(w, status, msg)
+		return
+	}
+
+	var buf bytes.Buffer
+	var err error
+	contentType := "application/pdf"
+	if format == "html" {
+		contentType = "text/html; charset=utf-8"
+		err = catalogHTML.Execute(&buf, doc)
+	} else {
+		err = renderCatalogPDF(&buf, doc)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to render catalog")
+		return
+	}
+
+	filename := "catalogo"
+	if filter.series != "" {
+		filename += "-" + filter.series
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.`+format+`"`)
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Write(buf.Bytes())
+}
+
+func renderCatalogPDF(out io.Writer, doc *catalogDocument) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(doc.Title+" - "+artistName, true)
+	pdf.SetAuthor(artistName, true)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pageW, pageH := pdf.GetPageSize()
+	const margin = 20.0
+	contentW := pageW - 2*margin
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(true, margin)
+	pdf.SetFooterFunc(func() {
+		if pdf.PageNo() == 1 {
+			return
+		}
+		pdf.SetY(-margin + 5)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(contentW/2, 5, tr(artistName+" · "+doc.Title), "", 0, "L", false, 0, "")
+		pdf.CellFormat(contentW/2, 5, strconv.Itoa(pdf.PageNo()), "", 0, "R", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	})
+
+	// Cover: the first artwork, the title and the date.
+	pdf.AddPage()
+	y := margin + 20
+	if cover := doc.Artworks[0].Image; cover != nil {
+		y += placeImage(pdf, "cover", cover, margin, y, contentW, 140) + 15
+	}
+	pdf.SetXY(margin, y)
+	pdf.SetFont("Helvetica", "", 26)
+	pdf.CellFormat(contentW, 12, tr(artistName), "", 1, "C", false, 0, "")
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(contentW, 10, tr(strings.ToUpper(doc.Title)), "", 1, "C", false, 0, "")
+	if doc.Subtitle != "" {
+		pdf.SetFont("Helvetica", "I", 13)
+		pdf.MultiCell(contentW, 7, tr(doc.Subtitle), "", "C", false)
+	}
+	pdf.SetXY(margin, pageH-margin-10)
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(contentW, 6, tr(doc.Date), "", 0, "C", false, 0, "")
+
+	// Biography, and the series description when there is one.
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(contentW, 10, tr("Biografía"), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+	pdf.SetFont("Helvetica", "", 11)
+	for _, p := range doc.Bio {
+		pdf.MultiCell(contentW, 6, tr(p), "", "J", false)
+		pdf.Ln(3)
+	}
+	if doc.Description != "" {
+		pdf.Ln(6)
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.MultiCell(contentW, 8, tr(doc.Subtitle), "", "L", false)
+		pdf.Ln(2)
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.MultiCell(contentW, 6, tr(doc.Description), "", "J", false)
+	}
+
+	// One page per artwork; a long detalle continues on the next page.
+	for i, a := range doc.Artworks {
+		pdf.AddPage()
+		y := margin
+		if a.Image != nil {
+			y += placeImage(pdf, "artwork-"+strconv.Itoa(i), a.Image, margin, y, contentW, 150) + 8
+		}
+		pdf.SetXY(margin, y)
+		pdf.SetFont("Helvetica", "B", 16)
+		pdf.MultiCell(contentW, 8, tr(a.Title), "", "L", false)
+		pdf.SetFont("Helvetica", "", 11)
+		var facts []string
+		for _, s := range []string{a.Technique, a.Size, a.Year} {
+			if s != "" {
+				facts = append(facts, s)
+			}
+		}
+		if len(facts) > 0 {
+			pdf.MultiCell(contentW, 6, tr(strings.Join(facts, " · ")), "", "L", false)
+		}
+		if status := strings.Trim(a.Status+" · "+a.PriceText, " ·"); status != "" {
+			pdf.SetFont("Helvetica", "I", 11)
+			pdf.MultiCell(contentW, 6, tr(status), "", "L", false)
+		}
+		pdf.Ln(3)
+		pdf.SetFont("Helvetica", "", 10)
+		for _, p := range a.Paragraphs {
+			pdf.MultiCell(contentW, 5, tr(p), "", "J", false)
+			pdf.Ln(2)
+		}
+	}
+
+	return pdf.Output(out)
+}
+
+var catalogHTML = template.Must(template.New("catalog").Parse(`<!DOCTYPE html>
+<html lang="es">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} · ` + artistName + `</title>
+<style>
+  @page { size: A4; margin: 20mm; }
+  body { font-family: Georgia, "Times New Roman", serif; color: #222; margin: 0; }
+  section { page-break-after: always; break-after: page; padding: 2rem 0; max-width: 170mm; margin: 0 auto; }
+  section:last-child { page-break-after: auto; break-after: auto; }
+  img { display: block; max-width: 100%; max-height: 150mm; margin: 0 auto 1.5rem; }
+  .cover { text-align: center; }
+  .cover h1 { font-weight: normal; font-size: 2.2rem; margin: 1rem 0 .5rem; }
+  .cover h2 { letter-spacing: .2em; font-size: 1.2rem; margin: 0; }
+  .cover .date { margin-top: 3rem; color: #666; }
+  .facts, .status { margin: .25rem 0; }
+  .status { font-style: italic; }
+  p { text-align: justify; line-height: 1.5; }
+</style>
+</head>
+<body>
+<section class="cover">
+  {{with index .Artworks 0}}{{if .Image}}<img src="{{.ImageURL}}" alt="{{.Title}}">{{end}}{{end}}
+  <h1>` + artistName + `</h1>
+  <h2>{{.Title}}</h2>
+  {{if .Subtitle}}<p><em>{{.Subtitle}}</em></p>{{end}}
+  <p class="date">{{.Date}}</p>
+</section>
+<section>
+  <h2>Biografía</h2>
+  {{range .Bio}}<p>{{.}}</p>
+  {{end}}
+  {{if .Description}}<h2>{{.Subtitle}}</h2>
+  <p>{{.Description}}</p>{{end}}
+</section>
+{{range .Artworks}}<section>
+  {{if .Image}}<img src="{{.ImageURL}}" alt="{{.Title}}">{{end}}
+  <h2>{{.Title}}</h2>
+  <p class="facts">{{.Technique}}{{if and .Technique .Size}} · {{end}}{{.Size}}{{if and (or .Technique .Size) .Year}} · {{end}}{{.Year}}</p>
+  {{if or .Status .PriceText}}<p class="status">{{.Status}}{{if and .Status .PriceText}} · {{end}}{{.PriceText}}</p>{{end}}
+  {{range .Paragraphs}}<p>{{.}}</p>
+  {{end}}
+</section>
+{{end}}</body>
+</html>
+`))
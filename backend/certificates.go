@@ -0,0 +1,458 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/gorilla/mux"
+	"golang.org/x/image/draw"
+
+	"alexis-art-backend/db"
+)
+
+const (
+	artistName = "Alexis Barros Contreras"
+
+	// Serials look like ABC-2024-0007.
+	certificateSerialPrefix = "ABC"
+
+	maxHolderNameLength = 200
+
+	// Longest side, in pixels, of images embedded in generated PDFs.
+	pdfThumbnailSize = 1200
+)
+
+// certificateVerifyBaseURL is where the verification code is checked, as
+// printed on certificates (CERTIFICATE_VERIFY_URL). Empty means this API.
+var certificateVerifyBaseURL string
+
+type adminCertificateCreate struct {
+	// Optional; printed on the PDF only, never shown on the public page.
+	HolderName string `json:"holderName"`
+}
+
+// CertificateVerification is the public answer of GET /certificates/{code}.
+type CertificateVerification struct {
+	Valid      bool       `json:"valid"`
+	Serial     string     `json:"serial"`
+	Code       string     `json:"code"`
+	ArtworkID  string     `json:"artworkId"`
+	Title      string     `json:"title"`
+	Technique  string     `json:"technique,omitempty"`
+	Dimensions string     `json:"dimensions,omitempty"`
+	StartDate  string     `json:"startDate,omitempty"`
+	EndDate    string     `json:"endDate,omitempty"`
+	IssuedAt   time.Time  `json:"issuedAt"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+type CertificateResponse struct {
+	CertificateVerification
+	PrimaryImage string `json:"primaryImage,omitempty"`
+	HolderName   string `json:"holderName,omitempty"`
+	IssuedBy     string `json:"issuedBy"`
+	RevokedBy    string `json:"revokedBy,omitempty"`
+	// Admin API path of the PDF.
+	PDFPath string `json:"pdfPath"`
+}
+
+type CertificateListResponse struct {
+	Certificates []CertificateResponse `json:"certificates"`
+	Total        int                   `json:"total"`
+}
+
+// generateCertificateCode returns 80 random bits as 16 base32 characters.
+func generateCertificateCode() string {
+	b := make([]byte, 10)
+	rand.Read(b)
+	return base32.StdEncoding.EncodeToString(b)
+}
+
+// formatCertificateCode groups a code for printing: ABCD-EFGH-IJKL-MNOP.
+func formatCertificateCode(code string) string {
+	var groups []string
+	for len(code) > 4 {
+		groups = append(groups, code[:4])
+		code = code[4:]
+	}
+	return strings.Join(append(groups, code), "-")
+}
+
+// normalizeCertificateCode accepts codes typed by hand: any case, with or
+// without dashes and spaces.
+func normalizeCertificateCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
+
+// String formats dimensions as printed on certificates: "100 x 81 cm".
+func (d *Dimensions) String() string {
+	if d.empty() {
+		return ""
+	}
+	var parts []string
+	for _, v := range []*float64{d.Height, d.Width, d.Depth} {
+		if v != nil {
+			parts = append(parts, strconv.FormatFloat(*v, 'f', -1, 64))
+		}
+	}
+	return strings.Join(parts, " x ") + " " + d.Unit
+}
+
+func certificateVerification(row db.CertificateRow) CertificateVerification {
+	return CertificateVerification{
+		Valid:      row.RevokedAt == nil,
+		Serial:     row.Serial,
+		Code:       formatCertificateCode(row.Code),
+		ArtworkID:  row.ArtworkID,
+		Title:      row.Title,
+		Technique:  row.Technique,
+		Dimensions: row.Dimensions,
+		StartDate:  row.StartDate,
+		EndDate:    row.EndDate,
+		IssuedAt:   row.IssuedAt,
+		RevokedAt:  row.RevokedAt,
+	}
+}
+
+func certificateResponse(row db.CertificateRow) CertificateResponse {
+	return CertificateResponse{
+		CertificateVerification: certificateVerification(row),
+		PrimaryImage:            row.PrimaryImage,
+		HolderName:              row.HolderName,
+		IssuedBy:                row.IssuedBy,
+		RevokedBy:               row.RevokedBy,
+		PDFPath:                 "/api/v1/admin/certificates/" + row.Serial + "/pdf",
+	}
+}
+
+// certificateVerifyURL is the address printed on a certificate.
+func certificateVerifyURL(r *http.Request, code string) string {
+	base := certificateVerifyBaseURL
+	if base == "" {
+		scheme := "https"
+		if r.TLS == nil && r.Header.Get("X-Forwarded-Proto") != "https" {
+			scheme = "http"
+		}
+		base = scheme + "://" + r.Host + "/api/v1/certificates/"
+	}
+	return strings.TrimRight(base, "/") + "/" + formatCertificateCode(code)
+}
+
+// getCertificate answers GET /api/v1/certificates/{code}. Revoked
+// certificates are still found, with valid=false.
+func getCertificate(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusNotFound, "Certificate not found")
+		return
+	}
+	code := normalizeCertificateCode(mux.Vars(r)["code"])
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	row, err := db.GetCertificateByCode(ctx, pgPool, code)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify certificate")
+		return
+	}
+	if row == nil {
+		respondWithError(w, http.StatusNotFound, "Certificate not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	json.NewEncoder(w).Encode(certificateVerification(*row))
+}
+
+// adminIssueCertificate certifies an artwork as it currently is.
+func adminIssueCertificate(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if !isSafeArtworkID(id) {
+		respondWithError(w, http.StatusBadRequest, "Invalid artwork id")
+		return
+	}
+	var payload adminCertificateCreate
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	payload.HolderName = strings.TrimSpace(payload.HolderName)
+	var errs validationErrors
+	errs.checkLength("holderName", payload.HolderName, maxHolderNameLength)
+	if err := errs.err(); err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+
+	artwork, err := getArtworkByID(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Artwork not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	row, err := db.InsertCertificate(ctx, pgPool, db.CertificateRow{
+		Code:         generateCertificateCode(),
+		ArtworkID:    id,
+		Title:        artwork.Title,
+		Technique:    artwork.Technique,
+		Dimensions:   artwork.Dimensions.String(),
+		StartDate:    artwork.StartDate,
+		EndDate:      artwork.EndDate,
+		PrimaryImage: artwork.PrimaryImage,
+		HolderName:   payload.HolderName,
+		IssuedBy:     actorFromContext(r.Context()),
+	}, certificateSerialPrefix)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to issue certificate")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(certificateResponse(*row))
+}
+
+func adminListCertificates(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if !isSafeArtworkID(id) {
+		respondWithError(w, http.StatusBadRequest, "Invalid artwork id")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	rows, err := db.ListCertificates(ctx, pgPool, id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list certificates")
+		return
+	}
+	certificates := make([]CertificateResponse, 0, len(rows))
+	for _, row := range rows {
+		certificates = append(certificates, certificateResponse(row))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CertificateListResponse{Certificates: certificates, Total: len(certificates)})
+}
+
+func adminRevokeCertificate(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	serial := mux.Vars(r)["serial"]
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	revoked, err := db.RevokeCertificate(ctx, pgPool, serial, actorFromContext(r.Context()))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke certificate")
+		return
+	}
+	if !revoked {
+		respondWithError(w, http.StatusNotFound, "Certificate not found or already revoked")
+		return
+	}
+	row, err := db.GetCertificateBySerial(ctx, pgPool, serial)
+	if err != nil || row == nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to re-read certificate")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(certificateResponse(*row))
+}
+
+// adminCertificatePDF renders a certificate. It can be downloaded again at
+// any time; revoked certificates are refused.
+func adminCertificatePDF(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	serial := mux.Vars(r)["serial"]
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	row, err := db.GetCertificateBySerial(ctx, pgPool, serial)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load certificate")
+		return
+	}
+	if row == nil {
+		respondWithError(w, http.StatusNotFound, "Certificate not found")
+		return
+	}
+	if row.RevokedAt != nil {
+		respondWithError(w, http.StatusGone, "Certificate has been revoked")
+		return
+	}
+
+	var thumbnail []byte
+	if row.PrimaryImage != "" {
+		ctxFile, cancelFile := context.WithTimeout(r.Context(), 10*time.Second)
+		if data, found, err := readArtworkFile(ctxFile, row.ArtworkID, row.PrimaryImage); err == nil && found {
+			thumbnail, _ = thumbnailJPEG(data, pdfThumbnailSize)
+		}
+		cancelFile()
+	}
+
+	var buf bytes.Buffer
+	if err := renderCertificate(&buf, *row, thumbnail, certificateVerifyURL(r, row.Code)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to render certificate")
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="certificado-`+row.Serial+`.pdf"`)
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Write(buf.Bytes())
+}
+
+// thumbnailJPEG scales an image down so its longest side is at most size
+// pixels and encodes it as JPEG.
+func thumbnailJPEG(data []byte, size int) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, h*size/w
+		} else {
+			w, h = w*size/h, size
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, max(w, 1), max(h, 1)))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, dst, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// placeImage draws a registered JPEG centered in a box and returns the
+// height it used.
+func placeImage(pdf *fpdf.Fpdf, name string, data []byte, x, y, boxW, boxH float64) float64 {
+	info := pdf.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: "JPG"}, bytes.NewReader(data))
+	if info == nil || pdf.Err() {
+		pdf.ClearError()
+		return 0
+	}
+	w, h := boxW, boxW*info.Height()/info.Width()
+	if h > boxH {
+		w, h = boxH*info.Width()/info.Height(), boxH
+	}
+	pdf.ImageOptions(name, x+(boxW-w)/2, y, w, h, false, fpdf.ImageOptions{ImageType: "JPG"}, 0, "")
+	return h
+}
+
+func renderCertificate(out io.Writer, cert db.CertificateRow, thumbnail []byte, verifyURL string) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Certificado de autenticidad "+cert.Serial, true)
+	pdf.SetAuthor(artistName, true)
+	pdf.SetAutoPageBreak(false, 0)
+	// Core fonts are cp1252; translate so accents print correctly.
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.AddPage()
+
+	pageW, pageH := pdf.GetPageSize()
+	const margin = 15.0
+	contentW := pageW - 2*margin
+
+	pdf.SetLineWidth(0.8)
+	pdf.Rect(margin-5, margin-5, pageW-2*(margin-5), pageH-2*(margin-5), "D")
+	pdf.SetLineWidth(0.2)
+	pdf.Rect(margin-3, margin-3, pageW-2*(margin-3), pageH-2*(margin-3), "D")
+
+	pdf.SetXY(margin, margin+8)
+	pdf.SetFont("Helvetica", "B", 22)
+	pdf.CellFormat(contentW, 10, tr("CERTIFICADO DE AUTENTICIDAD"), "", 1, "C", false, 0, "")
+	pdf.SetFont("Helvetica", "I", 11)
+	pdf.SetX(margin)
+	pdf.CellFormat(contentW, 6, "Certificate of Authenticity", "", 1, "C", false, 0, "")
+	pdf.SetFont("Helvetica", "", 13)
+	pdf.SetX(margin)
+	pdf.CellFormat(contentW, 9, tr(artistName), "", 1, "C", false, 0, "")
+
+	y := pdf.GetY() + 6
+	if thumbnail != nil {
+		y += placeImage(pdf, "primary", thumbnail, margin, y, contentW, 95) + 8
+	}
+
+	dates := cert.StartDate
+	if cert.EndDate != "" && cert.EndDate != cert.StartDate {
+		if dates != "" {
+			dates += " - "
+		}
+		dates += cert.EndDate
+	}
+	fields := []struct{ label, value string }{
+		{"Obra / Title", cert.Title},
+		{"Técnica / Technique", cert.Technique},
+		{"Dimensiones / Size", cert.Dimensions},
+		{"Fecha / Date", dates},
+		{"Propietario / Owner", cert.HolderName},
+		{"Número de serie / Serial", cert.Serial},
+		{"Código de verificación", formatCertificateCode(cert.Code)},
+	}
+	const labelW = 62.0
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		pdf.SetXY(margin+5, y)
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(labelW, 7, tr(f.label), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.MultiCell(contentW-labelW-10, 7, tr(f.value), "", "L", false)
+		y = pdf.GetY()
+	}
+
+	pdf.SetXY(margin+5, y+6)
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.MultiCell(contentW-10, 5, tr("Certifico que la obra descrita es original y de mi autoría. "+
+		"I certify that the work described above is an original work by my hand."), "", "L", false)
+
+	sigY := pageH - margin - 45
+	pdf.Line(pageW-margin-75, sigY, pageW-margin-5, sigY)
+	pdf.SetXY(pageW-margin-75, sigY+1)
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(70, 5, tr(artistName), "", 2, "C", false, 0, "")
+	pdf.CellFormat(70, 5, cert.IssuedAt.Format(dateLayout), "", 0, "C", false, 0, "")
+
+	pdf.SetXY(margin, pageH-margin-18)
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.CellFormat(contentW, 4, tr("Verifique la autenticidad de este certificado en / Verify at:"), "", 2, "C", false, 0, "")
+	pdf.SetTextColor(40, 60, 140)
+	pdf.CellFormat(contentW, 4, verifyURL, "", 0, "C", false, 0, verifyURL)
+	pdf.SetTextColor(0, 0, 0)
+
+	return pdf.Output(out)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+
+	"alexis-art-backend/money"
+)
+
+// catalogEntry is one artwork as printed in the gallery catalog.
+type catalogEntry struct {
+	Title         string
+	Description   string
+	Technique     string
+	Size          string
+	StatusOrPrice string
+	// The PDF prints titles in capitals, so their original casing is lost and
+	// they are only used for matching.
+	titleFromCaps bool
+}
+
+// Page header repeated on every page of the PDF text export.
+const catalogPageMarker = "1819ARTGALLERY"
+
+var (
+	sizePattern  = regexp.MustCompile(`(?i)^(\d+(?:[.,]\d+)?)\s*[x×]\s*(\d+(?:[.,]\d+)?)(?:\s*[x×]\s*(\d+(?:[.,]\d+)?))?\s*(cm|in)?$`)
+	pricePattern = regexp.MustCompile(`^(€|\$|US\$|EUR|USD|CLP)?\s*(\d{1,3}(?:[.\s]\d{3})*|\d+)(?:,(\d{1,2}))?\s*(€|\$|EUR|USD|CLP)?$`)
+	tsField      = regexp.MustCompile(`(\w+):\s*'((?:[^'\\]|\\.)*)'`)
+)
+
+// loadCatalog parses either the plain-text export of the PDF (.txt) or the
+// frontend's catalogo.ts.
+func loadCatalog(path string) ([]catalogEntry, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".txt":
+		return parseCatalogText(string(b)), nil
+	case ".ts", ".js":
+		return parseCatalogTS(string(b))
+	default:
+		return nil, fmt.Errorf("unsupported catalog format %q (want .txt or .ts)", filepath.Ext(path))
+	}
+}
+
+// parseCatalogText reads the PDF text export. Each artwork is printed as its
+// description followed by four lines: TITLE, technique, size and status or
+// price. Entries are found by their size line.
+func parseCatalogText(text string) []catalogEntry {
+	var lines []string
+	for _, ln := range strings.Split(text, "\n") {
+		if ln = strings.TrimSpace(ln); ln != "" {
+			lines = append(lines, ln)
+		}
+	}
+
+	var entries []catalogEntry
+	var buf []string
+	for k := 0; k < len(lines); k++ {
+		ln := lines[k]
+		if ln == catalogPageMarker {
+			buf = buf[:0]
+			continue
+		}
+		if sizePattern.MatchString(ln) && len(buf) >= 2 && k+1 < len(lines) {
+			entries = append(entries, catalogEntry{
+				Title:         sentenceCase(buf[len(buf)-2]),
+				Technique:     buf[len(buf)-1],
+				Size:          ln,
+				StatusOrPrice: lines[k+1],
+				Description:   strings.Join(buf[:len(buf)-2], " "),
+				titleFromCaps: strings.ToUpper(buf[len(buf)-2]) == buf[len(buf)-2],
+			})
+			buf = buf[:0]
+			k++
+			continue
+		}
+		buf = append(buf, ln)
+	}
+	return entries
+}
+
+// parseCatalogTS extracts the artworks array of catalogo.ts. It only
+// understands the single-quoted string fields used there, which is all the
+// file contains.
+func parseCatalogTS(src string) ([]catalogEntry, error) {
+	start := strings.Index(src, "artworks:")
+	if start < 0 {
+		return nil, fmt.Errorf("no artworks array found")
+	}
+
+	var entries []catalogEntry
+	var cur *catalogEntry
+	for _, m := range tsField.FindAllStringSubmatch(src[start:], -1) {
+		value := unescapeTS(m[2])
+		if m[1] == "title" {
+			entries = append(entries, catalogEntry{})
+			cur = &entries[len(entries)-1]
+		}
+		if cur == nil {
+			continue
+		}
+		switch m[1] {
+		case "title":
+			cur.Title = value
+		case "description":
+			cur.Description = value
+		case "technique":
+			cur.Technique = value
+		case "size":
+			cur.Size = value
+		case "statusOrPrice":
+			cur.StatusOrPrice = value
+		}
+	}
+	return entries, nil
+}
+
+func unescapeTS(s string) string {
+	r := strings.NewReplacer(`\'`, `'`, `\"`, `"`, `\n`, "\n", `\\`, `\`)
+	return r.Replace(s)
+}
+
+// sentenceCase turns the upper-case titles of the PDF into "Buscando un lugar".
+func sentenceCase(s string) string {
+	if strings.ToUpper(s) != s {
+		return s
+	}
+	r := []rune(strings.ToLower(s))
+	if len(r) > 0 {
+		r[0] = unicode.ToUpper(r[0])
+	}
+	return string(r)
+}
+
+// parseSize reads "80 x 100 cm" as height x width (x depth).
+func parseSize(s string) (height, width, depth *float64, unit string, ok bool) {
+	m := sizePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return nil, nil, nil, "", false
+	}
+	num := func(v string) *float64 {
+		if v == "" {
+			return nil
+		}
+		f, err := strconv.ParseFloat(strings.ReplaceAll(v, ",", "."), 64)
+		if err != nil {
+			return nil
+		}
+		return &f
+	}
+	unit = strings.ToLower(m[4])
+	if unit == "" {
+		unit = "cm"
+	}
+	return num(m[1]), num(m[2]), num(m[3]), unit, true
+}
+
+// parseStatusOrPrice maps the last catalog line to an availability and,
+// for works on sale, a price.
+func parseStatusOrPrice(s string) (availability string, amount *int64, currency string, ok bool) {
+	s = strings.TrimSpace(s)
+	switch normalize(s) {
+	case "vendido", "vendida", "sold":
+		return "sold", nil, "", true
+	case "reservado", "reservada", "reserved":
+		return "reserved", nil, "", true
+	case "no disponible", "no a la venta", "not for sale":
+		return "not_for_sale", nil, "", true
+	case "coleccion privada", "private collection":
+		return "private_collection", nil, "", true
+	}
+
+	m := pricePattern.FindStringSubmatch(s)
+	if m == nil || (m[1] == "" && m[4] == "") {
+		return "", nil, "", false
+	}
+	digits := strings.NewReplacer(".", "", " ", "").Replace(m[2])
+	if m[3] != "" {
+		digits += "." + m[3]
+	}
+	a, err := money.Parse(digits)
+	if err != nil {
+		return "", nil, "", false
+	}
+	cents := int64(a)
+	symbol := m[1]
+	if symbol == "" {
+		symbol = m[4]
+	}
+	switch symbol {
+	case "€", "EUR":
+		currency = "EUR"
+	case "$", "US$", "USD":
+		currency = "USD"
+	case "CLP":
+		currency = "CLP"
+	}
+	return "available", &cents, currency, true
+}
+
+var accents = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// normalize lower-cases s, strips accents and reduces everything that is not
+// a letter or digit to single spaces, so "La Unión" and "la-union" compare equal.
+func normalize(s string) string {
+	s, _, _ = transform.String(accents, strings.ToLower(s))
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteRune(r)
+			space = false
+		} else {
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// similarity is 1 - normalized Levenshtein distance of two normalized strings.
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return 1 - float64(prev[len(rb)])/float64(max(len(ra), len(rb)))
+}
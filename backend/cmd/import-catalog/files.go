@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// artworkFiles reads the artwork folders the server reads: the bucket when
+// one is configured (the same variables as the server), else the local
+// artworks folder.
+type artworkFiles interface {
+	// ids lists the artwork folders.
+	ids(ctx context.Context) ([]string, error)
+	// names lists the files of one artwork, sorted; subfolders are ignored.
+	names(ctx context.Context, id string) ([]string, error)
+	read(ctx context.Context, id, name string) ([]byte, error)
+	String() string
+}
+
+// maxTextFile is what the server reads of meta.json and text files in a bucket.
+const maxTextFile = 1 << 20
+
+func newArtworkFiles(dir string) (artworkFiles, error) {
+	bucket := envAny("BUCKET_NAME", "BUCKET", "ARTWORKS_BUCKET", "OBJECT_STORAGE_BUCKET", "AWS_S3_BUCKET")
+	if bucket == "" {
+		return dirFiles(dir), nil
+	}
+
+	region := envAny("BUCKET_REGION", "REGION", "AWS_REGION", "S3_REGION", "OBJECT_STORAGE_REGION")
+	if region == "" || strings.EqualFold(region, "auto") {
+		region = "us-east-1"
+	}
+	endpoint := envAny("BUCKET_ENDPOINT", "ENDPOINT", "AWS_ENDPOINT_URL_S3", "S3_ENDPOINT", "OBJECT_STORAGE_ENDPOINT")
+	accessKey := envAny("BUCKET_ACCESS_KEY_ID", "ACCESS_KEY_ID", "AWS_ACCESS_KEY_ID", "S3_ACCESS_KEY_ID", "OBJECT_STORAGE_ACCESS_KEY_ID")
+	secretKey := envAny("BUCKET_SECRET_ACCESS_KEY", "SECRET_ACCESS_KEY", "AWS_SECRET_ACCESS_KEY", "S3_SECRET_ACCESS_KEY", "OBJECT_STORAGE_SECRET_ACCESS_KEY")
+
+	opts := []func(*config.LoadOptions) error{config.WithRegion(region)}
+	if accessKey != "" && secretKey != "" {
+		opts = append(opts, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessKey, secretKey, "")))
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("load AWS config: %w", err)
+	}
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = true
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(strings.TrimRight(endpoint, "/"))
+		}
+	})
+	return bucketFiles{client: client, bucket: bucket}, nil
+}
+
+func envAny(keys ...string) string {
+	for _, k := range keys {
+		if v := strings.TrimSpace(os.Getenv(k)); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+type dirFiles string
+
+func (d dirFiles) String() string { return filepath.Clean(string(d)) }
+
+func (d dirFiles) ids(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(string(d))
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			ids = append(ids, e.Name())
+		}
+	}
+	return ids, nil
+}
+
+func (d dirFiles) names(ctx context.Context, id string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(string(d), id))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+func (d dirFiles) read(ctx context.Context, id, name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(string(d), id, name))
+}
+
+type bucketFiles struct {
+	client *s3.Client
+	bucket string
+}
+
+func (b bucketFiles) String() string { return "bucket " + b.bucket }
+
+// list returns the common prefixes (delimiter "/") and the keys under prefix.
+func (b bucketFiles) list(ctx context.Context, prefix string) (prefixes, keys []string, err error) {
+	var token *string
+	for {
+		out, err := b.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(b.bucket),
+			Prefix:            aws.String(prefix),
+			Delimiter:         aws.String("/"),
+			ContinuationToken: token,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, p := range out.CommonPrefixes {
+			prefixes = append(prefixes, aws.ToString(p.Prefix))
+		}
+		for _, obj := range out.Contents {
+			keys = append(keys, aws.ToString(obj.Key))
+		}
+		if !aws.ToBool(out.IsTruncated) || out.NextContinuationToken == nil {
+			return prefixes, keys, nil
+		}
+		token = out.NextContinuationToken
+	}
+}
+
+func (b bucketFiles) ids(ctx context.Context) ([]string, error) {
+	prefixes, _, err := b.list(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, p := range prefixes {
+		if id := strings.TrimSuffix(p, "/"); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (b bucketFiles) names(ctx context.Context, id string) ([]string, error) {
+	_, keys, err := b.list(ctx, id+"/")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, k := range keys {
+		if name := strings.TrimPrefix(k, id+"/"); name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (b bucketFiles) read(ctx context.Context, id, name string) ([]byte, error) {
+	out, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(id + "/" + name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	return io.ReadAll(io.LimitReader(out.Body, maxTextFile))
+}
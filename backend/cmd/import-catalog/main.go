@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+
+	"alexis-art-backend/db"
+	"alexis-art-backend/money"
+)
+
+// candidate is an existing artwork an entry can be matched to.
+type candidate struct {
+	id     string
+	row    db.ArtworkRow  // the values the API shows, see effectiveRow
+	stored *db.ArtworkRow // nil when the artwork has no row yet
+}
+
+type match struct {
+	entry  catalogEntry
+	target *candidate
+	score  float64
+	// Runner-up, reported when it is too close to tell the two apart.
+	second      *candidate
+	secondScore float64
+}
+
+// fieldUpdate is one value the catalog would write.
+type fieldUpdate struct {
+	field    string
+	current  string
+	proposed string
+	conflict bool // current is set and differs; only written with -overwrite
+}
+
+func main() {
+	_ = godotenv.Load()
+
+	dryRun := flag.Bool("dry-run", false, "only print the report, do not write to Postgres")
+	overwrite := flag.Bool("overwrite", false, "replace values already set in Postgres when they differ from the catalog")
+	minScore := flag.Float64("min-score", 0.8, "minimum title similarity (0-1) to accept a match")
+	artworksDir := flag.String("artworks", envOr("ARTWORKS_DIR", "../art"), "artworks folder, read for IDs without a Postgres row and for meta.json values (the bucket instead when one is configured)")
+	flag.Usage = func() {
+		fmt.Println("Usage: import-catalog [flags] <catalog-file>")
+		fmt.Println("Example: import-catalog -dry-run ../frontend/content/CATALOGO-ALEXIS.txt")
+		fmt.Println()
+		fmt.Println("The catalog can be the PDF text export (.txt) or catalogo.ts.")
+		fmt.Println("Requires DATABASE_URL (optional with -dry-run).")
+		fmt.Println()
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	entries, err := loadCatalog(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to read catalog: %v", err)
+	}
+	if len(entries) == 0 {
+		log.Fatalf("No artworks found in %s", flag.Arg(0))
+	}
+
+	ctx := context.Background()
+	var pool *pgxpool.Pool
+	if databaseURL := strings.TrimSpace(os.Getenv("DATABASE_URL")); databaseURL != "" {
+		ctxConn, cancel := context.WithTimeout(ctx, 10*time.Second)
+		pool, err = db.Connect(ctxConn, databaseURL)
+		cancel()
+		if err != nil {
+			log.Fatalf("Failed to connect to Postgres: %v", err)
+		}
+		defer pool.Close()
+		// A dry run only reads, so it leaves the schema as it is too.
+		if !*dryRun {
+			if err := db.Migrate(ctx, pool, "./db/migrations"); err != nil {
+				log.Fatalf("Failed to run migrations: %v", err)
+			}
+		}
+	} else if !*dryRun {
+		log.Fatal("DATABASE_URL is required (or use -dry-run)")
+	}
+
+	files, err := newArtworkFiles(*artworksDir)
+	if err != nil {
+		log.Fatalf("Failed to open artworks: %v", err)
+	}
+	candidates, err := loadCandidates(ctx, pool, files)
+	if err != nil {
+		log.Fatalf("Failed to list artworks: %v", err)
+	}
+	if len(candidates) == 0 {
+		log.Fatal("No existing artworks to match against")
+	}
+
+	fmt.Printf("Catalog: %s (%d entries)\n", flag.Arg(0), len(entries))
+	fmt.Printf("Artworks: %d (files from %s)\n", len(candidates), files)
+	if *dryRun {
+		fmt.Println("Dry run: nothing will be written")
+	}
+	fmt.Println()
+
+	matches := make([]match, len(entries))
+	for i, e := range entries {
+		matches[i] = bestMatch(e, candidates)
+	}
+
+	// An artwork claimed by more than one entry is a conflict for all of them.
+	claims := map[string]int{}
+	for _, m := range matches {
+		if m.target != nil && m.score >= *minScore {
+			claims[m.target.id]++
+		}
+	}
+
+	var matched, updated, conflicts, unmatched, failed int
+	for _, m := range matches {
+		switch {
+		case m.target == nil || m.score < *minScore:
+			unmatched++
+			fmt.Printf("[UNMATCHED] %q", m.entry.Title)
+			if m.target != nil {
+				fmt.Printf(" (closest: %s, score %.2f)", m.target.id, m.score)
+			}
+			fmt.Println()
+			continue
+		case claims[m.target.id] > 1:
+			conflicts++
+			fmt.Printf("[CONFLICT] %q -> %s: artwork matched by %d catalog entries, skipped\n", m.entry.Title, m.target.id, claims[m.target.id])
+			continue
+		case m.second != nil && m.score-m.secondScore < 0.05:
+			conflicts++
+			fmt.Printf("[CONFLICT] %q: ambiguous between %s (%.2f) and %s (%.2f), skipped\n", m.entry.Title, m.target.id, m.score, m.second.id, m.secondScore)
+			continue
+		}
+
+		matched++
+		row, updates, problems := plan(m, *overwrite)
+		fmt.Printf("[MATCH] %q -> %s (score %.2f)\n", m.entry.Title, m.target.id, m.score)
+		for _, p := range problems {
+			fmt.Printf("    ! %s\n", p)
+		}
+		changed := false
+		for _, u := range updates {
+			switch {
+			case u.conflict && !*overwrite:
+				conflicts++
+				fmt.Printf("    ~ %s: keeping %q (catalog: %q)\n", u.field, u.current, u.proposed)
+			default:
+				changed = true
+				fmt.Printf("    + %s: %q -> %q\n", u.field, u.current, u.proposed)
+			}
+		}
+		if !changed {
+			fmt.Println("    = up to date")
+			continue
+		}
+		if *dryRun {
+			continue
+		}
+
+		if err := writeArtwork(ctx, pool, m.target, row, flag.Arg(0)); err != nil {
+			failed++
+			if db.IsUniqueViolation(err) {
+				fmt.Printf("    ERROR: title %q already used by another artwork\n", row.Title)
+			} else {
+				fmt.Printf("    ERROR: %v\n", err)
+			}
+			continue
+		}
+		updated++
+	}
+
+	fmt.Println()
+	fmt.Printf("Matched: %d\n", matched)
+	fmt.Printf("Conflicts: %d\n", conflicts)
+	fmt.Printf("Unmatched: %d\n", unmatched)
+	if !*dryRun {
+		fmt.Printf("Updated: %d\n", updated)
+		fmt.Printf("Failed: %d\n", failed)
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// loadCandidates lists the artworks known to Postgres plus the artwork
+// folders (artworks that were never edited have no row yet).
+func loadCandidates(ctx context.Context, pool *pgxpool.Pool, files artworkFiles) ([]*candidate, error) {
+	rows := map[string]*db.ArtworkRow{}
+	if pool != nil {
+		ctxDB, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		list, err := db.ListArtworks(ctxDB, pool)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			rows[list[i].ID] = &list[i]
+		}
+	}
+	if ids, err := files.ids(ctx); err == nil {
+		for _, id := range ids {
+			if _, ok := rows[id]; !ok {
+				rows[id] = nil
+			}
+		}
+	} else if pool == nil {
+		return nil, fmt.Errorf("read %s: %w", files, err)
+	}
+
+	byID := map[string]*candidate{}
+	for id, row := range rows {
+		byID[id] = &candidate{id: id, row: effectiveRow(ctx, files, id, row), stored: row}
+	}
+
+	out := make([]*candidate, 0, len(byID))
+	for _, c := range byID {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].id < out[j].id })
+	return out, nil
+}
+
+// bestMatch scores an entry against every artwork by its title and by its
+// ID (slugs are titles with dashes), keeping the best two.
+func bestMatch(e catalogEntry, candidates []*candidate) match {
+	title := normalize(e.Title)
+	m := match{entry: e}
+	for _, c := range candidates {
+		score := max(similarity(title, normalize(c.id)), similarity(title, normalize(c.row.Title)))
+		switch {
+		case m.target == nil || score > m.score:
+			m.second, m.secondScore = m.target, m.score
+			m.target, m.score = c, score
+		case m.second == nil || score > m.secondScore:
+			m.second, m.secondScore = c, score
+		}
+	}
+	return m
+}
+
+// plan builds the row to upsert for a match, listing each field it would set.
+// It starts from the values the API shows, so fields set only in meta.json
+// are compared against and written back unchanged. Fields already set to
+// another value are only replaced with overwrite.
+func plan(m match, overwrite bool) (db.ArtworkRow, []fieldUpdate, []string) {
+	row := m.target.row
+
+	var updates []fieldUpdate
+	var problems []string
+	set := func(field, current, proposed string, apply func()) {
+		if proposed == "" || sameText(current, proposed) {
+			return
+		}
+		u := fieldUpdate{field: field, current: current, proposed: proposed, conflict: current != ""}
+		if !u.conflict || overwrite {
+			apply()
+		}
+		updates = append(updates, u)
+	}
+
+	e := m.entry
+	if !e.titleFromCaps {
+		set("title", row.Title, e.Title, func() { row.Title = e.Title })
+	}
+	set("detalle", row.Detalle, e.Description, func() { row.Detalle = e.Description })
+	set("technique", row.Technique, e.Technique, func() { row.Technique = e.Technique })
+
+	if e.Size != "" {
+		if h, w, d, unit, ok := parseSize(e.Size); ok {
+			current := formatSize(row.Height, row.Width, row.Depth, row.DimensionUnit)
+			set("dimensions", current, formatSize(h, w, d, unit), func() {
+				row.Height, row.Width, row.Depth, row.DimensionUnit = h, w, d, unit
+			})
+		} else {
+			problems = append(problems, fmt.Sprintf("size %q not understood, skipped", e.Size))
+		}
+	}
+
+	if e.StatusOrPrice != "" {
+		if availability, amount, currency, ok := parseStatusOrPrice(e.StatusOrPrice); ok {
+			set("availability", row.Availability, availability, func() { row.Availability = availability })
+			if amount != nil {
+				current := formatPrice(row.Price, row.Currency)
+				set("price", current, formatPrice(amount, currency), func() {
+					row.Price, row.Currency = amount, currency
+				})
+			}
+		} else {
+			problems = append(problems, fmt.Sprintf("status/price %q not understood, skipped", e.StatusOrPrice))
+		}
+	}
+	return row, updates, problems
+}
+
+// artworkMeta is the part of an artwork's meta.json the catalog can change.
+type artworkMeta struct {
+	PaintedLocation string      `json:"paintedLocation"`
+	StartDate       string      `json:"startDate"`
+	EndDate         string      `json:"endDate"`
+	InProgress      bool        `json:"inProgress"`
+	Technique       string      `json:"technique"`
+	Dimensions      *dimensions `json:"dimensions"`
+	Price           *price      `json:"price"`
+	Availability    string      `json:"availability"`
+}
+
+// dimensions and price have the JSON shape of the server's types.
+type dimensions struct {
+	Height *float64 `json:"height,omitempty"`
+	Width  *float64 `json:"width,omitempty"`
+	Depth  *float64 `json:"depth,omitempty"`
+	Unit   string   `json:"unit,omitempty"`
+}
+
+type price struct {
+	Amount   money.Amount `json:"amount"`
+	Currency string       `json:"currency"`
+}
+
+// effectiveRow returns the values the API shows for an artwork: its Postgres
+// row (nil when it has none) over the meta.json and detalle file in its
+// folder, with the precedence the server's overlayArtworkRow uses. Status is
+// left empty so upserting the row keeps the current one.
+func effectiveRow(ctx context.Context, folders artworkFiles, id string, row *db.ArtworkRow) db.ArtworkRow {
+	files := db.ArtworkRow{ID: id}
+	names, _ := folders.names(ctx, id)
+	var m artworkMeta
+	if content, ok := readFirst(ctx, folders, id, names, func(base, ext string) bool {
+		return base == "meta" && ext == ".json"
+	}); ok && json.Unmarshal(content, &m) == nil {
+		files.PaintedLocation = strings.TrimSpace(m.PaintedLocation)
+		files.StartDate = parseDate(m.StartDate)
+		files.EndDate = parseDate(m.EndDate)
+		files.InProgress = m.InProgress
+		files.Technique = strings.TrimSpace(m.Technique)
+		if d := m.Dimensions; d != nil {
+			files.Height, files.Width, files.Depth, files.DimensionUnit = d.Height, d.Width, d.Depth, d.Unit
+		}
+		if p := m.Price; p != nil {
+			cents := int64(p.Amount)
+			files.Price, files.Currency = &cents, p.Currency
+		}
+		files.Availability = strings.TrimSpace(m.Availability)
+	}
+	if content, ok := readFirst(ctx, folders, id, names, func(base, ext string) bool {
+		return (ext == ".txt" || ext == ".md") && (strings.HasPrefix(base, "detalle") || strings.HasPrefix(base, "detail"))
+	}); ok {
+		files.Detalle = string(content)
+	}
+	if row == nil {
+		return files
+	}
+
+	// Postgres wins, except where the server falls back to the files.
+	r := *row
+	r.Status = ""
+	if r.StartDate == nil {
+		r.StartDate = files.StartDate
+	}
+	if r.EndDate == nil {
+		r.EndDate = files.EndDate
+	}
+	if r.Detalle == "" {
+		r.Detalle = files.Detalle
+	}
+	if r.Technique == "" {
+		r.Technique = files.Technique
+	}
+	if r.Height == nil && r.Width == nil && r.Depth == nil {
+		r.Height, r.Width, r.Depth, r.DimensionUnit = files.Height, files.Width, files.Depth, files.DimensionUnit
+	}
+	if r.Price == nil {
+		r.Price, r.Currency = files.Price, files.Currency
+	}
+	if r.Availability == "" {
+		r.Availability = files.Availability
+	}
+	return r
+}
+
+// readFirst reads the first of names whose lower-cased base name and
+// extension match, the way the server picks meta.json and the detalle file.
+func readFirst(ctx context.Context, files artworkFiles, id string, names []string, match func(base, ext string) bool) ([]byte, bool) {
+	for _, name := range names {
+		ext := strings.ToLower(filepath.Ext(name))
+		if !match(strings.ToLower(strings.TrimSuffix(name, ext)), ext) {
+			continue
+		}
+		if content, err := files.read(ctx, id, name); err == nil {
+			return content, true
+		}
+	}
+	return nil, false
+}
+
+func parseDate(s string) *time.Time {
+	t, err := time.Parse("2006-01-02", strings.TrimSpace(s))
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// sameText compares ignoring whitespace differences (the PDF export wraps lines).
+func sameText(a, b string) bool {
+	return strings.Join(strings.Fields(a), " ") == strings.Join(strings.Fields(b), " ")
+}
+
+func formatSize(h, w, d *float64, unit string) string {
+	var parts []string
+	for _, v := range []*float64{h, w, d} {
+		if v != nil {
+			parts = append(parts, fmt.Sprintf("%g", *v))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, " x ") + " " + unit
+}
+
+func formatPrice(cents *int64, currency string) string {
+	if cents == nil {
+		return ""
+	}
+	return strings.TrimSpace(money.Amount(*cents).String() + " " + currency)
+}
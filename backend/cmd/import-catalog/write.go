@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"alexis-art-backend/db"
+	"alexis-art-backend/money"
+)
+
+// importActor is who the revisions and audit entries of an import are by.
+const importActor = "import"
+
+// errChangedMeanwhile is returned when the artwork was changed between
+// planning and writing; running the import again plans against the new values.
+var errChangedMeanwhile = errors.New("artwork changed while importing, run again")
+
+// editableFields is a row in the JSON shape of the editable fields in the
+// server's revision snapshots and audit entries (adminArtworkUpdate).
+type editableFields struct {
+	Title           string      `json:"title"`
+	PaintedLocation string      `json:"paintedLocation"`
+	StartDate       string      `json:"startDate"`
+	EndDate         string      `json:"endDate"`
+	InProgress      bool        `json:"inProgress"`
+	Detalle         string      `json:"detalle"`
+	PrimaryImage    string      `json:"primaryImage"`
+	Technique       string      `json:"technique"`
+	Dimensions      *dimensions `json:"dimensions"`
+	Price           *price      `json:"price"`
+	Availability    string      `json:"availability"`
+}
+
+func editableFieldsOf(row db.ArtworkRow) editableFields {
+	f := editableFields{
+		Title:           row.Title,
+		PaintedLocation: row.PaintedLocation,
+		InProgress:      row.InProgress,
+		Detalle:         row.Detalle,
+		PrimaryImage:    row.PrimaryImage,
+		Technique:       row.Technique,
+		Availability:    row.Availability,
+	}
+	if row.StartDate != nil {
+		f.StartDate = row.StartDate.Format("2006-01-02")
+	}
+	if row.EndDate != nil {
+		f.EndDate = row.EndDate.Format("2006-01-02")
+	}
+	if row.Height != nil || row.Width != nil || row.Depth != nil {
+		f.Dimensions = &dimensions{Height: row.Height, Width: row.Width, Depth: row.Depth, Unit: row.DimensionUnit}
+	}
+	if row.Price != nil {
+		f.Price = &price{Amount: money.Amount(*row.Price), Currency: row.Currency}
+	}
+	return f
+}
+
+// snapshotWith returns base, a revision snapshot, with the editable fields of
+// row. The import only changes those, so the rest of the latest snapshot
+// (status, images, tags...) is carried over.
+func snapshotWith(base map[string]any, row db.ArtworkRow) map[string]any {
+	s := maps.Clone(base)
+	b, _ := json.Marshal(editableFieldsOf(row))
+	var fields map[string]any
+	json.Unmarshal(b, &fields)
+	maps.Copy(s, fields)
+	return s
+}
+
+// writeArtwork stores the planned row of a candidate the way the admin API
+// stores a change: in one transaction with the artwork row locked, so server
+// writes to it wait, together with a revision and an audit entry by "import".
+func writeArtwork(ctx context.Context, pool *pgxpool.Pool, c *candidate, row db.ArtworkRow, catalog string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	current, err := db.LockArtworkRow(ctx, tx, c.id)
+	if err != nil {
+		return err
+	}
+	if (current == nil) != (c.stored == nil) || (current != nil && !current.UpdatedAt.Equal(c.stored.UpdatedAt)) {
+		return errChangedMeanwhile
+	}
+
+	base := map[string]any{}
+	latest, err := db.LatestRevision(ctx, tx, c.id)
+	if err != nil {
+		return err
+	}
+	if latest != nil {
+		json.Unmarshal(latest.Snapshot, &base)
+	}
+	before, after := snapshotWith(base, c.row), snapshotWith(base, row)
+
+	if err := db.UpsertArtwork(ctx, tx, row); err != nil {
+		return err
+	}
+	if err := db.RecordRevision(ctx, tx, c.id, importActor, "import", before, after, false); err != nil {
+		return err
+	}
+	beforeJSON, _ := json.Marshal(before)
+	afterJSON, _ := json.Marshal(after)
+	if err := db.InsertAudit(ctx, tx, db.AuditRow{
+		Actor:     importActor,
+		UserAgent: "import-catalog",
+		Method:    "IMPORT",
+		Action:    "IMPORT catalog",
+		Path:      catalog,
+		ArtworkID: c.id,
+		Status:    http.StatusOK,
+		Before:    beforeJSON,
+		After:     afterJSON,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"alexis-art-backend/media"
+)
+
+// apiDestination uploads through the admin API with an API key (scopes
+// read:drafts and write:media) instead of bucket credentials. The API only
+// takes images, and renames them "<unix-nanos>_<name><ext>".
+type apiDestination struct {
+	baseURL string
+	key     string
+	client  *http.Client
+	// images caches the images of each artwork read from the API; nil means
+	// the artwork does not exist on the server.
+	images map[string][]string
+}
+
+func newAPIDestination(baseURL, key string) *apiDestination {
+	return &apiDestination{
+		baseURL: strings.TrimRight(baseURL, "/") + "/api/v1/admin",
+		key:     key,
+		client:  &http.Client{Timeout: 2 * time.Minute},
+		images:  map[string][]string{},
+	}
+}
+
+func (d *apiDestination) String() string { return "API " + d.baseURL }
+
+func (d *apiDestination) artworkImages(ctx context.Context, id string) ([]string, error) {
+	if images, ok := d.images[id]; ok {
+		return images, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.baseURL+"/artworks/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	var artwork struct {
+		Images []string `json:"images"`
+	}
+	status, err := d.do(req, &artwork)
+	if status == http.StatusNotFound {
+		d.images[id] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if artwork.Images == nil {
+		artwork.Images = []string{}
+	}
+	d.images[id] = artwork.Images
+	return artwork.Images, nil
+}
+
+func (d *apiDestination) exists(ctx context.Context, id, filename string) (bool, error) {
+	if !isImage(filename) {
+		return false, skipReason("the API only takes images")
+	}
+	images, err := d.artworkImages(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if images == nil {
+		return false, skipReason("artwork not found on the server")
+	}
+	for _, image := range images {
+		if uploadedAs(image, filename) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (d *apiDestination) upload(ctx context.Context, id, filename, filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="image"; filename="%s"`, quoteEscaper.Replace(filename)))
+	h.Set("Content-Type", media.ContentType(filename))
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.baseURL+"/artworks/"+url.PathEscape(id)+"/images", &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	var artwork struct {
+		Images []string `json:"images"`
+	}
+	if _, err := d.do(req, &artwork); err != nil {
+		return err
+	}
+	d.images[id] = artwork.Images
+	return nil
+}
+
+// do sends req with the API key and decodes a 2xx JSON answer into out. Other
+// answers become an error with the message of the API.
+func (d *apiDestination) do(req *http.Request, out any) (int, error) {
+	req.Header.Set("Authorization", "Bearer "+d.key)
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&apiErr)
+		if apiErr.Error == "" {
+			apiErr.Error = http.StatusText(resp.StatusCode)
+		}
+		return resp.StatusCode, fmt.Errorf("%s %s: %d %s", req.Method, req.URL.Path, resp.StatusCode, apiErr.Error)
+	}
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(out)
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func isImage(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".jpg", ".jpeg", ".png", ".gif":
+		return true
+	}
+	return false
+}
+
+// uploadedAs reports whether stored, an image of the artwork, is filename:
+// copied as is, or uploaded through the API under the upload naming scheme.
+func uploadedAs(stored, filename string) bool {
+	if stored == filename {
+		return true
+	}
+	if !media.IsImmutable(stored) {
+		return false
+	}
+	_, name, _ := strings.Cut(stored, "_")
+	return name == media.SafeName(filename)+filepath.Ext(filename)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -13,8 +14,24 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/joho/godotenv"
+
+	"alexis-art-backend/media"
 )
 
+// destination is where the artwork files are copied to.
+type destination interface {
+	// exists reports whether filename of artwork id is already there; a
+	// skipReason error means the file cannot be copied there.
+	exists(ctx context.Context, id, filename string) (bool, error)
+	upload(ctx context.Context, id, filename, filePath string) error
+	String() string
+}
+
+// skipReason is why a file is skipped without being an error.
+type skipReason string
+
+func (s skipReason) Error() string { return string(s) }
+
 func main() {
 	_ = godotenv.Load()
 
@@ -22,11 +39,15 @@ func main() {
 		fmt.Println("Usage: migrate <source-directory>")
 		fmt.Println("Example: migrate /home/roz/Pictures/art")
 		fmt.Println()
-		fmt.Println("Required environment variables:")
+		fmt.Println("Upload straight to the bucket with:")
 		fmt.Println("  BUCKET_NAME or BUCKET - S3 bucket name")
 		fmt.Println("  BUCKET_ENDPOINT or ENDPOINT - S3 endpoint URL")
 		fmt.Println("  ACCESS_KEY_ID - S3 access key")
 		fmt.Println("  SECRET_ACCESS_KEY - S3 secret key")
+		fmt.Println()
+		fmt.Println("Or through the admin API (images only) with:")
+		fmt.Println("  API_URL - API base URL, e.g. https://api.example.com")
+		fmt.Println("  API_KEY - API key with the read:drafts and write:media scopes")
 		os.Exit(1)
 	}
 
@@ -38,43 +59,19 @@ func main() {
 		log.Fatalf("Source directory does not exist: %s", sourceDir)
 	}
 
-	// Get S3 config from environment
-	bucket := envAny("BUCKET_NAME", "BUCKET", "ARTWORKS_BUCKET")
-	endpoint := envAny("BUCKET_ENDPOINT", "ENDPOINT", "S3_ENDPOINT")
-	accessKey := envAny("BUCKET_ACCESS_KEY_ID", "ACCESS_KEY_ID", "AWS_ACCESS_KEY_ID")
-	secretKey := envAny("BUCKET_SECRET_ACCESS_KEY", "SECRET_ACCESS_KEY", "AWS_SECRET_ACCESS_KEY")
-	region := envAny("BUCKET_REGION", "REGION", "AWS_REGION")
-
-	if bucket == "" {
-		log.Fatal("BUCKET_NAME or BUCKET environment variable is required")
-	}
-	if endpoint == "" {
-		log.Fatal("BUCKET_ENDPOINT or ENDPOINT environment variable is required")
-	}
-	if accessKey == "" || secretKey == "" {
-		log.Fatal("ACCESS_KEY_ID and SECRET_ACCESS_KEY are required")
-	}
-	if region == "" || strings.EqualFold(region, "auto") {
-		region = "us-east-1"
-	}
-
-	// Create S3 client
-	cfg, err := config.LoadDefaultConfig(
-		context.Background(),
-		config.WithRegion(region),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessKey, secretKey, "")),
-	)
-	if err != nil {
-		log.Fatalf("Failed to load AWS config: %v", err)
+	var dest destination
+	if apiKey := envAny("API_KEY"); apiKey != "" {
+		apiURL := envAny("API_URL")
+		if apiURL == "" {
+			log.Fatal("API_URL environment variable is required with API_KEY")
+		}
+		dest = newAPIDestination(apiURL, apiKey)
+	} else {
+		dest = newBucketDestination()
 	}
 
-	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
-		o.UsePathStyle = true
-		o.BaseEndpoint = aws.String(strings.TrimRight(endpoint, "/"))
-	})
-
 	fmt.Printf("Migrating from: %s\n", sourceDir)
-	fmt.Printf("To bucket: %s (endpoint: %s)\n", bucket, endpoint)
+	fmt.Printf("To: %s\n", dest)
 	fmt.Println()
 
 	// Walk through source directory
@@ -109,9 +106,14 @@ func main() {
 			filename := file.Name()
 			totalFiles++
 
-			// Check if already exists in bucket
-			key := artworkID + "/" + filename
-			exists, err := objectExists(context.Background(), client, bucket, key)
+			// Check if already exists at the destination
+			exists, err := dest.exists(context.Background(), artworkID, filename)
+			var skip skipReason
+			if errors.As(err, &skip) {
+				fmt.Printf("  [SKIP] %s (%s)\n", filename, skip)
+				skippedFiles++
+				continue
+			}
 			if err != nil {
 				log.Printf("  Error checking %s: %v", filename, err)
 				continue
@@ -125,9 +127,7 @@ func main() {
 
 			// Upload file
 			filePath := filepath.Join(artworkPath, filename)
-			contentType := getContentType(filename)
-
-			if err := uploadFile(context.Background(), client, bucket, key, filePath, contentType); err != nil {
+			if err := dest.upload(context.Background(), artworkID, filename, filePath); err != nil {
 				log.Printf("  [ERROR] %s: %v", filename, err)
 				continue
 			}
@@ -144,6 +144,63 @@ func main() {
 	fmt.Printf("  Skipped: %d\n", skippedFiles)
 }
 
+// bucketDestination copies every file as is into the bucket.
+type bucketDestination struct {
+	client   *s3.Client
+	bucket   string
+	endpoint string
+}
+
+func newBucketDestination() *bucketDestination {
+	// Get S3 config from environment
+	bucket := envAny("BUCKET_NAME", "BUCKET", "ARTWORKS_BUCKET")
+	endpoint := envAny("BUCKET_ENDPOINT", "ENDPOINT", "S3_ENDPOINT")
+	accessKey := envAny("BUCKET_ACCESS_KEY_ID", "ACCESS_KEY_ID", "AWS_ACCESS_KEY_ID")
+	secretKey := envAny("BUCKET_SECRET_ACCESS_KEY", "SECRET_ACCESS_KEY", "AWS_SECRET_ACCESS_KEY")
+	region := envAny("BUCKET_REGION", "REGION", "AWS_REGION")
+
+	if bucket == "" {
+		log.Fatal("BUCKET_NAME or BUCKET environment variable is required (or API_URL and API_KEY)")
+	}
+	if endpoint == "" {
+		log.Fatal("BUCKET_ENDPOINT or ENDPOINT environment variable is required")
+	}
+	if accessKey == "" || secretKey == "" {
+		log.Fatal("ACCESS_KEY_ID and SECRET_ACCESS_KEY are required")
+	}
+	if region == "" || strings.EqualFold(region, "auto") {
+		region = "us-east-1"
+	}
+
+	// Create S3 client
+	cfg, err := config.LoadDefaultConfig(
+		context.Background(),
+		config.WithRegion(region),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessKey, secretKey, "")),
+	)
+	if err != nil {
+		log.Fatalf("Failed to load AWS config: %v", err)
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = true
+		o.BaseEndpoint = aws.String(strings.TrimRight(endpoint, "/"))
+	})
+	return &bucketDestination{client: client, bucket: bucket, endpoint: endpoint}
+}
+
+func (d *bucketDestination) String() string {
+	return fmt.Sprintf("bucket %s (endpoint: %s)", d.bucket, d.endpoint)
+}
+
+func (d *bucketDestination) exists(ctx context.Context, id, filename string) (bool, error) {
+	return objectExists(ctx, d.client, d.bucket, id+"/"+filename)
+}
+
+func (d *bucketDestination) upload(ctx context.Context, id, filename, filePath string) error {
+	return uploadFile(ctx, d.client, d.bucket, id+"/"+filename, filePath, media.ContentType(filename))
+}
+
 func envAny(keys ...string) string {
 	for _, k := range keys {
 		if v := strings.TrimSpace(os.Getenv(k)); v != "" {
@@ -183,29 +240,3 @@ func uploadFile(ctx context.Context, client *s3.Client, bucket, key, filePath, c
 	})
 	return err
 }
-
-func getContentType(filename string) string {
-	ext := strings.ToLower(filepath.Ext(filename))
-	switch ext {
-	case ".jpg", ".jpeg":
-		return "image/jpeg"
-	case ".png":
-		return "image/png"
-	case ".gif":
-		return "image/gif"
-	case ".mp4":
-		return "video/mp4"
-	case ".webm":
-		return "video/webm"
-	case ".mov":
-		return "video/quicktime"
-	case ".json":
-		return "application/json"
-	case ".txt":
-		return "text/plain; charset=utf-8"
-	case ".md":
-		return "text/markdown; charset=utf-8"
-	default:
-		return "application/octet-stream"
-	}
-}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"alexis-art-backend/db"
+)
+
+// artworkCollection is what differs between series and exhibitions, the
+// slug-addressed groups of artworks, so that saving them and changing their
+// artworks is written once.
+type artworkCollection[R any] struct {
+	name string // "Series", for messages
+	noun string // "series", for messages
+	// embedded is set when Artwork responses embed the collection, so that a
+	// change bumps the catalog version.
+	embedded bool
+	// Recorded in the history of each artwork added or removed.
+	revisionAction string
+
+	get    func(ctx context.Context, q db.Querier, slug string) (*R, error)
+	lock   func(ctx context.Context, q db.Querier, slug string) (int64, error)
+	insert func(ctx context.Context, q db.Querier, row R) (int64, error)
+	update func(ctx context.Context, q db.Querier, id int64, row R) error
+
+	setArtworks   func(ctx context.Context, q db.Querier, id int64, artworkIDs []string) error
+	addArtwork    func(ctx context.Context, q db.Querier, id int64, artworkID string) (bool, error)
+	removeArtwork func(ctx context.Context, q db.Querier, id int64, artworkID string) (bool, error)
+
+	slug       func(row R) string
+	artworkIDs func(row R) []string
+	response   func(row R) any
+}
+
+// membershipChanges returns the artworks that are in only one of before and
+// after.
+func membershipChanges(before, after []string) []string {
+	var changed []string
+	for _, id := range before {
+		if !slices.Contains(after, id) {
+			changed = append(changed, id)
+		}
+	}
+	for _, id := range after {
+		if !slices.Contains(before, id) {
+			changed = append(changed, id)
+		}
+	}
+	return changed
+}
+
+// lockArtworks takes the locks of several artworks in a fixed order, so two
+// requests with overlapping artworks cannot deadlock.
+func lockArtworks(ids []string) func() {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	unlocks := make([]func(), 0, len(ids))
+	for _, id := range ids {
+		unlocks = append(unlocks, lockArtwork(id))
+	}
+	return func() {
+		for _, unlock := range unlocks {
+			unlock()
+		}
+	}
+}
+
+// save creates a collection (slug == "") or replaces the fields of an
+// existing one, together with its artworks when artworkIDs is not nil. The
+// row of an existing collection is locked so concurrent saves and artwork
+// changes apply one after the other. Each artwork added or removed gets a
+// revision.
+func (c artworkCollection[R]) save(w http.ResponseWriter, r *http.Request, slug string, row R, artworkIDs *[]string) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	tx, err := pgPool.Begin(ctx)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save "+c.noun)
+		return
+	}
+	defer tx.Rollback(context.Background())
+
+	var id int64
+	var previous []string
+	status := http.StatusCreated
+	if slug == "" {
+		id, err = c.insert(ctx, tx, row)
+	} else {
+		status = http.StatusOK
+		id, err = c.lock(ctx, tx, slug)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to read "+c.noun)
+			return
+		}
+		if id == 0 {
+			respondWithError(w, http.StatusNotFound, c.name+" not found")
+			return
+		}
+		var existing *R
+		existing, err = c.get(ctx, tx, slug)
+		if err != nil || existing == nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to read "+c.noun)
+			return
+		}
+		previous = c.artworkIDs(*existing)
+		err = c.update(ctx, tx, id, row)
+	}
+	if db.IsUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Slug already exists")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save "+c.noun)
+		return
+	}
+	var changed []string
+	befores := map[string]*revisionSnapshot{}
+	if artworkIDs != nil {
+		changed = membershipChanges(previous, *artworkIDs)
+		unlock := lockArtworks(changed)
+		defer unlock()
+		for _, artworkID := range changed {
+			befores[artworkID] = snapshotArtwork(r.Context(), artworkID)
+		}
+		if err := c.setArtworks(ctx, tx, id, *artworkIDs); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to save "+c.noun+" artworks")
+			return
+		}
+	}
+
+	saved, err := c.get(ctx, tx, c.slug(row))
+	if err != nil || saved == nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to re-read "+c.noun)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save "+c.noun)
+		return
+	}
+	if c.embedded {
+		bumpCatalogVersion()
+	}
+	for _, artworkID := range changed {
+		recordArtworkChange(r.Context(), artworkID, c.revisionAction, befores[artworkID])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(c.response(*saved))
+}
+
+// changeArtwork adds the artwork {id} to the collection {slug}, or removes
+// it, and records a revision of the artwork. Adding an artwork already in it,
+// or removing one that is not, changes nothing.
+func (c artworkCollection[R]) changeArtwork(w http.ResponseWriter, r *http.Request, add bool) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	vars := mux.Vars(r)
+	artworkID := vars["id"]
+	if !isSafeArtworkID(artworkID) {
+		respondWithError(w, http.StatusBadRequest, "Invalid artwork id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	tx, err := pgPool.Begin(ctx)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save "+c.noun+" artworks")
+		return
+	}
+	defer tx.Rollback(context.Background())
+
+	id, err := c.lock(ctx, tx, vars["slug"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to read "+c.noun)
+		return
+	}
+	if id == 0 {
+		respondWithError(w, http.StatusNotFound, c.name+" not found")
+		return
+	}
+	// After the row lock, like save.
+	unlock := lockArtwork(artworkID)
+	defer unlock()
+	before := snapshotArtwork(r.Context(), artworkID)
+
+	change := c.removeArtwork
+	if add {
+		change = c.addArtwork
+	}
+	changed, err := change(ctx, tx, id, artworkID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save "+c.noun+" artworks")
+		return
+	}
+	saved, err := c.get(ctx, tx, vars["slug"])
+	if err != nil || saved == nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to re-read "+c.noun)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save "+c.noun+" artworks")
+		return
+	}
+	if changed {
+		if c.embedded {
+			bumpCatalogVersion()
+		}
+		recordArtworkChange(r.Context(), artworkID, c.revisionAction, before)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.response(*saved))
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type APIKeyRow struct {
+	ID         string
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	CreatedBy  string
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	LastUsedIP string
+	RevokedAt  *time.Time
+	RevokedBy  string
+}
+
+const apiKeyColumns = `id, name, prefix, key_hash, scopes, created_by, created_at, expires_at, last_used_at, last_used_ip, revoked_at, revoked_by`
+
+func scanAPIKeyRow(row pgx.Row) (APIKeyRow, error) {
+	var r APIKeyRow
+	err := row.Scan(&r.ID, &r.Name, &r.Prefix, &r.KeyHash, &r.Scopes, &r.CreatedBy, &r.CreatedAt, &r.ExpiresAt, &r.LastUsedAt, &r.LastUsedIP, &r.RevokedAt, &r.RevokedBy)
+	return r, err
+}
+
+func InsertAPIKey(ctx context.Context, q Querier, r APIKeyRow) (*APIKeyRow, error) {
+	saved, err := scanAPIKeyRow(q.QueryRow(ctx, `
+		INSERT INTO api_keys (id, name, prefix, key_hash, scopes, created_by, expires_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+		RETURNING `+apiKeyColumns,
+		r.ID, r.Name, r.Prefix, r.KeyHash, r.Scopes, r.CreatedBy, r.ExpiresAt))
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+func GetAPIKey(ctx context.Context, q Querier, id string) (*APIKeyRow, error) {
+	r, err := scanAPIKeyRow(q.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id=$1`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ListAPIKeys returns every key, revoked and expired ones included, newest
+// first.
+func ListAPIKeys(ctx context.Context, q Querier) ([]APIKeyRow, error) {
+	rows, err := q.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []APIKeyRow
+	for rows.Next() {
+		r, err := scanAPIKeyRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// UseAPIKey looks up an active key by hash and records the use. It returns
+// nil for unknown, expired or revoked keys.
+func UseAPIKey(ctx context.Context, q Querier, keyHash, ip string) (*APIKeyRow, error) {
+	r, err := scanAPIKeyRow(q.QueryRow(ctx, `
+		UPDATE api_keys SET last_used_at=NOW(), last_used_ip=$2
+		WHERE key_hash=$1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING `+apiKeyColumns, keyHash, ip))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+// RevokeAPIKey marks a key as revoked. It returns false when the key does not
+// exist or was already revoked.
+func RevokeAPIKey(ctx context.Context, q Querier, id, revokedBy string) (bool, error) {
+	tag, err := q.Exec(ctx, `
+		UPDATE api_keys SET revoked_at=NOW(), revoked_by=$2
+		WHERE id=$1 AND revoked_at IS NULL
+	`, id, revokedBy)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestUseAPIKey(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 10)
+	expired := time.Now().Add(-time.Minute)
+	later := time.Now().Add(time.Hour)
+	for _, k := range []APIKeyRow{
+		{ID: "active" + suffix, KeyHash: "h1" + suffix, ExpiresAt: &later},
+		{ID: "forever" + suffix, KeyHash: "h2" + suffix},
+		{ID: "expired" + suffix, KeyHash: "h3" + suffix, ExpiresAt: &expired},
+		{ID: "revoked" + suffix, KeyHash: "h4" + suffix},
+	} {
+		k.Name, k.Prefix, k.Scopes, k.CreatedBy = "test", "abk_test", []string{"read:drafts"}, "test"
+		if _, err := InsertAPIKey(ctx, pool, k); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { pool.Exec(context.Background(), `DELETE FROM api_keys WHERE id=$1`, k.ID) })
+	}
+	if ok, err := RevokeAPIKey(ctx, pool, "revoked"+suffix, "test"); err != nil || !ok {
+		t.Fatalf("RevokeAPIKey = %v, %v", ok, err)
+	}
+	if ok, _ := RevokeAPIKey(ctx, pool, "revoked"+suffix, "test"); ok {
+		t.Error("RevokeAPIKey revoked a key twice")
+	}
+
+	tests := []struct {
+		name, hash, wantID string
+	}{
+		{"active", "h1", "active"},
+		{"without expiry", "h2", "forever"},
+		{"expired", "h3", ""},
+		{"revoked", "h4", ""},
+		{"unknown hash", "nope", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := UseAPIKey(ctx, pool, tt.hash+suffix, "192.0.2.1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantID == "" {
+				if key != nil {
+					t.Fatalf("UseAPIKey = key %s, want none", key.ID)
+				}
+				return
+			}
+			if key == nil || key.ID != tt.wantID+suffix {
+				t.Fatalf("UseAPIKey = %+v, want key %s", key, tt.wantID)
+			}
+			if key.LastUsedAt == nil || key.LastUsedIP != "192.0.2.1" {
+				t.Errorf("UseAPIKey did not record its use: %v, %q", key.LastUsedAt, key.LastUsedIP)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+type AuditRow struct {
+	ID         int64
+	OccurredAt time.Time
+	Actor      string
+	IP         string
+	UserAgent  string
+	Method     string
+	Action     string
+	Path       string
+	ArtworkID  string
+	Status     int
+	Before     []byte // JSON, nil when there is no snapshot
+	After      []byte // JSON, nil when there is no snapshot
+}
+
+// AuditFilter narrows ListAudit; zero values match everything. From is
+// inclusive, To exclusive.
+type AuditFilter struct {
+	ArtworkID string
+	Actor     string
+	Action    string
+	From      *time.Time
+	To        *time.Time
+	Limit     int
+	Offset    int
+}
+
+func InsertAudit(ctx context.Context, q Querier, r AuditRow) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO audit_log (actor, ip, user_agent, method, action, path, artwork_id, status, before, after)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+	`, r.Actor, r.IP, r.UserAgent, r.Method, r.Action, r.Path, r.ArtworkID, r.Status, jsonOrNull(r.Before), jsonOrNull(r.After))
+	return err
+}
+
+func jsonOrNull(b []byte) *string {
+	if b == nil {
+		return nil
+	}
+	s := string(b)
+	return &s
+}
+
+// ListAudit returns a page of matching entries, newest first, and how many
+// match in total.
+func ListAudit(ctx context.Context, q Querier, f AuditFilter) ([]AuditRow, int, error) {
+	rows, err := q.Query(ctx, `
+		SELECT id, occurred_at, actor, ip, user_agent, method, action, path, artwork_id, status, before, after,
+			COUNT(*) OVER ()
+		FROM audit_log
+		WHERE ($1 = '' OR artwork_id = $1)
+			AND ($2 = '' OR actor = $2)
+			AND ($3 = '' OR action = $3)
+			AND ($4::timestamptz IS NULL OR occurred_at >= $4)
+			AND ($5::timestamptz IS NULL OR occurred_at < $5)
+		ORDER BY occurred_at DESC, id DESC
+		LIMIT $6 OFFSET $7
+	`, f.ArtworkID, f.Actor, f.Action, f.From, f.To, f.Limit, f.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var result []AuditRow
+	total := 0
+	for rows.Next() {
+		var r AuditRow
+		if err := rows.Scan(&r.ID, &r.OccurredAt, &r.Actor, &r.IP, &r.UserAgent, &r.Method, &r.Action, &r.Path, &r.ArtworkID, &r.Status,
+			&r.Before, &r.After, &total); err != nil {
+			return nil, 0, err
+		}
+		result = append(result, r)
+	}
+	return result, total, rows.Err()
+}
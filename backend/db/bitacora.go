@@ -0,0 +1,167 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type BitacoraEntryRow struct {
+	ID        int64
+	ArtworkID string
+	Date      time.Time
+	Body      string
+	Media     []string
+	CreatedBy string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+const bitacoraColumns = `id, artwork_id, entry_date, body, media, created_by, created_at, updated_at`
+
+func scanBitacoraEntry(row pgx.Row) (BitacoraEntryRow, error) {
+	var r BitacoraEntryRow
+	err := row.Scan(&r.ID, &r.ArtworkID, &r.Date, &r.Body, &r.Media, &r.CreatedBy, &r.CreatedAt, &r.UpdatedAt)
+	return r, err
+}
+
+// ListBitacoraEntries returns the entries of an artwork in chronological
+// order; an empty artworkID returns those of every artwork, by artwork.
+func ListBitacoraEntries(ctx context.Context, q Querier, artworkID string) ([]BitacoraEntryRow, error) {
+	rows, err := q.Query(ctx, `
+		SELECT `+bitacoraColumns+`
+		FROM bitacora_entries
+		WHERE ($1 = '' OR artwork_id=$1)
+		ORDER BY artwork_id, entry_date, id
+	`, artworkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []BitacoraEntryRow
+	for rows.Next() {
+		r, err := scanBitacoraEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+func GetBitacoraEntry(ctx context.Context, q Querier, artworkID string, id int64) (*BitacoraEntryRow, error) {
+	r, err := scanBitacoraEntry(q.QueryRow(ctx, `
+		SELECT `+bitacoraColumns+` FROM bitacora_entries WHERE artwork_id=$1 AND id=$2
+	`, artworkID, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+func InsertBitacoraEntry(ctx context.Context, q Querier, r BitacoraEntryRow) (int64, error) {
+	if r.Media == nil {
+		r.Media = []string{}
+	}
+	var id int64
+	err := q.QueryRow(ctx, `
+		INSERT INTO bitacora_entries (artwork_id, entry_date, body, media, created_by)
+		VALUES ($1,$2,$3,$4,$5)
+		RETURNING id
+	`, r.ArtworkID, r.Date, r.Body, r.Media, r.CreatedBy).Scan(&id)
+	return id, err
+}
+
+// UpdateBitacoraEntry replaces an entry. It returns false when it does not
+// exist.
+func UpdateBitacoraEntry(ctx context.Context, q Querier, r BitacoraEntryRow) (bool, error) {
+	if r.Media == nil {
+		r.Media = []string{}
+	}
+	tag, err := q.Exec(ctx, `
+		UPDATE bitacora_entries SET entry_date=$3, body=$4, media=$5, updated_at=NOW()
+		WHERE artwork_id=$1 AND id=$2
+	`, r.ArtworkID, r.ID, r.Date, r.Body, r.Media)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func DeleteBitacoraEntry(ctx context.Context, q Querier, artworkID string, id int64) (bool, error) {
+	tag, err := q.Exec(ctx, `DELETE FROM bitacora_entries WHERE artwork_id=$1 AND id=$2`, artworkID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ImportedBitacoras returns the artworks whose free-text bitácora was
+// already imported as an entry.
+func ImportedBitacoras(ctx context.Context, q Querier) (map[string]bool, error) {
+	rows, err := q.Query(ctx, `SELECT artwork_id FROM bitacora_imports`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	imported := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		imported[id] = true
+	}
+	return imported, rows.Err()
+}
+
+// ImportBitacora stores the free-text bitácora of an artwork as its first
+// entry, once, and moves the translations of that text to the entry. Use it
+// inside a transaction.
+func ImportBitacora(ctx context.Context, q Querier, r BitacoraEntryRow) (bool, error) {
+	tag, err := q.Exec(ctx, `
+		INSERT INTO bitacora_imports (artwork_id) VALUES ($1) ON CONFLICT DO NOTHING
+	`, r.ArtworkID)
+	if err != nil || tag.RowsAffected() == 0 {
+		return false, err
+	}
+	id, err := InsertBitacoraEntry(ctx, q, r)
+	if err != nil {
+		return false, err
+	}
+	if _, err := q.Exec(ctx, `
+		INSERT INTO bitacora_entry_translations (entry_id, locale, body, updated_by, updated_at)
+		SELECT $1, locale, bitacora, updated_by, updated_at
+		FROM artwork_translations
+		WHERE artwork_id=$2 AND bitacora <> ''
+		ON CONFLICT DO NOTHING
+	`, id, r.ArtworkID); err != nil {
+		return false, err
+	}
+	if _, err := q.Exec(ctx, `
+		UPDATE artwork_translations SET bitacora='' WHERE artwork_id=$1 AND bitacora <> ''
+	`, r.ArtworkID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// BitacoraStamp is the bitácora counterpart of TagsStamp.
+func BitacoraStamp(ctx context.Context, q Querier, artworkID string) (int, time.Time, error) {
+	var count int
+	var last *time.Time
+	err := q.QueryRow(ctx, `
+		SELECT COUNT(*), MAX(updated_at)
+		FROM bitacora_entries
+		WHERE $1 = '' OR artwork_id = $1
+	`, artworkID).Scan(&count, &last)
+	if err != nil || last == nil {
+		return count, time.Time{}, err
+	}
+	return count, *last, nil
+}
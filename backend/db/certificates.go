@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type CertificateRow struct {
+	ID           int64
+	Serial       string
+	Code         string
+	ArtworkID    string
+	Title        string
+	Technique    string
+	Dimensions   string
+	StartDate    string
+	EndDate      string
+	PrimaryImage string
+	HolderName   string
+	IssuedBy     string
+	IssuedAt     time.Time
+	RevokedAt    *time.Time
+	RevokedBy    string
+}
+
+const certificateColumns = `id, serial, code, artwork_id, title, technique, dimensions, start_date, end_date,
+	primary_image, holder_name, issued_by, issued_at, revoked_at, revoked_by`
+
+func scanCertificateRow(row pgx.Row) (CertificateRow, error) {
+	var r CertificateRow
+	err := row.Scan(&r.ID, &r.Serial, &r.Code, &r.ArtworkID, &r.Title, &r.Technique, &r.Dimensions, &r.StartDate, &r.EndDate,
+		&r.PrimaryImage, &r.HolderName, &r.IssuedBy, &r.IssuedAt, &r.RevokedAt, &r.RevokedBy)
+	return r, err
+}
+
+// InsertCertificate stores a new certificate. The serial is built from
+// serialPrefix, the year of issue and the row id: ABC-2024-0007.
+func InsertCertificate(ctx context.Context, q Querier, r CertificateRow, serialPrefix string) (*CertificateRow, error) {
+	row, err := scanCertificateRow(q.QueryRow(ctx, `
+		WITH next AS (SELECT nextval(pg_get_serial_sequence('certificates', 'id')) AS id)
+		INSERT INTO certificates (id, serial, code, artwork_id, title, technique, dimensions, start_date, end_date,
+			primary_image, holder_name, issued_by)
+		SELECT next.id, $1 || '-' || to_char(NOW(), 'YYYY') || '-' || lpad(next.id::text, 4, '0'),
+			$2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+		FROM next
+		RETURNING `+certificateColumns,
+		serialPrefix, r.Code, r.ArtworkID, r.Title, r.Technique, r.Dimensions, r.StartDate, r.EndDate,
+		r.PrimaryImage, r.HolderName, r.IssuedBy))
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+func GetCertificateBySerial(ctx context.Context, q Querier, serial string) (*CertificateRow, error) {
+	return getCertificate(ctx, q, "serial", serial)
+}
+
+func GetCertificateByCode(ctx context.Context, q Querier, code string) (*CertificateRow, error) {
+	return getCertificate(ctx, q, "code", code)
+}
+
+func getCertificate(ctx context.Context, q Querier, column, value string) (*CertificateRow, error) {
+	r, err := scanCertificateRow(q.QueryRow(ctx, `
+		SELECT `+certificateColumns+` FROM certificates WHERE `+column+`=$1
+	`, value))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ListCertificates returns the certificates of an artwork, newest first.
+func ListCertificates(ctx context.Context, q Querier, artworkID string) ([]CertificateRow, error) {
+	rows, err := q.Query(ctx, `
+		SELECT `+certificateColumns+`
+		FROM certificates
+		WHERE artwork_id=$1
+		ORDER BY issued_at DESC, id DESC
+	`, artworkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []CertificateRow
+	for rows.Next() {
+		r, err := scanCertificateRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// RevokeCertificate marks a certificate as revoked. It returns false when it
+// does not exist or was already revoked.
+func RevokeCertificate(ctx context.Context, q Querier, serial, revokedBy string) (bool, error) {
+	tag, err := q.Exec(ctx, `
+		UPDATE certificates SET revoked_at=NOW(), revoked_by=$2
+		WHERE serial=$1 AND revoked_at IS NULL
+	`, serial, revokedBy)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,9 +10,24 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Querier is implemented by both *pgxpool.Pool and pgx.Tx, so the same query
+// helpers work inside and outside a transaction.
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// IsUniqueViolation reports whether err is a Postgres unique_violation.
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
 type ArtworkRow struct {
 	ID              string
 	Title           string
@@ -20,8 +36,30 @@ type ArtworkRow struct {
 	EndDate         *time.Time
 	InProgress      bool
 	Detalle         string
-	Bitacora        string
+	Bitacora        string // legacy free text; read for the entry import, never written
 	PrimaryImage    string
+	Status          string // draft, published or archived; empty means "leave as is"
+	PublishAt       *time.Time
+	Technique       string
+	Height          *float64
+	Width           *float64
+	Depth           *float64
+	DimensionUnit   string
+	Price           *int64 // in hundredths, price is NUMERIC(12,2)
+	Currency        string
+	Availability    string
+	UpdatedAt       time.Time
+}
+
+const artworkColumns = `id, title, painted_location, start_date, end_date, in_progress, detalle, bitacora, primary_image, status, publish_at,
+	technique, height, width, depth, dimension_unit, (price * 100)::BIGINT, currency, availability, updated_at`
+
+// scanArtworkRow scans a row selected with artworkColumns.
+func scanArtworkRow(row pgx.Row) (ArtworkRow, error) {
+	var r ArtworkRow
+	err := row.Scan(&r.ID, &r.Title, &r.PaintedLocation, &r.StartDate, &r.EndDate, &r.InProgress, &r.Detalle, &r.Bitacora, &r.PrimaryImage, &r.Status, &r.PublishAt,
+		&r.Technique, &r.Height, &r.Width, &r.Depth, &r.DimensionUnit, &r.Price, &r.Currency, &r.Availability, &r.UpdatedAt)
+	return r, err
 }
 
 func Connect(ctx context.Context, databaseURL string) (*pgxpool.Pool, error) {
@@ -48,6 +86,23 @@ func Migrate(ctx context.Context, pool *pgxpool.Pool, migrationsDir string) erro
 	migrations := []string{
 		"001_init.sql",
 		"002_title_unique_and_primary_image.sql",
+		"003_artwork_revisions.sql",
+		"004_artwork_status.sql",
+		"005_preview_links.sql",
+		"006_artwork_sales.sql",
+		"007_series.sql",
+		"008_tags.sql",
+		"009_exhibitions.sql",
+		"010_provenance.sql",
+		"011_certificates.sql",
+		"012_artwork_translations.sql",
+		"013_bitacora_entries.sql",
+		"014_admin_users.sql",
+		"015_admin_roles.sql",
+		"016_api_keys.sql",
+		"017_audit_log.sql",
+		"018_bitacora_translations.sql",
+		"019_audit_log_append_only.sql",
 	}
 
 	for _, filename := range migrations {
@@ -71,7 +126,8 @@ func Migrate(ctx context.Context, pool *pgxpool.Pool, migrationsDir string) erro
 }
 
 func splitSQLStatements(sql string) []string {
-	// Strip "--" comment lines, then naive split by ';' (ok for our simple file)
+	// Strip "--" comment lines, then naive split by ';', except inside
+	// $$-quoted bodies (trigger functions)
 	lines := strings.Split(sql, "\n")
 	buf := make([]string, 0, len(lines))
 	for _, ln := range lines {
@@ -82,7 +138,21 @@ func splitSQLStatements(sql string) []string {
 		buf = append(buf, ln)
 	}
 	clean := strings.Join(buf, "\n")
-	parts := strings.Split(clean, ";")
+
+	var parts []string
+	start, quoted := 0, false
+	for i := 0; i < len(clean); i++ {
+		switch {
+		case strings.HasPrefix(clean[i:], "$$"):
+			quoted = !quoted
+			i++
+		case clean[i] == ';' && !quoted:
+			parts = append(parts, clean[start:i])
+			start = i + 1
+		}
+	}
+	parts = append(parts, clean[start:])
+
 	out := make([]string, 0, len(parts))
 	for _, p := range parts {
 		p = strings.TrimSpace(p)
@@ -94,15 +164,22 @@ func splitSQLStatements(sql string) []string {
 	return out
 }
 
-func GetArtwork(ctx context.Context, pool *pgxpool.Pool, id string) (*ArtworkRow, error) {
-	row := pool.QueryRow(ctx, `
-		SELECT id, title, painted_location, start_date, end_date, in_progress, detalle, bitacora, primary_image
-		FROM artworks
-		WHERE id=$1
-	`, id)
+func GetArtwork(ctx context.Context, q Querier, id string) (*ArtworkRow, error) {
+	r, err := scanArtworkRow(q.QueryRow(ctx, `SELECT `+artworkColumns+` FROM artworks WHERE id=$1`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &r, nil
+}
 
-	var r ArtworkRow
-	if err := row.Scan(&r.ID, &r.Title, &r.PaintedLocation, &r.StartDate, &r.EndDate, &r.InProgress, &r.Detalle, &r.Bitacora, &r.PrimaryImage); err != nil {
+// LockArtworkRow is GetArtwork inside a transaction, locking the row until
+// it ends so other writes to the artwork wait.
+func LockArtworkRow(ctx context.Context, q Querier, id string) (*ArtworkRow, error) {
+	r, err := scanArtworkRow(q.QueryRow(ctx, `SELECT `+artworkColumns+` FROM artworks WHERE id=$1 FOR UPDATE`, id))
+	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
 		}
@@ -111,10 +188,11 @@ func GetArtwork(ctx context.Context, pool *pgxpool.Pool, id string) (*ArtworkRow
 	return &r, nil
 }
 
-func UpsertArtwork(ctx context.Context, pool *pgxpool.Pool, r ArtworkRow) error {
-	_, err := pool.Exec(ctx, `
-		INSERT INTO artworks (id, title, painted_location, start_date, end_date, in_progress, detalle, bitacora, primary_image)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+func UpsertArtwork(ctx context.Context, q Querier, r ArtworkRow) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO artworks (id, title, painted_location, start_date, end_date, in_progress, detalle, primary_image, status,
+			technique, height, width, depth, dimension_unit, price, currency, availability)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,COALESCE(NULLIF($9,''),'published'),$10,$11,$12,$13,$14,$15::NUMERIC / 100,$16,$17)
 		ON CONFLICT (id) DO UPDATE SET
 			title=EXCLUDED.title,
 			painted_location=EXCLUDED.painted_location,
@@ -122,20 +200,42 @@ func UpsertArtwork(ctx context.Context, pool *pgxpool.Pool, r ArtworkRow) error
 			end_date=EXCLUDED.end_date,
 			in_progress=EXCLUDED.in_progress,
 			detalle=EXCLUDED.detalle,
-			bitacora=EXCLUDED.bitacora,
 			primary_image=EXCLUDED.primary_image,
+			technique=EXCLUDED.technique,
+			height=EXCLUDED.height,
+			width=EXCLUDED.width,
+			depth=EXCLUDED.depth,
+			dimension_unit=EXCLUDED.dimension_unit,
+			price=EXCLUDED.price,
+			currency=EXCLUDED.currency,
+			availability=EXCLUDED.availability,
 			updated_at=NOW()
-	`, r.ID, r.Title, r.PaintedLocation, r.StartDate, r.EndDate, r.InProgress, r.Detalle, r.Bitacora, r.PrimaryImage)
+	`, r.ID, r.Title, r.PaintedLocation, r.StartDate, r.EndDate, r.InProgress, r.Detalle, r.PrimaryImage, r.Status,
+		r.Technique, r.Height, r.Width, r.Depth, r.DimensionUnit, r.Price, r.Currency, r.Availability)
+	return err
+}
+
+// SetArtworkStatus changes the publishing status of an artwork, creating its
+// row if needed. publishAt schedules publication (nil = immediately).
+func SetArtworkStatus(ctx context.Context, q Querier, id, status string, publishAt *time.Time) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO artworks (id, status, publish_at)
+		VALUES ($1,$2,$3)
+		ON CONFLICT (id) DO UPDATE SET
+			status=EXCLUDED.status,
+			publish_at=EXCLUDED.publish_at,
+			updated_at=NOW()
+	`, id, status, publishAt)
 	return err
 }
 
 // IsTitleUnique checks if a title is unique (excluding the given ID for updates)
-func IsTitleUnique(ctx context.Context, pool *pgxpool.Pool, title, excludeID string) (bool, error) {
+func IsTitleUnique(ctx context.Context, q Querier, title, excludeID string) (bool, error) {
 	if strings.TrimSpace(title) == "" {
 		return false, fmt.Errorf("title cannot be empty")
 	}
 	var count int
-	err := pool.QueryRow(ctx, `
+	err := q.QueryRow(ctx, `
 		SELECT COUNT(*) FROM artworks WHERE title = $1 AND id != $2
 	`, title, excludeID).Scan(&count)
 	if err != nil {
@@ -146,11 +246,7 @@ func IsTitleUnique(ctx context.Context, pool *pgxpool.Pool, title, excludeID str
 
 // ListArtworks returns all artworks ordered by start_date (nulls last), then by title
 func ListArtworks(ctx context.Context, pool *pgxpool.Pool) ([]ArtworkRow, error) {
-	rows, err := pool.Query(ctx, `
-		SELECT id, title, painted_location, start_date, end_date, in_progress, detalle, bitacora, primary_image
-		FROM artworks
-		ORDER BY start_date DESC NULLS LAST, title ASC
-	`)
+	rows, err := pool.Query(ctx, `SELECT `+artworkColumns+` FROM artworks ORDER BY start_date DESC NULLS LAST, title ASC`)
 	if err != nil {
 		return nil, err
 	}
@@ -158,11 +254,58 @@ func ListArtworks(ctx context.Context, pool *pgxpool.Pool) ([]ArtworkRow, error)
 
 	var result []ArtworkRow
 	for rows.Next() {
-		var r ArtworkRow
-		if err := rows.Scan(&r.ID, &r.Title, &r.PaintedLocation, &r.StartDate, &r.EndDate, &r.InProgress, &r.Detalle, &r.Bitacora, &r.PrimaryImage); err != nil {
+		r, err := scanArtworkRow(rows)
+		if err != nil {
 			return nil, err
 		}
 		result = append(result, r)
 	}
 	return result, rows.Err()
 }
+
+// CatalogStamp returns the number of artwork rows, how many are publicly
+// visible right now and the most recent change (an edit, or a scheduled
+// publication that has come due), used to fingerprint the public catalog for
+// HTTP caching.
+func CatalogStamp(ctx context.Context, pool *pgxpool.Pool) (int, int, time.Time, error) {
+	var count, visible int
+	var last *time.Time
+	err := pool.QueryRow(ctx, `
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status='published' AND (publish_at IS NULL OR publish_at <= NOW())),
+			MAX(GREATEST(updated_at, CASE WHEN publish_at <= NOW() THEN publish_at END))
+		FROM artworks
+	`).Scan(&count, &visible, &last)
+	if err != nil {
+		return 0, 0, time.Time{}, err
+	}
+	if last == nil {
+		return count, visible, time.Time{}, nil
+	}
+	return count, visible, *last, nil
+}
+
+// HiddenArtworkIDs returns the IDs of artworks that are not publicly visible
+// right now. Artworks without a row are public, so listing the hidden ones is
+// what lets callers filter a set of IDs in one query.
+func HiddenArtworkIDs(ctx context.Context, q Querier) (map[string]bool, error) {
+	rows, err := q.Query(ctx, `
+		SELECT id FROM artworks
+		WHERE NOT (status='published' AND (publish_at IS NULL OR publish_at <= NOW()))
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hidden := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		hidden[id] = true
+	}
+	return hidden, rows.Err()
+}
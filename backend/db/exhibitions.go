@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type ExhibitionRow struct {
+	ID          int64
+	Slug        string
+	Name        string
+	Venue       string
+	City        string
+	Country     string
+	StartDate   time.Time
+	EndDate     *time.Time
+	Description string
+	Link        string
+	PosterImage string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	ArtworkIDs  []string
+}
+
+const exhibitionSelect = `
+	SELECT e.id, e.slug, e.name, e.venue, e.city, e.country, e.start_date, e.end_date, e.description, e.link, e.poster_image, e.created_at, e.updated_at,
+		COALESCE(array_agg(ea.artwork_id ORDER BY ea.artwork_id) FILTER (WHERE ea.artwork_id IS NOT NULL), '{}')
+	FROM exhibitions e
+	LEFT JOIN exhibition_artworks ea ON ea.exhibition_id = e.id
+`
+
+func scanExhibitionRow(row pgx.Row) (ExhibitionRow, error) {
+	var r ExhibitionRow
+	err := row.Scan(&r.ID, &r.Slug, &r.Name, &r.Venue, &r.City, &r.Country, &r.StartDate, &r.EndDate, &r.Description, &r.Link, &r.PosterImage, &r.CreatedAt, &r.UpdatedAt, &r.ArtworkIDs)
+	return r, err
+}
+
+func queryExhibitions(ctx context.Context, q Querier, sql string, args ...any) ([]ExhibitionRow, error) {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []ExhibitionRow
+	for rows.Next() {
+		r, err := scanExhibitionRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// ListExhibitions returns all exhibitions, most recent first.
+func ListExhibitions(ctx context.Context, q Querier) ([]ExhibitionRow, error) {
+	return queryExhibitions(ctx, q, exhibitionSelect+` GROUP BY e.id ORDER BY e.start_date DESC, e.name`)
+}
+
+// ArtworkExhibitions returns the exhibitions an artwork was shown in, most
+// recent first. ArtworkIDs is not filled in.
+func ArtworkExhibitions(ctx context.Context, q Querier, artworkID string) ([]ExhibitionRow, error) {
+	return queryExhibitions(ctx, q, `
+		SELECT e.id, e.slug, e.name, e.venue, e.city, e.country, e.start_date, e.end_date, e.description, e.link, e.poster_image, e.created_at, e.updated_at,
+			'{}'::text[]
+		FROM exhibitions e
+		JOIN exhibition_artworks ea ON ea.exhibition_id = e.id
+		WHERE ea.artwork_id=$1
+		ORDER BY e.start_date DESC, e.name
+	`, artworkID)
+}
+
+func GetExhibition(ctx context.Context, q Querier, slug string) (*ExhibitionRow, error) {
+	r, err := scanExhibitionRow(q.QueryRow(ctx, exhibitionSelect+` WHERE e.slug=$1 GROUP BY e.id`, slug))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+func InsertExhibition(ctx context.Context, q Querier, r ExhibitionRow) (int64, error) {
+	var id int64
+	err := q.QueryRow(ctx, `
+		INSERT INTO exhibitions (slug, name, venue, city, country, start_date, end_date, description, link, poster_image)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+		RETURNING id
+	`, r.Slug, r.Name, r.Venue, r.City, r.Country, r.StartDate, r.EndDate, r.Description, r.Link, r.PosterImage).Scan(&id)
+	return id, err
+}
+
+func UpdateExhibition(ctx context.Context, q Querier, r ExhibitionRow) error {
+	_, err := q.Exec(ctx, `
+		UPDATE exhibitions SET
+			slug=$2,
+			name=$3,
+			venue=$4,
+			city=$5,
+			country=$6,
+			start_date=$7,
+			end_date=$8,
+			description=$9,
+			link=$10,
+			poster_image=$11,
+			updated_at=NOW()
+		WHERE id=$1
+	`, r.ID, r.Slug, r.Name, r.Venue, r.City, r.Country, r.StartDate, r.EndDate, r.Description, r.Link, r.PosterImage)
+	return err
+}
+
+func DeleteExhibition(ctx context.Context, q Querier, slug string) (bool, error) {
+	tag, err := q.Exec(ctx, `DELETE FROM exhibitions WHERE slug=$1`, slug)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// SetExhibitionArtworks replaces the artworks shown in an exhibition.
+func SetExhibitionArtworks(ctx context.Context, q Querier, exhibitionID int64, artworkIDs []string) error {
+	if artworkIDs == nil {
+		artworkIDs = []string{}
+	}
+	if _, err := q.Exec(ctx, `
+		DELETE FROM exhibition_artworks WHERE exhibition_id=$1 AND NOT (artwork_id = ANY($2))
+	`, exhibitionID, artworkIDs); err != nil {
+		return err
+	}
+	if _, err := q.Exec(ctx, `
+		INSERT INTO exhibition_artworks (exhibition_id, artwork_id)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT DO NOTHING
+	`, exhibitionID, artworkIDs); err != nil {
+		return err
+	}
+	_, err := q.Exec(ctx, `UPDATE exhibitions SET updated_at=NOW() WHERE id=$1`, exhibitionID)
+	return err
+}
+
+// LockExhibition is the exhibitions counterpart of LockSeries.
+func LockExhibition(ctx context.Context, q Querier, slug string) (int64, error) {
+	var id int64
+	err := q.QueryRow(ctx, `SELECT id FROM exhibitions WHERE slug=$1 FOR UPDATE`, slug).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// AddExhibitionArtwork adds an artwork to an exhibition. It returns false
+// when the artwork was already in it.
+func AddExhibitionArtwork(ctx context.Context, q Querier, exhibitionID int64, artworkID string) (bool, error) {
+	tag, err := q.Exec(ctx, `
+		INSERT INTO exhibition_artworks (exhibition_id, artwork_id) VALUES ($1,$2)
+		ON CONFLICT DO NOTHING
+	`, exhibitionID, artworkID)
+	if err != nil || tag.RowsAffected() == 0 {
+		return false, err
+	}
+	_, err = q.Exec(ctx, `UPDATE exhibitions SET updated_at=NOW() WHERE id=$1`, exhibitionID)
+	return true, err
+}
+
+// RemoveExhibitionArtwork takes an artwork out of an exhibition. It returns
+// false when the artwork was not in it.
+func RemoveExhibitionArtwork(ctx context.Context, q Querier, exhibitionID int64, artworkID string) (bool, error) {
+	tag, err := q.Exec(ctx, `DELETE FROM exhibition_artworks WHERE exhibition_id=$1 AND artwork_id=$2`, exhibitionID, artworkID)
+	if err != nil || tag.RowsAffected() == 0 {
+		return false, err
+	}
+	_, err = q.Exec(ctx, `UPDATE exhibitions SET updated_at=NOW() WHERE id=$1`, exhibitionID)
+	return true, err
+}
+
+// ExhibitionsStamp is the exhibitions counterpart of TagsStamp.
+func ExhibitionsStamp(ctx context.Context, q Querier, artworkID string) (int, time.Time, error) {
+	var count int
+	var last *time.Time
+	err := q.QueryRow(ctx, `
+		SELECT COUNT(*), MAX(GREATEST(ea.created_at, e.updated_at))
+		FROM exhibition_artworks ea
+		JOIN exhibitions e ON e.id = ea.exhibition_id
+		WHERE $1 = '' OR ea.artwork_id = $1
+	`, artworkID).Scan(&count, &last)
+	if err != nil || last == nil {
+		return count, time.Time{}, err
+	}
+	return count, *last, nil
+}
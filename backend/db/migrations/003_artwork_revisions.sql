@@ -0,0 +1,15 @@
+-- Revision history for artwork edits made through the admin API.
+-- snapshot holds the full set of editable fields after the change,
+-- diff holds {field: {"from": ..., "to": ...}} for the fields that changed.
+
+CREATE TABLE IF NOT EXISTS artwork_revisions (
+  id BIGSERIAL PRIMARY KEY,
+  artwork_id TEXT NOT NULL,
+  actor TEXT NOT NULL DEFAULT '',
+  action TEXT NOT NULL DEFAULT 'update',
+  snapshot JSONB NOT NULL,
+  diff JSONB NOT NULL DEFAULT '{}'::jsonb,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS artwork_revisions_artwork_idx ON artwork_revisions (artwork_id, id DESC);
@@ -0,0 +1,8 @@
+-- Publishing workflow: draft / published / archived, plus scheduled publishing.
+-- Existing rows default to 'published' so everything already public stays public;
+-- artworks created from the backoffice are inserted as 'draft'.
+
+ALTER TABLE artworks ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'published';
+ALTER TABLE artworks ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ NULL;
+
+CREATE INDEX IF NOT EXISTS artworks_status_idx ON artworks (status, publish_at);
@@ -0,0 +1,16 @@
+-- Signed, expiring preview links for unpublished artworks.
+-- The token itself is never stored: it is an HMAC over (id, artwork_id, expires_at).
+-- Rows exist for revocation and to audit who created each link.
+
+CREATE TABLE IF NOT EXISTS preview_links (
+  id TEXT PRIMARY KEY,
+  artwork_id TEXT NOT NULL,
+  note TEXT NOT NULL DEFAULT '',
+  created_by TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMPTZ NOT NULL,
+  revoked_at TIMESTAMPTZ NULL,
+  revoked_by TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS preview_links_artwork_idx ON preview_links (artwork_id, created_at DESC);
@@ -0,0 +1,13 @@
+-- Sales and availability: technique, physical dimensions, price and whether
+-- the work can be bought. All optional; NULL/'' means "not set".
+
+ALTER TABLE artworks ADD COLUMN IF NOT EXISTS technique TEXT NOT NULL DEFAULT '';
+ALTER TABLE artworks ADD COLUMN IF NOT EXISTS height NUMERIC(10,2) NULL;
+ALTER TABLE artworks ADD COLUMN IF NOT EXISTS width NUMERIC(10,2) NULL;
+ALTER TABLE artworks ADD COLUMN IF NOT EXISTS depth NUMERIC(10,2) NULL;
+ALTER TABLE artworks ADD COLUMN IF NOT EXISTS dimension_unit TEXT NOT NULL DEFAULT '';
+ALTER TABLE artworks ADD COLUMN IF NOT EXISTS price NUMERIC(12,2) NULL;
+ALTER TABLE artworks ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT '';
+ALTER TABLE artworks ADD COLUMN IF NOT EXISTS availability TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS artworks_availability_idx ON artworks (availability);
@@ -0,0 +1,23 @@
+-- Series: named groups of artworks with their own text, cover and ordering.
+-- artwork_id has no foreign key because artworks without edits have no row.
+
+CREATE TABLE IF NOT EXISTS series (
+  id BIGSERIAL PRIMARY KEY,
+  slug TEXT NOT NULL UNIQUE,
+  title TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  cover_artwork_id TEXT NOT NULL DEFAULT '',
+  cover_image TEXT NOT NULL DEFAULT '',
+  sort_order INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS series_artworks (
+  series_id BIGINT NOT NULL REFERENCES series(id) ON DELETE CASCADE,
+  artwork_id TEXT NOT NULL,
+  position INT NOT NULL DEFAULT 0,
+  PRIMARY KEY (series_id, artwork_id)
+);
+
+CREATE INDEX IF NOT EXISTS series_artworks_artwork_idx ON series_artworks (artwork_id);
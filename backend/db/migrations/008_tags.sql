@@ -0,0 +1,21 @@
+-- Tags: a small taxonomy (theme, subject, palette, technique) for artworks.
+-- A slug is unique per kind, so "azul" can be both a palette and a subject.
+
+CREATE TABLE IF NOT EXISTS tags (
+  id BIGSERIAL PRIMARY KEY,
+  kind TEXT NOT NULL,
+  slug TEXT NOT NULL,
+  name TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (kind, slug)
+);
+
+CREATE TABLE IF NOT EXISTS artwork_tags (
+  artwork_id TEXT NOT NULL,
+  tag_id BIGINT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (artwork_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS artwork_tags_tag_idx ON artwork_tags (tag_id);
@@ -0,0 +1,28 @@
+-- Exhibitions and events the artist took part in, and the artworks shown.
+
+CREATE TABLE IF NOT EXISTS exhibitions (
+  id BIGSERIAL PRIMARY KEY,
+  slug TEXT NOT NULL UNIQUE,
+  name TEXT NOT NULL,
+  venue TEXT NOT NULL DEFAULT '',
+  city TEXT NOT NULL DEFAULT '',
+  country TEXT NOT NULL DEFAULT '',
+  start_date DATE NOT NULL,
+  end_date DATE NULL,
+  description TEXT NOT NULL DEFAULT '',
+  link TEXT NOT NULL DEFAULT '',
+  poster_image TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS exhibitions_dates_idx ON exhibitions (start_date, end_date);
+
+CREATE TABLE IF NOT EXISTS exhibition_artworks (
+  exhibition_id BIGINT NOT NULL REFERENCES exhibitions(id) ON DELETE CASCADE,
+  artwork_id TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (exhibition_id, artwork_id)
+);
+
+CREATE INDEX IF NOT EXISTS exhibition_artworks_artwork_idx ON exhibition_artworks (artwork_id);
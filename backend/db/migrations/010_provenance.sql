@@ -0,0 +1,23 @@
+-- Provenance: private ownership history of each artwork (admin only).
+-- Never joined into public responses.
+
+CREATE TABLE IF NOT EXISTS provenance_records (
+  id BIGSERIAL PRIMARY KEY,
+  artwork_id TEXT NOT NULL,
+  owner_name TEXT NOT NULL,
+  owner_email TEXT NOT NULL DEFAULT '',
+  owner_phone TEXT NOT NULL DEFAULT '',
+  owner_address TEXT NOT NULL DEFAULT '',
+  acquired_on DATE NULL,
+  price NUMERIC(12,2) NULL,
+  currency TEXT NOT NULL DEFAULT '',
+  sale_channel TEXT NOT NULL DEFAULT '',
+  location TEXT NOT NULL DEFAULT '',
+  loan_status TEXT NOT NULL DEFAULT 'none',
+  notes TEXT NOT NULL DEFAULT '',
+  created_by TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS provenance_records_artwork_idx ON provenance_records (artwork_id, acquired_on);
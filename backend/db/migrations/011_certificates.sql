@@ -0,0 +1,23 @@
+-- Certificates of authenticity. Each row is a snapshot of the artwork as it
+-- was certified, so the verification page does not change if it is edited.
+-- code is the public verification code (stored without dashes).
+
+CREATE TABLE IF NOT EXISTS certificates (
+  id BIGSERIAL PRIMARY KEY,
+  serial TEXT NOT NULL UNIQUE,
+  code TEXT NOT NULL UNIQUE,
+  artwork_id TEXT NOT NULL,
+  title TEXT NOT NULL,
+  technique TEXT NOT NULL DEFAULT '',
+  dimensions TEXT NOT NULL DEFAULT '',
+  start_date TEXT NOT NULL DEFAULT '',
+  end_date TEXT NOT NULL DEFAULT '',
+  primary_image TEXT NOT NULL DEFAULT '',
+  holder_name TEXT NOT NULL DEFAULT '',
+  issued_by TEXT NOT NULL DEFAULT '',
+  issued_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  revoked_at TIMESTAMPTZ NULL,
+  revoked_by TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS certificates_artwork_idx ON certificates (artwork_id, issued_at DESC);
@@ -0,0 +1,16 @@
+-- Translations of the text fields of an artwork. Spanish, the source
+-- language, stays in artworks; this table only holds other locales.
+-- An empty field falls back to Spanish.
+
+CREATE TABLE IF NOT EXISTS artwork_translations (
+  artwork_id TEXT NOT NULL,
+  locale TEXT NOT NULL,
+  title TEXT NOT NULL DEFAULT '',
+  detalle TEXT NOT NULL DEFAULT '',
+  bitacora TEXT NOT NULL DEFAULT '',
+  updated_by TEXT NOT NULL DEFAULT '',
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (artwork_id, locale)
+);
+
+CREATE INDEX IF NOT EXISTS artwork_translations_locale_idx ON artwork_translations (locale);
@@ -0,0 +1,23 @@
+-- Bitácora as a timeline: dated entries, each optionally pointing at images
+-- or videos (filenames) of the artwork.
+-- bitacora_imports records which artworks already had their old free-text
+-- bitácora (artworks.bitacora or bitacora.txt) imported as a first entry, so
+-- deleting that entry does not bring it back.
+
+CREATE TABLE IF NOT EXISTS bitacora_entries (
+  id BIGSERIAL PRIMARY KEY,
+  artwork_id TEXT NOT NULL,
+  entry_date DATE NOT NULL,
+  body TEXT NOT NULL DEFAULT '',
+  media TEXT[] NOT NULL DEFAULT '{}',
+  created_by TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS bitacora_entries_artwork_idx ON bitacora_entries (artwork_id, entry_date, id);
+
+CREATE TABLE IF NOT EXISTS bitacora_imports (
+  artwork_id TEXT PRIMARY KEY,
+  imported_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,5 @@
+-- Roles of backoffice accounts: owner, editor or viewer.
+-- Accounts created before roles existed could do everything, so they start
+-- as owners.
+
+ALTER TABLE admin_users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'owner';
@@ -0,0 +1,17 @@
+-- API keys for scripts and automation, issued from the backoffice.
+-- Only a SHA-256 of the key is stored; prefix is kept to recognize it.
+
+CREATE TABLE IF NOT EXISTS api_keys (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  prefix TEXT NOT NULL,
+  key_hash TEXT NOT NULL UNIQUE,
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  created_by TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMPTZ NULL,
+  last_used_at TIMESTAMPTZ NULL,
+  last_used_ip TEXT NOT NULL DEFAULT '',
+  revoked_at TIMESTAMPTZ NULL,
+  revoked_by TEXT NOT NULL DEFAULT ''
+);
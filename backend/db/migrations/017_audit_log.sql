@@ -0,0 +1,23 @@
+-- Audit log of every admin mutation (POST, PUT, PATCH, DELETE), including
+-- the ones that were rejected. Append-only, see 019_audit_log_append_only.sql.
+-- before/after hold the artwork (as in revision snapshots) around the change
+-- when the request changed one.
+
+CREATE TABLE IF NOT EXISTS audit_log (
+  id BIGSERIAL PRIMARY KEY,
+  occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  actor TEXT NOT NULL DEFAULT '',
+  ip TEXT NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  method TEXT NOT NULL,
+  action TEXT NOT NULL,
+  path TEXT NOT NULL,
+  artwork_id TEXT NOT NULL DEFAULT '',
+  status INTEGER NOT NULL,
+  before JSONB NULL,
+  after JSONB NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_occurred_idx ON audit_log (occurred_at DESC);
+CREATE INDEX IF NOT EXISTS audit_log_artwork_idx ON audit_log (artwork_id, occurred_at DESC);
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log (actor, occurred_at DESC);
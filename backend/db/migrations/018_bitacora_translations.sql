@@ -0,0 +1,26 @@
+-- Translations of bitácora entries. They replace
+-- artwork_translations.bitacora, which translated the old free text and is
+-- no longer used: it is moved here to the entry that text was imported as
+-- (and by the import itself for artworks imported later), then cleared so
+-- running this again does not bring back deleted translations.
+-- An entry without a translation shows its Spanish text.
+
+CREATE TABLE IF NOT EXISTS bitacora_entry_translations (
+  entry_id BIGINT NOT NULL REFERENCES bitacora_entries(id) ON DELETE CASCADE,
+  locale TEXT NOT NULL,
+  body TEXT NOT NULL DEFAULT '',
+  updated_by TEXT NOT NULL DEFAULT '',
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (entry_id, locale)
+);
+
+INSERT INTO bitacora_entry_translations (entry_id, locale, body, updated_by, updated_at)
+SELECT e.id, t.locale, t.bitacora, t.updated_by, t.updated_at
+FROM artwork_translations t
+JOIN bitacora_entries e ON e.artwork_id = t.artwork_id AND e.created_by = 'import'
+WHERE t.bitacora <> ''
+ON CONFLICT DO NOTHING;
+
+UPDATE artwork_translations t SET bitacora = ''
+WHERE t.bitacora <> ''
+  AND EXISTS (SELECT 1 FROM bitacora_entries e WHERE e.artwork_id = t.artwork_id AND e.created_by = 'import');
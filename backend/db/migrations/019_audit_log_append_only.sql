@@ -0,0 +1,28 @@
+-- Makes audit_log append-only. It replaces the rules first used for this,
+-- which turned UPDATE and DELETE into silent no-ops and did not cover
+-- TRUNCATE: the trigger fails every UPDATE, DELETE and TRUNCATE with an error,
+-- and the role running the migrations loses those privileges on the table.
+-- The owner of the table can still drop the trigger or grant the privileges
+-- back; to rule that out, let another role own the table and run the app with
+-- one that only has SELECT and INSERT on it.
+
+DROP RULE IF EXISTS audit_log_no_update ON audit_log;
+DROP RULE IF EXISTS audit_log_no_delete ON audit_log;
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append-only: % is not allowed', TG_OP;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_no_update_delete ON audit_log;
+CREATE TRIGGER audit_log_no_update_delete
+  BEFORE UPDATE OR DELETE ON audit_log
+  FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+CREATE TRIGGER audit_log_no_truncate
+  BEFORE TRUNCATE ON audit_log
+  FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+REVOKE UPDATE, DELETE, TRUNCATE ON audit_log FROM CURRENT_USER;
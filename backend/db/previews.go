@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type PreviewLinkRow struct {
+	ID        string
+	ArtworkID string
+	Note      string
+	CreatedBy string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	RevokedBy string
+}
+
+func InsertPreviewLink(ctx context.Context, q Querier, r PreviewLinkRow) (*PreviewLinkRow, error) {
+	err := q.QueryRow(ctx, `
+		INSERT INTO preview_links (id, artwork_id, note, created_by, expires_at)
+		VALUES ($1,$2,$3,$4,$5)
+		RETURNING created_at
+	`, r.ID, r.ArtworkID, r.Note, r.CreatedBy, r.ExpiresAt).Scan(&r.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func GetPreviewLink(ctx context.Context, q Querier, id string) (*PreviewLinkRow, error) {
+	var r PreviewLinkRow
+	err := q.QueryRow(ctx, `
+		SELECT id, artwork_id, note, created_by, created_at, expires_at, revoked_at, revoked_by
+		FROM preview_links
+		WHERE id=$1
+	`, id).Scan(&r.ID, &r.ArtworkID, &r.Note, &r.CreatedBy, &r.CreatedAt, &r.ExpiresAt, &r.RevokedAt, &r.RevokedBy)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ListPreviewLinks returns the links of an artwork, newest first.
+func ListPreviewLinks(ctx context.Context, q Querier, artworkID string) ([]PreviewLinkRow, error) {
+	rows, err := q.Query(ctx, `
+		SELECT id, artwork_id, note, created_by, created_at, expires_at, revoked_at, revoked_by
+		FROM preview_links
+		WHERE artwork_id=$1
+		ORDER BY created_at DESC
+	`, artworkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []PreviewLinkRow
+	for rows.Next() {
+		var r PreviewLinkRow
+		if err := rows.Scan(&r.ID, &r.ArtworkID, &r.Note, &r.CreatedBy, &r.CreatedAt, &r.ExpiresAt, &r.RevokedAt, &r.RevokedBy); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// RevokePreviewLink marks a link as revoked. It returns false when the link
+// does not exist or was already revoked.
+func RevokePreviewLink(ctx context.Context, q Querier, id, revokedBy string) (bool, error) {
+	tag, err := q.Exec(ctx, `
+		UPDATE preview_links SET revoked_at=NOW(), revoked_by=$2
+		WHERE id=$1 AND revoked_at IS NULL
+	`, id, revokedBy)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type ProvenanceRow struct {
+	ID           int64
+	ArtworkID    string
+	OwnerName    string
+	OwnerEmail   string
+	OwnerPhone   string
+	OwnerAddress string
+	AcquiredOn   *time.Time
+	Price        *int64 // in hundredths, like ArtworkRow.Price
+	Currency     string
+	SaleChannel  string
+	Location     string
+	LoanStatus   string
+	Notes        string
+	CreatedBy    string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+const provenanceColumns = `id, artwork_id, owner_name, owner_email, owner_phone, owner_address, acquired_on, (price * 100)::BIGINT, currency,
+	sale_channel, location, loan_status, notes, created_by, created_at, updated_at`
+
+func scanProvenanceRow(row pgx.Row) (ProvenanceRow, error) {
+	var r ProvenanceRow
+	err := row.Scan(&r.ID, &r.ArtworkID, &r.OwnerName, &r.OwnerEmail, &r.OwnerPhone, &r.OwnerAddress, &r.AcquiredOn, &r.Price, &r.Currency,
+		&r.SaleChannel, &r.Location, &r.LoanStatus, &r.Notes, &r.CreatedBy, &r.CreatedAt, &r.UpdatedAt)
+	return r, err
+}
+
+// ListProvenance returns the provenance records of one artwork (all artworks
+// when empty) in chronological order; undated records go last.
+func ListProvenance(ctx context.Context, q Querier, artworkID string) ([]ProvenanceRow, error) {
+	rows, err := q.Query(ctx, `
+		SELECT `+provenanceColumns+`
+		FROM provenance_records
+		WHERE $1 = '' OR artwork_id = $1
+		ORDER BY artwork_id, acquired_on NULLS LAST, id
+	`, artworkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []ProvenanceRow
+	for rows.Next() {
+		r, err := scanProvenanceRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+func GetProvenance(ctx context.Context, q Querier, artworkID string, id int64) (*ProvenanceRow, error) {
+	r, err := scanProvenanceRow(q.QueryRow(ctx, `
+		SELECT `+provenanceColumns+` FROM provenance_records WHERE artwork_id=$1 AND id=$2
+	`, artworkID, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+func InsertProvenance(ctx context.Context, q Querier, r ProvenanceRow) (int64, error) {
+	var id int64
+	err := q.QueryRow(ctx, `
+		INSERT INTO provenance_records (artwork_id, owner_name, owner_email, owner_phone, owner_address, acquired_on, price, currency,
+			sale_channel, location, loan_status, notes, created_by)
+		VALUES ($1,$2,$3,$4,$5,$6,$7::NUMERIC / 100,$8,$9,$10,$11,$12,$13)
+		RETURNING id
+	`, r.ArtworkID, r.OwnerName, r.OwnerEmail, r.OwnerPhone, r.OwnerAddress, r.AcquiredOn, r.Price, r.Currency,
+		r.SaleChannel, r.Location, r.LoanStatus, r.Notes, r.CreatedBy).Scan(&id)
+	return id, err
+}
+
+// UpdateProvenance replaces a record. It returns false when it does not exist.
+func UpdateProvenance(ctx context.Context, q Querier, r ProvenanceRow) (bool, error) {
+	tag, err := q.Exec(ctx, `
+		UPDATE provenance_records SET
+			owner_name=$3,
+			owner_email=$4,
+			owner_phone=$5,
+			owner_address=$6,
+			acquired_on=$7,
+			price=$8::NUMERIC / 100,
+			currency=$9,
+			sale_channel=$10,
+			location=$11,
+			loan_status=$12,
+			notes=$13,
+			updated_at=NOW()
+		WHERE artwork_id=$1 AND id=$2
+	`, r.ArtworkID, r.ID, r.OwnerName, r.OwnerEmail, r.OwnerPhone, r.OwnerAddress, r.AcquiredOn, r.Price, r.Currency,
+		r.SaleChannel, r.Location, r.LoanStatus, r.Notes)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func DeleteProvenance(ctx context.Context, q Querier, artworkID string, id int64) (bool, error) {
+	tag, err := q.Exec(ctx, `DELETE FROM provenance_records WHERE artwork_id=$1 AND id=$2`, artworkID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,174 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RevisionActionBaseline is recorded before the first tracked change, so the
+// state an artwork had before history existed can be restored too.
+const RevisionActionBaseline = "baseline"
+
+type RevisionRow struct {
+	ID        int64
+	ArtworkID string
+	Actor     string
+	Action    string
+	Snapshot  []byte // JSON
+	Diff      []byte // JSON
+	CreatedAt time.Time
+}
+
+func InsertRevision(ctx context.Context, q Querier, r RevisionRow) (int64, error) {
+	var id int64
+	err := q.QueryRow(ctx, `
+		INSERT INTO artwork_revisions (artwork_id, actor, action, snapshot, diff)
+		VALUES ($1,$2,$3,$4,$5)
+		RETURNING id
+	`, r.ArtworkID, r.Actor, r.Action, string(r.Snapshot), string(r.Diff)).Scan(&id)
+	return id, err
+}
+
+// HasRevisions reports whether any revision was recorded for the artwork.
+func HasRevisions(ctx context.Context, q Querier, artworkID string) (bool, error) {
+	var exists bool
+	err := q.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM artwork_revisions WHERE artwork_id=$1)
+	`, artworkID).Scan(&exists)
+	return exists, err
+}
+
+// ListRevisions returns the revisions of an artwork, newest first.
+func ListRevisions(ctx context.Context, q Querier, artworkID string) ([]RevisionRow, error) {
+	rows, err := q.Query(ctx, `
+		SELECT id, artwork_id, actor, action, snapshot, diff, created_at
+		FROM artwork_revisions
+		WHERE artwork_id=$1
+		ORDER BY id DESC
+	`, artworkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []RevisionRow
+	for rows.Next() {
+		var r RevisionRow
+		if err := rows.Scan(&r.ID, &r.ArtworkID, &r.Actor, &r.Action, &r.Snapshot, &r.Diff, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+func GetRevision(ctx context.Context, q Querier, artworkID string, id int64) (*RevisionRow, error) {
+	var r RevisionRow
+	err := q.QueryRow(ctx, `
+		SELECT id, artwork_id, actor, action, snapshot, diff, created_at
+		FROM artwork_revisions
+		WHERE artwork_id=$1 AND id=$2
+	`, artworkID, id).Scan(&r.ID, &r.ArtworkID, &r.Actor, &r.Action, &r.Snapshot, &r.Diff, &r.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+// LatestRevision returns the newest revision of an artwork, nil when it has
+// none.
+func LatestRevision(ctx context.Context, q Querier, artworkID string) (*RevisionRow, error) {
+	var r RevisionRow
+	err := q.QueryRow(ctx, `
+		SELECT id, artwork_id, actor, action, snapshot, diff, created_at
+		FROM artwork_revisions
+		WHERE artwork_id=$1
+		ORDER BY id DESC
+		LIMIT 1
+	`, artworkID).Scan(&r.ID, &r.ArtworkID, &r.Actor, &r.Action, &r.Snapshot, &r.Diff, &r.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+// FieldChange is one entry of a revision diff.
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// DiffFields compares two values by their JSON representation and returns
+// the top-level fields whose value differs.
+func DiffFields(before, after any) map[string]FieldChange {
+	toMap := func(v any) map[string]any {
+		m := map[string]any{}
+		b, _ := json.Marshal(v)
+		json.Unmarshal(b, &m)
+		return m
+	}
+	b, a := toMap(before), toMap(after)
+	diff := map[string]FieldChange{}
+	for k, av := range a {
+		if bv, ok := b[k]; !ok || !reflect.DeepEqual(av, bv) {
+			diff[k] = FieldChange{From: b[k], To: av}
+		}
+	}
+	for k, bv := range b {
+		if _, ok := a[k]; !ok {
+			diff[k] = FieldChange{From: bv, To: nil}
+		}
+	}
+	return diff
+}
+
+// RecordRevision stores next, the new state of an artwork, and what changed
+// since previous. The first revision of an existing artwork is preceded by a
+// baseline with previous. Nothing is recorded when nothing changed, unless
+// created is set.
+func RecordRevision(ctx context.Context, q Querier, artworkID, actor, action string, previous, next any, created bool) error {
+	diff := DiffFields(previous, next)
+	if len(diff) == 0 && !created {
+		return nil
+	}
+
+	if !created {
+		exists, err := HasRevisions(ctx, q, artworkID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			snapshot, _ := json.Marshal(previous)
+			if _, err := InsertRevision(ctx, q, RevisionRow{
+				ArtworkID: artworkID,
+				Actor:     actor,
+				Action:    RevisionActionBaseline,
+				Snapshot:  snapshot,
+				Diff:      []byte("{}"),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	snapshot, _ := json.Marshal(next)
+	diffJSON, _ := json.Marshal(diff)
+	_, err := InsertRevision(ctx, q, RevisionRow{
+		ArtworkID: artworkID,
+		Actor:     actor,
+		Action:    action,
+		Snapshot:  snapshot,
+		Diff:      diffJSON,
+	})
+	return err
+}
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type SeriesRow struct {
+	ID             int64
+	Slug           string
+	Title          string
+	Description    string
+	CoverArtworkID string
+	CoverImage     string
+	SortOrder      int
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	ArtworkIDs     []string // in series order
+}
+
+const seriesSelect = `
+	SELECT s.id, s.slug, s.title, s.description, s.cover_artwork_id, s.cover_image, s.sort_order, s.created_at, s.updated_at,
+		COALESCE(array_agg(sa.artwork_id ORDER BY sa.position, sa.artwork_id) FILTER (WHERE sa.artwork_id IS NOT NULL), '{}')
+	FROM series s
+	LEFT JOIN series_artworks sa ON sa.series_id = s.id
+`
+
+func scanSeriesRow(row pgx.Row) (SeriesRow, error) {
+	var r SeriesRow
+	err := row.Scan(&r.ID, &r.Slug, &r.Title, &r.Description, &r.CoverArtworkID, &r.CoverImage, &r.SortOrder, &r.CreatedAt, &r.UpdatedAt, &r.ArtworkIDs)
+	return r, err
+}
+
+// ListSeries returns all series ordered by sort_order, then title.
+func ListSeries(ctx context.Context, q Querier) ([]SeriesRow, error) {
+	rows, err := q.Query(ctx, seriesSelect+` GROUP BY s.id ORDER BY s.sort_order, s.title`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []SeriesRow
+	for rows.Next() {
+		r, err := scanSeriesRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+func GetSeries(ctx context.Context, q Querier, slug string) (*SeriesRow, error) {
+	r, err := scanSeriesRow(q.QueryRow(ctx, seriesSelect+` WHERE s.slug=$1 GROUP BY s.id`, slug))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+// InsertSeries creates a series and returns its id.
+func InsertSeries(ctx context.Context, q Querier, r SeriesRow) (int64, error) {
+	var id int64
+	err := q.QueryRow(ctx, `
+		INSERT INTO series (slug, title, description, cover_artwork_id, cover_image, sort_order)
+		VALUES ($1,$2,$3,$4,$5,$6)
+		RETURNING id
+	`, r.Slug, r.Title, r.Description, r.CoverArtworkID, r.CoverImage, r.SortOrder).Scan(&id)
+	return id, err
+}
+
+// UpdateSeries replaces the fields of series r.ID (including its slug).
+func UpdateSeries(ctx context.Context, q Querier, r SeriesRow) error {
+	_, err := q.Exec(ctx, `
+		UPDATE series SET
+			slug=$2,
+			title=$3,
+			description=$4,
+			cover_artwork_id=$5,
+			cover_image=$6,
+			sort_order=$7,
+			updated_at=NOW()
+		WHERE id=$1
+	`, r.ID, r.Slug, r.Title, r.Description, r.CoverArtworkID, r.CoverImage, r.SortOrder)
+	return err
+}
+
+// DeleteSeries removes a series and its memberships. It returns false when
+// the series does not exist.
+func DeleteSeries(ctx context.Context, q Querier, slug string) (bool, error) {
+	tag, err := q.Exec(ctx, `DELETE FROM series WHERE slug=$1`, slug)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// SetSeriesArtworks replaces the artworks of a series, in the given order.
+func SetSeriesArtworks(ctx context.Context, q Querier, seriesID int64, artworkIDs []string) error {
+	if _, err := q.Exec(ctx, `DELETE FROM series_artworks WHERE series_id=$1`, seriesID); err != nil {
+		return err
+	}
+	for i, id := range artworkIDs {
+		if _, err := q.Exec(ctx, `
+			INSERT INTO series_artworks (series_id, artwork_id, position) VALUES ($1,$2,$3)
+		`, seriesID, id, i); err != nil {
+			return err
+		}
+	}
+	_, err := q.Exec(ctx, `UPDATE series SET updated_at=NOW() WHERE id=$1`, seriesID)
+	return err
+}
+
+// LockSeries locks the row of a series until the transaction ends, so that
+// changes to its artworks do not interleave. It returns 0 when the series
+// does not exist.
+func LockSeries(ctx context.Context, q Querier, slug string) (int64, error) {
+	var id int64
+	err := q.QueryRow(ctx, `SELECT id FROM series WHERE slug=$1 FOR UPDATE`, slug).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// AddSeriesArtwork appends an artwork to a series. It returns false when the
+// artwork was already in it.
+func AddSeriesArtwork(ctx context.Context, q Querier, seriesID int64, artworkID string) (bool, error) {
+	tag, err := q.Exec(ctx, `
+		INSERT INTO series_artworks (series_id, artwork_id, position)
+		SELECT $1, $2, COALESCE(MAX(position) + 1, 0) FROM series_artworks WHERE series_id=$1
+		ON CONFLICT DO NOTHING
+	`, seriesID, artworkID)
+	if err != nil || tag.RowsAffected() == 0 {
+		return false, err
+	}
+	_, err = q.Exec(ctx, `UPDATE series SET updated_at=NOW() WHERE id=$1`, seriesID)
+	return true, err
+}
+
+// RemoveSeriesArtwork takes an artwork out of a series. It returns false when
+// the artwork was not in it.
+func RemoveSeriesArtwork(ctx context.Context, q Querier, seriesID int64, artworkID string) (bool, error) {
+	tag, err := q.Exec(ctx, `DELETE FROM series_artworks WHERE series_id=$1 AND artwork_id=$2`, seriesID, artworkID)
+	if err != nil || tag.RowsAffected() == 0 {
+		return false, err
+	}
+	_, err = q.Exec(ctx, `UPDATE series SET updated_at=NOW() WHERE id=$1`, seriesID)
+	return true, err
+}
+
+// ArtworkSeriesSlugs returns the slugs of the series an artwork is in.
+func ArtworkSeriesSlugs(ctx context.Context, q Querier, artworkID string) ([]string, error) {
+	rows, err := q.Query(ctx, `
+		SELECT s.slug FROM series s
+		JOIN series_artworks sa ON sa.series_id = s.id
+		WHERE sa.artwork_id=$1
+		ORDER BY s.slug
+	`, artworkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slugs := []string{}
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return nil, err
+		}
+		slugs = append(slugs, slug)
+	}
+	return slugs, rows.Err()
+}
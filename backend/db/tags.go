@@ -0,0 +1,190 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type TagRow struct {
+	ID        int64
+	Kind      string
+	Slug      string
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// Number of artworks with the tag; only set by ListTags.
+	Count int
+}
+
+// ListTags returns the tags of one kind (all kinds when empty) with how many
+// artworks carry each. With publicOnly, artworks that are not publicly
+// visible are not counted.
+func ListTags(ctx context.Context, q Querier, kind string, publicOnly bool) ([]TagRow, error) {
+	rows, err := q.Query(ctx, `
+		SELECT t.id, t.kind, t.slug, t.name, t.created_at, t.updated_at,
+			COUNT(at.artwork_id) FILTER (WHERE NOT $2 OR a.id IS NULL OR (a.status='published' AND (a.publish_at IS NULL OR a.publish_at <= NOW())))
+		FROM tags t
+		LEFT JOIN artwork_tags at ON at.tag_id = t.id
+		LEFT JOIN artworks a ON a.id = at.artwork_id
+		WHERE $1 = '' OR t.kind = $1
+		GROUP BY t.id
+		ORDER BY t.kind, t.name
+	`, kind, publicOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []TagRow
+	for rows.Next() {
+		var r TagRow
+		if err := rows.Scan(&r.ID, &r.Kind, &r.Slug, &r.Name, &r.CreatedAt, &r.UpdatedAt, &r.Count); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+func GetTag(ctx context.Context, q Querier, id int64) (*TagRow, error) {
+	var r TagRow
+	err := q.QueryRow(ctx, `
+		SELECT id, kind, slug, name, created_at, updated_at FROM tags WHERE id=$1
+	`, id).Scan(&r.ID, &r.Kind, &r.Slug, &r.Name, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+func InsertTag(ctx context.Context, q Querier, r TagRow) (*TagRow, error) {
+	err := q.QueryRow(ctx, `
+		INSERT INTO tags (kind, slug, name) VALUES ($1,$2,$3)
+		RETURNING id, created_at, updated_at
+	`, r.Kind, r.Slug, r.Name).Scan(&r.ID, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// UpdateTag renames a tag. It returns false when the tag does not exist.
+func UpdateTag(ctx context.Context, q Querier, r TagRow) (bool, error) {
+	tag, err := q.Exec(ctx, `
+		UPDATE tags SET kind=$2, slug=$3, name=$4, updated_at=NOW() WHERE id=$1
+	`, r.ID, r.Kind, r.Slug, r.Name)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeleteTag removes a tag from every artwork and deletes it.
+func DeleteTag(ctx context.Context, q Querier, id int64) (bool, error) {
+	tag, err := q.Exec(ctx, `DELETE FROM tags WHERE id=$1`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// TagsByArtwork returns the tags of every artwork that has any, by artwork
+// id, each ordered by kind and name.
+func TagsByArtwork(ctx context.Context, q Querier) (map[string][]TagRow, error) {
+	rows, err := q.Query(ctx, `
+		SELECT at.artwork_id, t.id, t.kind, t.slug, t.name, t.created_at, t.updated_at
+		FROM artwork_tags at
+		JOIN tags t ON t.id = at.tag_id
+		ORDER BY at.artwork_id, t.kind, t.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := map[string][]TagRow{}
+	for rows.Next() {
+		var artworkID string
+		var r TagRow
+		if err := rows.Scan(&artworkID, &r.ID, &r.Kind, &r.Slug, &r.Name, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		result[artworkID] = append(result[artworkID], r)
+	}
+	return result, rows.Err()
+}
+
+// ArtworkTags returns the tags of an artwork ordered by kind and name.
+func ArtworkTags(ctx context.Context, q Querier, artworkID string) ([]TagRow, error) {
+	rows, err := q.Query(ctx, `
+		SELECT t.id, t.kind, t.slug, t.name, t.created_at, t.updated_at
+		FROM artwork_tags at
+		JOIN tags t ON t.id = at.tag_id
+		WHERE at.artwork_id=$1
+		ORDER BY t.kind, t.name
+	`, artworkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []TagRow
+	for rows.Next() {
+		var r TagRow
+		if err := rows.Scan(&r.ID, &r.Kind, &r.Slug, &r.Name, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// SetArtworkTags replaces the tags of an artwork. It returns false, without
+// changing anything, when one of the tag ids does not exist.
+func SetArtworkTags(ctx context.Context, q Querier, artworkID string, tagIDs []int64) (bool, error) {
+	if tagIDs == nil {
+		// A nil slice is sent as NULL, which ANY() never matches.
+		tagIDs = []int64{}
+	}
+	var found int
+	if err := q.QueryRow(ctx, `SELECT COUNT(*) FROM tags WHERE id = ANY($1)`, tagIDs).Scan(&found); err != nil {
+		return false, err
+	}
+	if found != len(tagIDs) {
+		return false, nil
+	}
+	if _, err := q.Exec(ctx, `DELETE FROM artwork_tags WHERE artwork_id=$1 AND NOT (tag_id = ANY($2))`, artworkID, tagIDs); err != nil {
+		return false, err
+	}
+	if _, err := q.Exec(ctx, `
+		INSERT INTO artwork_tags (artwork_id, tag_id)
+		SELECT $1, unnest($2::bigint[])
+		ON CONFLICT DO NOTHING
+	`, artworkID, tagIDs); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// TagsStamp summarizes the tag assignments, for HTTP cache validation: any
+// assignment, removal or rename changes it. With an artwork id it only covers
+// that artwork.
+func TagsStamp(ctx context.Context, q Querier, artworkID string) (int, time.Time, error) {
+	var count int
+	var last *time.Time
+	err := q.QueryRow(ctx, `
+		SELECT COUNT(*), MAX(GREATEST(at.created_at, t.updated_at))
+		FROM artwork_tags at
+		JOIN tags t ON t.id = at.tag_id
+		WHERE $1 = '' OR at.artwork_id = $1
+	`, artworkID).Scan(&count, &last)
+	if err != nil || last == nil {
+		return count, time.Time{}, err
+	}
+	return count, *last, nil
+}
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+type TranslationRow struct {
+	ArtworkID string
+	Locale    string
+	Title     string
+	Detalle   string
+	UpdatedBy string
+	UpdatedAt time.Time
+}
+
+// ListTranslations returns the translations of one artwork and/or one locale;
+// an empty argument matches all.
+func ListTranslations(ctx context.Context, q Querier, artworkID, locale string) ([]TranslationRow, error) {
+	rows, err := q.Query(ctx, `
+		SELECT artwork_id, locale, title, detalle, updated_by, updated_at
+		FROM artwork_translations
+		WHERE ($1 = '' OR artwork_id = $1) AND ($2 = '' OR locale = $2)
+		ORDER BY artwork_id, locale
+	`, artworkID, locale)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []TranslationRow
+	for rows.Next() {
+		var r TranslationRow
+		if err := rows.Scan(&r.ArtworkID, &r.Locale, &r.Title, &r.Detalle, &r.UpdatedBy, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+func UpsertTranslation(ctx context.Context, q Querier, r TranslationRow) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO artwork_translations (artwork_id, locale, title, detalle, updated_by)
+		VALUES ($1,$2,$3,$4,$5)
+		ON CONFLICT (artwork_id, locale) DO UPDATE SET
+			title=EXCLUDED.title,
+			detalle=EXCLUDED.detalle,
+			updated_by=EXCLUDED.updated_by,
+			updated_at=NOW()
+	`, r.ArtworkID, r.Locale, r.Title, r.Detalle, r.UpdatedBy)
+	return err
+}
+
+func DeleteTranslation(ctx context.Context, q Querier, artworkID, locale string) (bool, error) {
+	tag, err := q.Exec(ctx, `DELETE FROM artwork_translations WHERE artwork_id=$1 AND locale=$2`, artworkID, locale)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// TranslationsStamp summarizes the translations of one artwork (all when
+// empty) for the catalog ETag.
+func TranslationsStamp(ctx context.Context, q Querier, artworkID string) (int, time.Time, error) {
+	var count int
+	var last *time.Time
+	err := q.QueryRow(ctx, `
+		SELECT COUNT(*), MAX(updated_at)
+		FROM artwork_translations
+		WHERE $1 = '' OR artwork_id = $1
+	`, artworkID).Scan(&count, &last)
+	if err != nil || last == nil {
+		return count, time.Time{}, err
+	}
+	return count, *last, nil
+}
+
+type BitacoraTranslationRow struct {
+	EntryID   int64
+	ArtworkID string
+	Locale    string
+	Body      string
+	UpdatedBy string
+	UpdatedAt time.Time
+}
+
+// ListBitacoraTranslations returns the translated bitácora entries of one
+// artwork and/or one locale; an empty argument matches all.
+func ListBitacoraTranslations(ctx context.Context, q Querier, artworkID, locale string) ([]BitacoraTranslationRow, error) {
+	rows, err := q.Query(ctx, `
+		SELECT t.entry_id, e.artwork_id, t.locale, t.body, t.updated_by, t.updated_at
+		FROM bitacora_entry_translations t
+		JOIN bitacora_entries e ON e.id = t.entry_id
+		WHERE ($1 = '' OR e.artwork_id = $1) AND ($2 = '' OR t.locale = $2)
+		ORDER BY e.artwork_id, t.locale, e.entry_date, e.id
+	`, artworkID, locale)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []BitacoraTranslationRow
+	for rows.Next() {
+		var r BitacoraTranslationRow
+		if err := rows.Scan(&r.EntryID, &r.ArtworkID, &r.Locale, &r.Body, &r.UpdatedBy, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// SetBitacoraTranslations replaces the translations of the entries of an
+// artwork into locale: entries not in bodies lose theirs. Entry ids must
+// belong to the artwork.
+func SetBitacoraTranslations(ctx context.Context, q Querier, artworkID, locale string, bodies map[int64]string, updatedBy string) error {
+	ids := make([]int64, 0, len(bodies))
+	for id := range bodies {
+		ids = append(ids, id)
+	}
+	if _, err := q.Exec(ctx, `
+		DELETE FROM bitacora_entry_translations t
+		USING bitacora_entries e
+		WHERE e.id = t.entry_id AND e.artwork_id=$1 AND t.locale=$2 AND NOT (t.entry_id = ANY($3))
+	`, artworkID, locale, ids); err != nil {
+		return err
+	}
+	for id, body := range bodies {
+		if _, err := q.Exec(ctx, `
+			INSERT INTO bitacora_entry_translations (entry_id, locale, body, updated_by)
+			VALUES ($1,$2,$3,$4)
+			ON CONFLICT (entry_id, locale) DO UPDATE SET
+				body=EXCLUDED.body,
+				updated_by=EXCLUDED.updated_by,
+				updated_at=NOW()
+			WHERE bitacora_entry_translations.body IS DISTINCT FROM EXCLUDED.body
+		`, id, locale, body, updatedBy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteBitacoraTranslations removes the translations of the entries of an
+// artwork into locale and returns how many there were.
+func DeleteBitacoraTranslations(ctx context.Context, q Querier, artworkID, locale string) (int64, error) {
+	tag, err := q.Exec(ctx, `
+		DELETE FROM bitacora_entry_translations t
+		USING bitacora_entries e
+		WHERE e.id = t.entry_id AND e.artwork_id=$1 AND t.locale=$2
+	`, artworkID, locale)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// BitacoraTranslationsStamp is the TranslationsStamp of bitácora entries.
+func BitacoraTranslationsStamp(ctx context.Context, q Querier, artworkID string) (int, time.Time, error) {
+	var count int
+	var last *time.Time
+	err := q.QueryRow(ctx, `
+		SELECT COUNT(*), MAX(t.updated_at)
+		FROM bitacora_entry_translations t
+		JOIN bitacora_entries e ON e.id = t.entry_id
+		WHERE $1 = '' OR e.artwork_id = $1
+	`, artworkID).Scan(&count, &last)
+	if err != nil || last == nil {
+		return count, time.Time{}, err
+	}
+	return count, *last, nil
+}
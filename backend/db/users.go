@@ -0,0 +1,214 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type AdminUserRow struct {
+	ID           int64
+	Email        string
+	Name         string
+	Role         string
+	PasswordHash string
+	DisabledAt   *time.Time
+	LastLoginAt  *time.Time
+	CreatedBy    string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+const adminUserColumns = `u.id, u.email, u.name, u.role, u.password_hash, u.disabled_at, u.last_login_at, u.created_by, u.created_at, u.updated_at`
+
+func scanAdminUserRow(row pgx.Row) (AdminUserRow, error) {
+	var r AdminUserRow
+	err := row.Scan(&r.ID, &r.Email, &r.Name, &r.Role, &r.PasswordHash, &r.DisabledAt, &r.LastLoginAt, &r.CreatedBy, &r.CreatedAt, &r.UpdatedAt)
+	return r, err
+}
+
+func getAdminUser(ctx context.Context, q Querier, where string, arg any) (*AdminUserRow, error) {
+	r, err := scanAdminUserRow(q.QueryRow(ctx, `SELECT `+adminUserColumns+` FROM admin_users u WHERE `+where, arg))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+func GetAdminUser(ctx context.Context, q Querier, id int64) (*AdminUserRow, error) {
+	return getAdminUser(ctx, q, `u.id=$1`, id)
+}
+
+// GetAdminUserByEmail looks an account up by email, ignoring case.
+func GetAdminUserByEmail(ctx context.Context, q Querier, email string) (*AdminUserRow, error) {
+	return getAdminUser(ctx, q, `lower(u.email)=lower($1)`, email)
+}
+
+func ListAdminUsers(ctx context.Context, q Querier) ([]AdminUserRow, error) {
+	rows, err := q.Query(ctx, `SELECT `+adminUserColumns+` FROM admin_users u ORDER BY u.email`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []AdminUserRow
+	for rows.Next() {
+		r, err := scanAdminUserRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+func InsertAdminUser(ctx context.Context, q Querier, r AdminUserRow) (int64, error) {
+	var id int64
+	err := q.QueryRow(ctx, `
+		INSERT INTO admin_users (email, name, role, password_hash, created_by)
+		VALUES ($1,$2,$3,$4,$5)
+		RETURNING id
+	`, r.Email, r.Name, r.Role, r.PasswordHash, r.CreatedBy).Scan(&id)
+	return id, err
+}
+
+// UpdateAdminUser saves email, name, role and the disabled state. The password is
+// only changed when PasswordHash is set.
+func UpdateAdminUser(ctx context.Context, q Querier, r AdminUserRow) (bool, error) {
+	tag, err := q.Exec(ctx, `
+		UPDATE admin_users SET
+			email=$2,
+			name=$3,
+			role=$4,
+			password_hash=COALESCE(NULLIF($5,''), password_hash),
+			disabled_at=$6,
+			updated_at=NOW()
+		WHERE id=$1
+	`, r.ID, r.Email, r.Name, r.Role, r.PasswordHash, r.DisabledAt)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func TouchAdminUserLogin(ctx context.Context, q Querier, id int64) error {
+	_, err := q.Exec(ctx, `UPDATE admin_users SET last_login_at=NOW() WHERE id=$1`, id)
+	return err
+}
+
+type AdminSessionRow struct {
+	ID          string
+	UserID      int64
+	RefreshHash string
+	UserAgent   string
+	IP          string
+	CreatedAt   time.Time
+	RefreshedAt time.Time
+	ExpiresAt   time.Time
+	RevokedAt   *time.Time
+}
+
+const adminSessionColumns = `id, user_id, refresh_hash, user_agent, ip, created_at, refreshed_at, expires_at, revoked_at`
+
+func scanAdminSessionRow(row pgx.Row) (AdminSessionRow, error) {
+	var r AdminSessionRow
+	err := row.Scan(&r.ID, &r.UserID, &r.RefreshHash, &r.UserAgent, &r.IP, &r.CreatedAt, &r.RefreshedAt, &r.ExpiresAt, &r.RevokedAt)
+	return r, err
+}
+
+func InsertAdminSession(ctx context.Context, q Querier, r AdminSessionRow) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO admin_sessions (id, user_id, refresh_hash, user_agent, ip, expires_at)
+		VALUES ($1,$2,$3,$4,$5,$6)
+	`, r.ID, r.UserID, r.RefreshHash, r.UserAgent, r.IP, r.ExpiresAt)
+	return err
+}
+
+// GetSessionUser returns the account of a session that is still active: not
+// revoked, not expired and with the account enabled.
+func GetSessionUser(ctx context.Context, q Querier, sessionID string) (*AdminUserRow, error) {
+	r, err := scanAdminUserRow(q.QueryRow(ctx, `
+		SELECT `+adminUserColumns+`
+		FROM admin_sessions s JOIN admin_users u ON u.id = s.user_id
+		WHERE s.id=$1 AND s.revoked_at IS NULL AND s.expires_at > NOW() AND u.disabled_at IS NULL
+	`, sessionID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+// RotateAdminSession swaps the refresh token of an active session and extends
+// it. It returns nil when no active session has that refresh token, so a
+// token can only be used once.
+func RotateAdminSession(ctx context.Context, q Querier, refreshHash, newRefreshHash string, expiresAt time.Time) (*AdminSessionRow, error) {
+	r, err := scanAdminSessionRow(q.QueryRow(ctx, `
+		UPDATE admin_sessions SET refresh_hash=$2, refreshed_at=NOW(), expires_at=$3
+		WHERE refresh_hash=$1 AND revoked_at IS NULL AND expires_at > NOW()
+		RETURNING `+adminSessionColumns, refreshHash, newRefreshHash, expiresAt))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ListAdminSessions returns the sessions of an account that are still
+// active, newest first.
+func ListAdminSessions(ctx context.Context, q Querier, userID int64) ([]AdminSessionRow, error) {
+	rows, err := q.Query(ctx, `
+		SELECT `+adminSessionColumns+`
+		FROM admin_sessions
+		WHERE user_id=$1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []AdminSessionRow
+	for rows.Next() {
+		r, err := scanAdminSessionRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// RevokeAdminSession ends one session, by id or by refresh token hash. It
+// returns false when there was no active session to revoke.
+func RevokeAdminSession(ctx context.Context, q Querier, id, refreshHash string) (bool, error) {
+	tag, err := q.Exec(ctx, `
+		UPDATE admin_sessions SET revoked_at=NOW()
+		WHERE (id=$1 OR refresh_hash=$2) AND revoked_at IS NULL
+	`, id, refreshHash)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RevokeAdminUserSessions ends every session of an account but keepID (if
+// any) and returns how many were active.
+func RevokeAdminUserSessions(ctx context.Context, q Querier, userID int64, keepID string) (int64, error) {
+	tag, err := q.Exec(ctx, `
+		UPDATE admin_sessions SET revoked_at=NOW()
+		WHERE user_id=$1 AND id<>$2 AND revoked_at IS NULL
+	`, userID, keepID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
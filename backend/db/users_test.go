@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testPool connects to TEST_DATABASE_URL and runs the migrations; tests that
+// need Postgres are skipped without it.
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	pool, err := Connect(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	if err := Migrate(ctx, pool, "migrations"); err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
+
+func TestRotateAdminSession(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+
+	email := "rotate-" + strconv.FormatInt(time.Now().UnixNano(), 10) + "@example.com"
+	userID, err := InsertAdminUser(ctx, pool, AdminUserRow{Email: email, Role: "editor", PasswordHash: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pool.Exec(context.Background(), `DELETE FROM admin_users WHERE id=$1`, userID) })
+
+	suffix := strconv.FormatInt(userID, 10)
+	insert := func(id, refreshHash string, expiresAt time.Time) {
+		t.Helper()
+		if err := InsertAdminSession(ctx, pool, AdminSessionRow{ID: id + suffix, UserID: userID, RefreshHash: refreshHash + suffix, ExpiresAt: expiresAt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	insert("active", "r1", time.Now().Add(time.Hour))
+	insert("expired", "e1", time.Now().Add(-time.Minute))
+	insert("revoked", "v1", time.Now().Add(time.Hour))
+	if _, err := RevokeAdminSession(ctx, pool, "revoked"+suffix, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	extended := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	tests := []struct {
+		name, from, to string
+		wantSession    string
+	}{
+		{"rotates", "r1", "r2", "active"},
+		{"old token is spent", "r1", "r3", ""},
+		{"new token works once", "r2", "r3", "active"},
+		{"reusing it fails", "r2", "r4", ""},
+		{"expired session", "e1", "e2", ""},
+		{"revoked session", "v1", "v2", ""},
+		{"unknown token", "nope", "n2", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, err := RotateAdminSession(ctx, pool, tt.from+suffix, tt.to+suffix, extended)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantSession == "" {
+				if session != nil {
+					t.Fatalf("RotateAdminSession = session %s, want none", session.ID)
+				}
+				return
+			}
+			if session == nil || session.ID != tt.wantSession+suffix {
+				t.Fatalf("RotateAdminSession = %+v, want session %s", session, tt.wantSession)
+			}
+			if session.RefreshHash != tt.to+suffix || !session.ExpiresAt.Equal(extended) {
+				t.Errorf("RotateAdminSession = hash %q, expires %v, want %q, %v", session.RefreshHash, session.ExpiresAt, tt.to+suffix, extended)
+			}
+		})
+	}
+}
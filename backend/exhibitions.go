@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"alexis-art-backend/db"
+)
+
+// When an exhibition takes place relative to today.
+const (
+	exhibitionPast     = "past"
+	exhibitionCurrent  = "current"
+	exhibitionUpcoming = "upcoming"
+)
+
+const maxLinkLength = 2000
+
+// ArtworkExhibition is an exhibition as embedded in Artwork responses. It has
+// no past/current/upcoming status, so the artwork ETag does not depend on the
+// date.
+type ArtworkExhibition struct {
+	Slug      string `json:"slug"`
+	Name      string `json:"name"`
+	Venue     string `json:"venue,omitempty"`
+	City      string `json:"city,omitempty"`
+	Country   string `json:"country,omitempty"`
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate,omitempty"`
+}
+
+type ExhibitionResponse struct {
+	ArtworkExhibition
+	Status      string    `json:"status"`
+	Description string    `json:"description,omitempty"`
+	Link        string    `json:"link,omitempty"`
+	PosterImage string    `json:"posterImage,omitempty"`
+	ArtworkIDs  []string  `json:"artworkIds"`
+	Artworks    []Artwork `json:"artworks,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+type ExhibitionListResponse struct {
+	Exhibitions []ExhibitionResponse `json:"exhibitions"`
+	Total       int                  `json:"total"`
+}
+
+type adminExhibitionPayload struct {
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	Venue       string `json:"venue"`
+	City        string `json:"city"`
+	Country     string `json:"country"`
+	StartDate   string `json:"startDate"`
+	EndDate     string `json:"endDate"`
+	Description string `json:"description"`
+	Link        string `json:"link"`
+	// URL of the poster, absolute or a path on this API.
+	PosterImage string `json:"posterImage"`
+	// Omitted leaves the artworks of the exhibition unchanged.
+	ArtworkIDs *[]string `json:"artworkIds"`
+}
+
+func (p *adminExhibitionPayload) normalize() {
+	for _, f := range []*string{&p.Slug, &p.Name, &p.Venue, &p.City, &p.Country, &p.StartDate, &p.EndDate, &p.Link, &p.PosterImage} {
+		*f = strings.TrimSpace(*f)
+	}
+	if p.Slug == "" {
+		p.Slug = slugify(p.StartDate[:min(4, len(p.StartDate))] + " " + p.Name)
+	}
+	if p.ArtworkIDs != nil {
+		ids := make([]string, 0, len(*p.ArtworkIDs))
+		for _, id := range *p.ArtworkIDs {
+			ids = append(ids, strings.TrimSpace(id))
+		}
+		p.ArtworkIDs = &ids
+	}
+}
+
+func (p adminExhibitionPayload) validate() error {
+	var errs validationErrors
+	if p.Name == "" {
+		errs.add("name", codeRequired, "is required")
+	}
+	errs.checkLength("name", p.Name, maxTitleLength)
+	if !slugPattern.MatchString(p.Slug) || len(p.Slug) > maxSlugLength {
+		errs.add("slug", codeInvalidValue, "must be lowercase letters, digits and dashes (at most 100)")
+	}
+	errs.checkLength("venue", p.Venue, maxLocationLength)
+	errs.checkLength("city", p.City, maxLocationLength)
+	errs.checkLength("country", p.Country, maxLocationLength)
+	errs.checkLength("description", p.Description, maxDescriptionLength)
+
+	if p.StartDate == "" {
+		errs.add("startDate", codeRequired, "is required")
+	}
+	start := errs.checkDate("startDate", p.StartDate)
+	end := errs.checkDate("endDate", p.EndDate)
+	if start != nil && end != nil && end.Before(*start) {
+		errs.add("endDate", codeEndBeforeStart, "must be on or after startDate")
+	}
+
+	for _, f := range []struct{ name, value string }{{"link", p.Link}, {"posterImage", p.PosterImage}} {
+		if f.value == "" {
+			continue
+		}
+		errs.checkLength(f.name, f.value, maxLinkLength)
+		if u, err := url.Parse(f.value); err != nil || !(u.Scheme == "http" || u.Scheme == "https" || (u.Scheme == "" && strings.HasPrefix(f.value, "/"))) {
+			errs.add(f.name, codeInvalidValue, "must be an http(s) URL or a path starting with /")
+		}
+	}
+
+	if p.ArtworkIDs != nil {
+		for _, id := range *p.ArtworkIDs {
+			if !isSafeArtworkID(id) {
+				errs.add("artworkIds", codeInvalidValue, "contains an invalid artwork id")
+				break
+			}
+		}
+	}
+	return errs.err()
+}
+
+// exhibitionStatus places an exhibition relative to today. One without an
+// end date lasts a single day.
+func exhibitionStatus(start time.Time, end *time.Time, today time.Time) string {
+	last := start
+	if end != nil {
+		last = *end
+	}
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	switch {
+	case start.After(today):
+		return exhibitionUpcoming
+	case last.Before(today):
+		return exhibitionPast
+	default:
+		return exhibitionCurrent
+	}
+}
+
+func artworkExhibition(row db.ExhibitionRow) ArtworkExhibition {
+	e := ArtworkExhibition{
+		Slug:      row.Slug,
+		Name:      row.Name,
+		Venue:     row.Venue,
+		City:      row.City,
+		Country:   row.Country,
+		StartDate: row.StartDate.Format(dateLayout),
+	}
+	if row.EndDate != nil {
+		e.EndDate = row.EndDate.Format(dateLayout)
+	}
+	return e
+}
+
+// exhibitionResponse builds the response for an exhibition; hidden (public
+// responses only) lists artworks to leave out.
+func exhibitionResponse(row db.ExhibitionRow, hidden map[string]bool, today time.Time) ExhibitionResponse {
+	resp := ExhibitionResponse{
+		ArtworkExhibition: artworkExhibition(row),
+		Status:            exhibitionStatus(row.StartDate, row.EndDate, today),
+		Description:       row.Description,
+		Link:              row.Link,
+		PosterImage:       row.PosterImage,
+		ArtworkIDs:        make([]string, 0, len(row.ArtworkIDs)),
+		CreatedAt:         row.CreatedAt,
+		UpdatedAt:         row.UpdatedAt,
+	}
+	for _, id := range row.ArtworkIDs {
+		if !hidden[id] {
+			resp.ArtworkIDs = append(resp.ArtworkIDs, id)
+		}
+	}
+	return resp
+}
+
+// attachArtworkExhibitions sets the exhibitions an artwork was shown in from
+// their rows.
+func attachArtworkExhibitions(artwork *Artwork, rows []db.ExhibitionRow) {
+	artwork.Exhibitions = nil
+	for _, row := range rows {
+		artwork.Exhibitions = append(artwork.Exhibitions, artworkExhibition(row))
+	}
+}
+
+// getExhibitions lists exhibitions; ?when=past|current|upcoming selects one
+// group. Upcoming exhibitions are listed soonest first, the rest most recent
+// first.
+func getExhibitions(w http.ResponseWriter, r *http.Request) {
+	when := strings.TrimSpace(r.URL.Query().Get("when"))
+	if when != "" && when != exhibitionPast && when != exhibitionCurrent && when != exhibitionUpcoming {
+		respondWithError(w, http.StatusBadRequest, "Invalid when: must be past, current or upcoming")
+		return
+	}
+
+	list := []ExhibitionResponse{}
+	if pgPool != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		defer cancel()
+		rows, err := db.ListExhibitions(ctx, pgPool)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to list exhibitions")
+			return
+		}
+		hidden, err := db.HiddenArtworkIDs(ctx, pgPool)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to list exhibitions")
+			return
+		}
+		today := time.Now()
+		for _, row := range rows {
+			e := exhibitionResponse(row, hidden, today)
+			if when == "" || e.Status == when {
+				list = append(list, e)
+			}
+		}
+		if when == exhibitionUpcoming {
+			slices.Reverse(list)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ExhibitionListResponse{Exhibitions: list, Total: len(list)})
+}
+
+// getExhibition returns an exhibition with the public artworks shown in it.
+func getExhibition(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusNotFound, "Exhibition not found")
+		return
+	}
+	format, ok := parseTextFormat(r.URL.Query())
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, invalidTextFormatMessage())
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	row, err := db.GetExhibition(ctx, pgPool, mux.Vars(r)["slug"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to read exhibition")
+		return
+	}
+	if row == nil {
+		respondWithError(w, http.StatusNotFound, "Exhibition not found")
+		return
+	}
+	hidden, err := db.HiddenArtworkIDs(ctx, pgPool)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to read exhibition")
+		return
+	}
+
+	resp := exhibitionResponse(*row, hidden, time.Now())
+	resp.Artworks = []Artwork{}
+	for _, id := range resp.ArtworkIDs {
+		a, err := getArtworkByID(r.Context(), id)
+		if err != nil || !a.isPublic() {
+			continue
+		}
+		resp.Artworks = append(resp.Artworks, a.publicView())
+	}
+	locale := requestLocale(r)
+	localizeArtworks(r.Context(), locale, resp.Artworks)
+	renderArtworkTexts(resp.Artworks, format)
+
+	setLocaleHeaders(w, locale)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func adminListExhibitions(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	rows, err := db.ListExhibitions(ctx, pgPool)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list exhibitions")
+		return
+	}
+
+	today := time.Now()
+	list := make([]ExhibitionResponse, 0, len(rows))
+	for _, row := range rows {
+		list = append(list, exhibitionResponse(row, nil, today))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ExhibitionListResponse{Exhibitions: list, Total: len(list)})
+}
+
+func adminGetExhibition(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	row, err := db.GetExhibition(ctx, pgPool, mux.Vars(r)["slug"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to read exhibition")
+		return
+	}
+	if row == nil {
+		respondWithError(w, http.StatusNotFound, "Exhibition not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exhibitionResponse(*row, nil, time.Now()))
+}
+
+var exhibitionCollection = artworkCollection[db.ExhibitionRow]{
+	name: "Exhibition",
+	noun: "exhibition",
+	// Artwork responses embed their exhibitions.
+	embedded:       true,
+	revisionAction: revisionActionExhibitions,
+	get:            db.GetExhibition,
+	lock:           db.LockExhibition,
+	insert:         db.InsertExhibition,
+	update: func(ctx context.Context, q db.Querier, id int64, row db.ExhibitionRow) error {
+		row.ID = id
+		return db.UpdateExhibition(ctx, q, row)
+	},
+	setArtworks:   db.SetExhibitionArtworks,
+	addArtwork:    db.AddExhibitionArtwork,
+	removeArtwork: db.RemoveExhibitionArtwork,
+	slug:          func(row db.ExhibitionRow) string { return row.Slug },
+	artworkIDs:    func(row db.ExhibitionRow) []string { return row.ArtworkIDs },
+	response:      func(row db.ExhibitionRow) any { return exhibitionResponse(row, nil, time.Now()) },
+}
+
+func adminCreateExhibition(w http.ResponseWriter, r *http.Request) {
+	saveExhibition(w, r, "")
+}
+
+func adminUpdateExhibition(w http.ResponseWriter, r *http.Request) {
+	saveExhibition(w, r, mux.Vars(r)["slug"])
+}
+
+// saveExhibition creates an exhibition (slug == "") or replaces the fields of
+// an existing one, together with its artworks when the payload lists them.
+func saveExhibition(w http.ResponseWriter, r *http.Request, slug string) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	var payload adminExhibitionPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	payload.normalize()
+	if err := payload.validate(); err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+	start, _ := parseDate(payload.StartDate)
+	end, _ := parseDate(payload.EndDate)
+
+	exhibitionCollection.save(w, r, slug, db.ExhibitionRow{
+		Slug:        payload.Slug,
+		Name:        payload.Name,
+		Venue:       payload.Venue,
+		City:        payload.City,
+		Country:     payload.Country,
+		StartDate:   *start,
+		EndDate:     end,
+		Description: payload.Description,
+		Link:        payload.Link,
+		PosterImage: payload.PosterImage,
+	}, payload.ArtworkIDs)
+}
+
+func adminDeleteExhibition(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	deleted, err := db.DeleteExhibition(ctx, pgPool, mux.Vars(r)["slug"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete exhibition")
+		return
+	}
+	if !deleted {
+		respondWithError(w, http.StatusNotFound, "Exhibition not found")
+		return
+	}
+	bumpCatalogVersion()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func adminAddExhibitionArtwork(w http.ResponseWriter, r *http.Request) {
+	exhibitionCollection.changeArtwork(w, r, true)
+}
+
+func adminRemoveExhibitionArtwork(w http.ResponseWriter, r *http.Request) {
+	exhibitionCollection.changeArtwork(w, r, false)
+}
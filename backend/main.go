@@ -3,8 +3,10 @@ package main
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -13,14 +15,17 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 	"github.com/rs/cors"
 
 	"alexis-art-backend/db"
+	"alexis-art-backend/media"
 )
 
 const maxUploadSize = 10 << 20 // 10MB
@@ -35,8 +40,21 @@ type Artwork struct {
 	StartDate       string   `json:"startDate,omitempty"`
 	EndDate         string   `json:"endDate,omitempty"`
 	InProgress      bool     `json:"inProgress,omitempty"`
-	Bitacora        string   `json:"bitacora,omitempty"`
-	PrimaryImage    string   `json:"primaryImage,omitempty"`
+	// Bitacora is the old free-text bitácora (bitacora.txt or the bitacora
+	// column). It is only read to import it as the first entry of
+	// BitacoraEntries, which replaced it.
+	Bitacora        string              `json:"-"`
+	PrimaryImage    string              `json:"primaryImage,omitempty"`
+	Status          string              `json:"status"`
+	PublishAt       *time.Time          `json:"publishAt,omitempty"`
+	Technique       string              `json:"technique,omitempty"`
+	Dimensions      *Dimensions         `json:"dimensions,omitempty"`
+	Price           *Price              `json:"price,omitempty"`
+	Availability    string              `json:"availability,omitempty"`
+	Tags            []Tag               `json:"tags,omitempty"`
+	Exhibitions     []ArtworkExhibition `json:"exhibitions,omitempty"`
+	BitacoraEntries []BitacoraEntry     `json:"bitacoraEntries,omitempty"`
+	Rendered        *Rendered           `json:"rendered,omitempty"`
 }
 
 type artworkMeta struct {
@@ -44,6 +62,29 @@ type artworkMeta struct {
 	StartDate       string `json:"startDate"`
 	EndDate         string `json:"endDate"`
 	InProgress      bool   `json:"inProgress"`
+	// Sales fields are omitted when unset to keep older files unchanged.
+	Technique    string      `json:"technique,omitempty"`
+	Dimensions   *Dimensions `json:"dimensions,omitempty"`
+	Price        *Price      `json:"price,omitempty"`
+	Availability string      `json:"availability,omitempty"`
+}
+
+// applyTo fills the fields of an artwork that come from its meta.json.
+func (m artworkMeta) applyTo(artwork *Artwork) {
+	if artwork.PaintedLocation == "" {
+		artwork.PaintedLocation = strings.TrimSpace(m.PaintedLocation)
+	}
+	if artwork.StartDate == "" {
+		artwork.StartDate = strings.TrimSpace(m.StartDate)
+	}
+	if artwork.EndDate == "" {
+		artwork.EndDate = strings.TrimSpace(m.EndDate)
+	}
+	artwork.InProgress = m.InProgress
+	artwork.Technique = strings.TrimSpace(m.Technique)
+	artwork.Dimensions = m.Dimensions
+	artwork.Price = m.Price
+	artwork.Availability = strings.TrimSpace(m.Availability)
 }
 
 type adminArtworkUpdate struct {
@@ -53,8 +94,14 @@ type adminArtworkUpdate struct {
 	EndDate         string `json:"endDate"`
 	InProgress      bool   `json:"inProgress"`
 	Detalle         string `json:"detalle"`
-	Bitacora        string `json:"bitacora"`
-	PrimaryImage    string `json:"primaryImage"`
+	// Read-only: the bitácora is edited as entries (/bitacora routes), so
+	// sending it is a validation error.
+	Bitacora     *string     `json:"bitacora,omitempty"`
+	PrimaryImage string      `json:"primaryImage"`
+	Technique    string      `json:"technique"`
+	Dimensions   *Dimensions `json:"dimensions"`
+	Price        *Price      `json:"price"`
+	Availability string      `json:"availability"`
 }
 
 type adminArtworkCreate struct {
@@ -67,7 +114,25 @@ type ArtworkListResponse struct {
 }
 
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields,omitempty"`
+	// Set on 403: the permission the role of the caller lacks.
+	MissingPermission string `json:"missingPermission,omitempty"`
+}
+
+// apiError is an error that maps directly to an HTTP error response.
+type apiError struct {
+	Code    int
+	Message string
+}
+
+func (e *apiError) Error() string { return e.Message }
+
+// ConflictResponse is returned with 412 when If-Match no longer matches, so
+// the client can show what changed without another round-trip.
+type ConflictResponse struct {
+	Error   string  `json:"error"`
+	Current Artwork `json:"current"`
 }
 
 var artworksDir string
@@ -86,6 +151,22 @@ func main() {
 
 	adminToken = os.Getenv("ADMIN_TOKEN")
 
+	// Key for signing preview links; derived from ADMIN_TOKEN when unset so
+	// previews work without extra configuration (rotating either one
+	// invalidates all links).
+	previewSecret = secretFromEnv("PREVIEW_SECRET", "alexis-art preview links")
+
+	// Key for signing admin access tokens; same fallback as previews, with
+	// its own label. Rotating it logs everyone out of their current access
+	// token (refresh tokens keep working).
+	sessionSecret = secretFromEnv("SESSION_SECRET", "alexis-art session tokens")
+
+	// Without it X-Forwarded-For is ignored and the caller is RemoteAddr.
+	trustedProxies = parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+
+	// Printed on certificates of authenticity; defaults to this API.
+	certificateVerifyBaseURL = strings.TrimSpace(os.Getenv("CERTIFICATE_VERIFY_URL"))
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8090"
@@ -120,6 +201,8 @@ func main() {
 		pgPool = pool
 		if pgPool != nil {
 			log.Printf("Postgres enabled")
+			// Scans all storage, so it must not delay startup.
+			go importLegacyBitacoras(context.Background())
 		}
 	}
 
@@ -129,19 +212,22 @@ func main() {
 	api := r.PathPrefix("/api/v1").Subrouter()
 	api.HandleFunc("/artworks", getArtworks).Methods("GET")
 	api.HandleFunc("/artworks/{id}", getArtwork).Methods("GET")
-	api.HandleFunc("/artworks/{id}/images/{filename}", serveImage).Methods("GET")
-	api.HandleFunc("/artworks/{id}/videos/{filename}", serveVideo).Methods("GET")
-
-	// Admin API (token required)
+	api.HandleFunc("/artworks/{id}/images/{filename}", serveImage).Methods("GET", "HEAD")
+	api.HandleFunc("/artworks/{id}/videos/{filename}", serveVideo).Methods("GET", "HEAD")
+	api.HandleFunc("/tags", getTags).Methods("GET")
+	api.HandleFunc("/exhibitions", getExhibitions).Methods("GET")
+	api.HandleFunc("/exhibitions/{slug}", getExhibition).Methods("GET")
+	api.HandleFunc("/series", getSeriesList).Methods("GET")
+	api.HandleFunc("/series/{slug}", getSeries).Methods("GET")
+	api.HandleFunc("/certificates/{code}", getCertificate).Methods("GET")
+	api.HandleFunc("/auth/login", authLogin).Methods("POST")
+	api.HandleFunc("/auth/refresh", authRefresh).Methods("POST")
+	api.HandleFunc("/auth/logout", authLogout).Methods("POST")
+
+	// Admin API (ADMIN_TOKEN, an API key or a session access token required).
 	admin := api.PathPrefix("/admin").Subrouter()
-	admin.Use(adminAuthMiddleware)
-	admin.HandleFunc("/artworks", adminListArtworks).Methods("GET")
-	admin.HandleFunc("/artworks", adminCreateArtwork).Methods("POST")
-	admin.HandleFunc("/artworks/{id}", adminGetArtwork).Methods("GET")
-	admin.HandleFunc("/artworks/{id}", adminUpsertArtwork).Methods("PUT")
-	admin.HandleFunc("/artworks/{id}/images", adminUploadImage).Methods("POST")
-	admin.HandleFunc("/artworks/{id}/images/{filename}", adminDeleteImage).Methods("DELETE")
-	admin.HandleFunc("/artworks/check-title", adminCheckTitle).Methods("GET")
+	admin.Use(adminAuthMiddleware, auditMiddleware)
+	registerAdminRoutes(admin)
 
 	// Health check
 	r.HandleFunc("/health", healthCheck).Methods("GET")
@@ -149,8 +235,9 @@ func main() {
 	// CORS configuration
 	c := cors.New(cors.Options{
 		AllowedOrigins: []string{"*"}, // In production, specify exact origins
-		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders: []string{"*"},
+		ExposedHeaders: []string{"ETag"},
 	})
 
 	handler := c.Handler(r)
@@ -160,92 +247,228 @@ func main() {
 	log.Fatal(http.ListenAndServe(":"+port, handler))
 }
 
+// registerAdminRoutes adds the admin API to admin, which authenticates the
+// caller. Each route names the permission it requires (roles.go).
+func registerAdminRoutes(admin *mux.Router) {
+	admin.HandleFunc("/artworks", requirePermission(permDraftsRead, adminListArtworks)).Methods("GET")
+	admin.HandleFunc("/artworks", requirePermission(permArtworksWrite, adminCreateArtwork)).Methods("POST")
+	// Before /artworks/{id}, which would otherwise match it.
+	admin.HandleFunc("/artworks/check-title", requirePermission(permArtworksRead, adminCheckTitle)).Methods("GET")
+	admin.HandleFunc("/artworks/{id}", requirePermission(permDraftsRead, adminGetArtwork)).Methods("GET")
+	admin.HandleFunc("/artworks/{id}", requirePermission(permArtworksWrite, adminUpsertArtwork)).Methods("PUT")
+	admin.HandleFunc("/artworks/{id}", requirePermission(permArtworksWrite, adminPatchArtwork)).Methods("PATCH")
+	admin.HandleFunc("/artworks/{id}/images", requirePermission(permMediaUpload, adminUploadImage)).Methods("POST")
+	admin.HandleFunc("/artworks/{id}/images/{filename}", requirePermission(permContentDelete, adminDeleteImage)).Methods("DELETE")
+	admin.HandleFunc("/artworks/{id}/publish", requirePermission(permArtworksWrite, adminPublishArtwork)).Methods("POST")
+	admin.HandleFunc("/artworks/{id}/unpublish", requirePermission(permArtworksWrite, adminUnpublishArtwork)).Methods("POST")
+	admin.HandleFunc("/artworks/{id}/archive", requirePermission(permContentDelete, adminArchiveArtwork)).Methods("POST")
+	admin.HandleFunc("/artworks/{id}/preview-links", requirePermission(permArtworksRead, adminListPreviewLinks)).Methods("GET")
+	admin.HandleFunc("/artworks/{id}/preview-links", requirePermission(permArtworksWrite, adminCreatePreviewLink)).Methods("POST")
+	admin.HandleFunc("/preview-links/{linkId}", requirePermission(permArtworksWrite, adminRevokePreviewLink)).Methods("DELETE")
+	admin.HandleFunc("/artworks/{id}/revisions", requirePermission(permArtworksRead, adminListRevisions)).Methods("GET")
+	admin.HandleFunc("/artworks/{id}/revisions/compare", requirePermission(permArtworksRead, adminCompareRevisions)).Methods("GET")
+	admin.HandleFunc("/artworks/{id}/revisions/{rev:[0-9]+}", requirePermission(permArtworksRead, adminGetRevision)).Methods("GET")
+	admin.HandleFunc("/artworks/{id}/revisions/{rev:[0-9]+}/rollback", requirePermission(permArtworksWrite, adminRollbackRevision)).Methods("POST")
+	admin.HandleFunc("/artworks/{id}/provenance", requirePermission(permProvenance, adminListProvenance)).Methods("GET")
+	admin.HandleFunc("/artworks/{id}/provenance", requirePermission(permProvenance, adminCreateProvenance)).Methods("POST")
+	admin.HandleFunc("/artworks/{id}/provenance/{recordId:[0-9]+}", requirePermission(permProvenance, adminUpdateProvenance)).Methods("PUT")
+	admin.HandleFunc("/artworks/{id}/provenance/{recordId:[0-9]+}", requirePermission(permProvenance, adminDeleteProvenance)).Methods("DELETE")
+	admin.HandleFunc("/provenance.csv", requirePermission(permProvenance, adminExportProvenance)).Methods("GET")
+	admin.HandleFunc("/artworks/{id}/certificates", requirePermission(permCertificates, adminListCertificates)).Methods("GET")
+	admin.HandleFunc("/artworks/{id}/certificates", requirePermission(permCertificates, adminIssueCertificate)).Methods("POST")
+	admin.HandleFunc("/certificates/{serial}/pdf", requirePermission(permCertificates, adminCertificatePDF)).Methods("GET")
+	admin.HandleFunc("/certificates/{serial}", requirePermission(permCertificates, adminRevokeCertificate)).Methods("DELETE")
+	admin.HandleFunc("/catalog.{format:pdf|html}", requirePermission(permArtworksRead, adminExportCatalog)).Methods("GET")
+	admin.HandleFunc("/artworks/{id}/translations", requirePermission(permArtworksRead, adminListTranslations)).Methods("GET")
+	admin.HandleFunc("/artworks/{id}/translations/{locale}", requirePermission(permArtworksWrite, adminPutTranslation)).Methods("PUT")
+	admin.HandleFunc("/artworks/{id}/translations/{locale}", requirePermission(permContentDelete, adminDeleteTranslation)).Methods("DELETE")
+	admin.HandleFunc("/translations/missing", requirePermission(permArtworksRead, adminListMissingTranslations)).Methods("GET")
+	admin.HandleFunc("/artworks/{id}/bitacora", requirePermission(permArtworksRead, adminListBitacora)).Methods("GET")
+	admin.HandleFunc("/artworks/{id}/bitacora", requirePermission(permArtworksWrite, adminCreateBitacoraEntry)).Methods("POST")
+	admin.HandleFunc("/artworks/{id}/bitacora/{entryId:[0-9]+}", requirePermission(permArtworksWrite, adminUpdateBitacoraEntry)).Methods("PUT")
+	admin.HandleFunc("/artworks/{id}/bitacora/{entryId:[0-9]+}", requirePermission(permContentDelete, adminDeleteBitacoraEntry)).Methods("DELETE")
+	admin.HandleFunc("/tags", requirePermission(permArtworksRead, adminListTags)).Methods("GET")
+	admin.HandleFunc("/tags", requirePermission(permArtworksWrite, adminCreateTag)).Methods("POST")
+	admin.HandleFunc("/tags/{tagId:[0-9]+}", requirePermission(permArtworksWrite, adminUpdateTag)).Methods("PUT")
+	admin.HandleFunc("/tags/{tagId:[0-9]+}", requirePermission(permContentDelete, adminDeleteTag)).Methods("DELETE")
+	admin.HandleFunc("/artworks/{id}/tags", requirePermission(permArtworksWrite, adminSetArtworkTags)).Methods("PUT")
+	admin.HandleFunc("/exhibitions", requirePermission(permArtworksRead, adminListExhibitions)).Methods("GET")
+	admin.HandleFunc("/exhibitions", requirePermission(permArtworksWrite, adminCreateExhibition)).Methods("POST")
+	admin.HandleFunc("/exhibitions/{slug}", requirePermission(permArtworksRead, adminGetExhibition)).Methods("GET")
+	admin.HandleFunc("/exhibitions/{slug}", requirePermission(permArtworksWrite, adminUpdateExhibition)).Methods("PUT")
+	admin.HandleFunc("/exhibitions/{slug}", requirePermission(permContentDelete, adminDeleteExhibition)).Methods("DELETE")
+	admin.HandleFunc("/exhibitions/{slug}/artworks/{id}", requirePermission(permArtworksWrite, adminAddExhibitionArtwork)).Methods("PUT")
+	admin.HandleFunc("/exhibitions/{slug}/artworks/{id}", requirePermission(permArtworksWrite, adminRemoveExhibitionArtwork)).Methods("DELETE")
+	admin.HandleFunc("/series", requirePermission(permArtworksRead, adminListSeries)).Methods("GET")
+	admin.HandleFunc("/series", requirePermission(permArtworksWrite, adminCreateSeries)).Methods("POST")
+	admin.HandleFunc("/series/{slug}", requirePermission(permArtworksRead, adminGetSeries)).Methods("GET")
+	admin.HandleFunc("/series/{slug}", requirePermission(permArtworksWrite, adminUpdateSeries)).Methods("PUT")
+	admin.HandleFunc("/series/{slug}", requirePermission(permContentDelete, adminDeleteSeries)).Methods("DELETE")
+	admin.HandleFunc("/series/{slug}/artworks/{id}", requirePermission(permArtworksWrite, adminAddSeriesArtwork)).Methods("PUT")
+	admin.HandleFunc("/series/{slug}/artworks/{id}", requirePermission(permArtworksWrite, adminRemoveSeriesArtwork)).Methods("DELETE")
+	admin.HandleFunc("/api-keys", requirePermission(permAPIKeys, adminListAPIKeys)).Methods("GET")
+	admin.HandleFunc("/api-keys", requirePermission(permAPIKeys, adminCreateAPIKey)).Methods("POST")
+	admin.HandleFunc("/api-keys/{keyId}", requirePermission(permAPIKeys, adminRevokeAPIKey)).Methods("DELETE")
+	admin.HandleFunc("/audit", requirePermission(permAuditRead, adminListAudit)).Methods("GET")
+	admin.HandleFunc("/me", adminGetMe).Methods("GET")
+	admin.HandleFunc("/me/password", adminChangeOwnPassword).Methods("PUT")
+	admin.HandleFunc("/users", requirePermission(permUsersManage, adminListUsers)).Methods("GET")
+	admin.HandleFunc("/users", requirePermission(permUsersManage, adminCreateUser)).Methods("POST")
+	admin.HandleFunc("/users/{userId:[0-9]+}", requirePermission(permUsersManage, adminGetUser)).Methods("GET")
+	admin.HandleFunc("/users/{userId:[0-9]+}", requirePermission(permUsersManage, adminUpdateUser)).Methods("PUT")
+	admin.HandleFunc("/users/{userId:[0-9]+}/sessions", requirePermission(permUsersManage, adminListUserSessions)).Methods("GET")
+	admin.HandleFunc("/users/{userId:[0-9]+}/sessions", requirePermission(permUsersManage, adminRevokeUserSessions)).Methods("DELETE")
+	admin.HandleFunc("/users/{userId:[0-9]+}/sessions/{sessionId}", requirePermission(permUsersManage, adminRevokeUserSession)).Methods("DELETE")
+}
+
 func healthCheck(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
 func getArtworks(w http.ResponseWriter, r *http.Request) {
-	artworks, err := scanArtworks(r.Context())
+	filter, invalid := parseSalesFilter(r.URL.Query())
+	if invalid != "" {
+		respondWithError(w, http.StatusBadRequest, invalid)
+		return
+	}
+	tags := r.URL.Query()["tag"]
+	format, ok := parseTextFormat(r.URL.Query())
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, invalidTextFormatMessage())
+		return
+	}
+	locale := requestLocale(r)
+	setLocaleHeaders(w, locale)
+
+	fingerprint, lastModified, err := catalogFingerprint(r.Context())
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	etag := responseETag(fingerprint, r)
+	if setValidators(w, r, etag, lastModified) {
+		return
+	}
+
+	body, ok := cachedCatalogBody(fingerprint, etag)
+	if !ok {
+		artworks, err := scanArtworks(r.Context())
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
 
-	response := ArtworkListResponse{
-		Artworks: artworks,
-		Total:    len(artworks),
+		artworks = publicArtworks(artworks)
+		filtered := artworks[:0]
+		for _, a := range artworks {
+			if a = a.publicView(); filter.match(a) && a.hasTags(tags) {
+				filtered = append(filtered, a)
+			}
+		}
+		artworks = filtered
+		localizeArtworks(r.Context(), locale, artworks)
+		renderArtworkTexts(artworks, format)
+		response := ArtworkListResponse{
+			Artworks: artworks,
+			Total:    len(artworks),
+		}
+		body, _ = json.Marshal(response)
+		body = append(body, '\n')
+		storeCatalogBody(fingerprint, etag, body)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	w.Write(body)
 }
 
 func getArtwork(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
-
-	artwork, err := getArtworkByID(r.Context(), id)
-	if err != nil {
+	if !isSafeArtworkID(id) {
 		respondWithError(w, http.StatusNotFound, "Artwork not found")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(artwork)
-}
-
-func serveImage(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-	filename := vars["filename"]
+	format, ok := parseTextFormat(r.URL.Query())
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, invalidTextFormatMessage())
+		return
+	}
+	locale := requestLocale(r)
+	setLocaleHeaders(w, locale)
 
-	if s3Store != nil {
-		u, err := s3Store.objectURL(r.Context(), id, filename)
+	// Visibility is settled before any validator is computed: an unpublished
+	// artwork answers exactly like a missing one (no ETag, no 304) unless the
+	// request has a valid preview token, and previews are never revalidated.
+	public := isArtworkPublic(r.Context(), id)
+	if !public && !hasValidPreview(r, id) {
+		respondWithError(w, http.StatusNotFound, "Artwork not found")
+		return
+	}
+	if public {
+		fingerprint, lastModified, err := artworkFingerprint(r.Context(), id)
 		if err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid path")
+			respondWithError(w, http.StatusNotFound, "Artwork not found")
+			return
+		}
+		if setValidators(w, r, responseETag(fingerprint, r), lastModified) {
 			return
 		}
-		http.Redirect(w, r, u, http.StatusTemporaryRedirect)
-		return
 	}
 
-	imagePath := filepath.Join(artworksDir, id, filename)
-
-	// Security: ensure the path is within artworks directory
-	if !strings.HasPrefix(imagePath, artworksDir) {
-		respondWithError(w, http.StatusForbidden, "Invalid path")
+	artwork, err := getArtworkByID(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Artwork not found")
 		return
 	}
-
-	// Check if file exists
-	if _, err := os.Stat(imagePath); os.IsNotExist(err) {
-		respondWithError(w, http.StatusNotFound, "Image not found")
-		return
+	if !artwork.isPublic() {
+		// Unpublished since the check above.
+		w.Header().Del("ETag")
+		w.Header().Del("Last-Modified")
+		if !hasValidPreview(r, id) {
+			respondWithError(w, http.StatusNotFound, "Artwork not found")
+			return
+		}
+		w.Header().Set("Cache-Control", previewCacheControl)
 	}
 
-	// Set appropriate content type
-	ext := strings.ToLower(filepath.Ext(filename))
-	switch ext {
-	case ".jpg", ".jpeg":
-		w.Header().Set("Content-Type", "image/jpeg")
-	case ".png":
-		w.Header().Set("Content-Type", "image/png")
-	case ".gif":
-		w.Header().Set("Content-Type", "image/gif")
-	default:
-		w.Header().Set("Content-Type", "application/octet-stream")
-	}
+	localized := []Artwork{artwork.publicView()}
+	localizeArtworks(r.Context(), locale, localized)
+	renderArtworkTexts(localized, format)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(localized[0])
+}
 
-	http.ServeFile(w, r, imagePath)
+func serveImage(w http.ResponseWriter, r *http.Request) {
+	serveArtworkMedia(w, r, "Image not found")
 }
 
 func serveVideo(w http.ResponseWriter, r *http.Request) {
+	serveArtworkMedia(w, r, "Video not found")
+}
+
+func serveArtworkMedia(w http.ResponseWriter, r *http.Request, notFoundMsg string) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 	filename := vars["filename"]
 
+	// Media of unpublished artworks is only served to preview link holders,
+	// and must not end up in shared caches.
+	cacheControl := media.CacheControl(filename)
+	if !isArtworkPublic(r.Context(), id) {
+		if !hasValidPreview(r, id) {
+			respondWithError(w, http.StatusNotFound, notFoundMsg)
+			return
+		}
+		cacheControl = previewCacheControl
+	}
+
 	if s3Store != nil {
+		if s3Store.proxyMedia {
+			s3Store.serveObject(w, r, id, filename, cacheControl)
+			return
+		}
 		u, err := s3Store.objectURL(r.Context(), id, filename)
 		if err != nil {
 			respondWithError(w, http.StatusBadRequest, "Invalid path")
@@ -255,22 +478,45 @@ func serveVideo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	videoPath := filepath.Join(artworksDir, id, filename)
+	serveDiskMedia(w, r, id, filename, notFoundMsg, cacheControl)
+}
+
+// serveDiskMedia serves an artwork file from ARTWORKS_DIR. http.ServeContent
+// takes care of Range (video seeking), If-Range, If-None-Match and HEAD.
+func serveDiskMedia(w http.ResponseWriter, r *http.Request, id, filename, notFoundMsg, cacheControl string) {
+	filePath := filepath.Join(artworksDir, id, filename)
 
 	// Security: ensure the path is within artworks directory
-	if !strings.HasPrefix(videoPath, artworksDir) {
+	absArtworks, _ := filepath.Abs(artworksDir)
+	absFile, _ := filepath.Abs(filePath)
+	if !strings.HasPrefix(absFile, absArtworks+string(filepath.Separator)) {
 		respondWithError(w, http.StatusForbidden, "Invalid path")
 		return
 	}
 
-	// Check if file exists
-	if _, err := os.Stat(videoPath); os.IsNotExist(err) {
-		respondWithError(w, http.StatusNotFound, "Video not found")
+	f, err := os.Open(filePath)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, notFoundMsg)
 		return
 	}
+	defer f.Close()
 
-	w.Header().Set("Content-Type", "video/mp4")
-	http.ServeFile(w, r, videoPath)
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		respondWithError(w, http.StatusNotFound, notFoundMsg)
+		return
+	}
+
+	etag, err := media.FileETag(filePath, info)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to read file")
+		return
+	}
+
+	w.Header().Set("Content-Type", media.ContentType(filename))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl)
+	http.ServeContent(w, r, filename, info.ModTime(), f)
 }
 
 func scanArtworks(ctx context.Context) ([]Artwork, error) {
@@ -284,6 +530,7 @@ func scanArtworks(ctx context.Context) ([]Artwork, error) {
 		return nil, fmt.Errorf("failed to read artworks directory: %v", err)
 	}
 
+	overlay := loadArtworkOverlay(ctx, "")
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
@@ -292,7 +539,7 @@ func scanArtworks(ctx context.Context) ([]Artwork, error) {
 		artworkID := entry.Name()
 		artworkPath := filepath.Join(artworksDir, artworkID)
 
-		artwork, err := scanArtworkDirectory(artworkID, artworkPath)
+		artwork, err := scanArtworkDirectory(artworkID, artworkPath, overlay)
 		if err != nil {
 			log.Printf("Error scanning artwork %s: %v", artworkID, err)
 			continue
@@ -306,7 +553,7 @@ func scanArtworks(ctx context.Context) ([]Artwork, error) {
 	return artworks, nil
 }
 
-func scanArtworkDirectory(id, path string) (Artwork, error) {
+func scanArtworkDirectory(id, path string, overlay *artworkOverlay) (Artwork, error) {
 	artwork := Artwork{
 		ID:     id,
 		Title:  formatTitle(id),
@@ -347,16 +594,7 @@ func scanArtworkDirectory(id, path string) (Artwork, error) {
 			if err := json.Unmarshal(content, &m); err != nil {
 				continue
 			}
-			if artwork.PaintedLocation == "" {
-				artwork.PaintedLocation = strings.TrimSpace(m.PaintedLocation)
-			}
-			if artwork.StartDate == "" {
-				artwork.StartDate = strings.TrimSpace(m.StartDate)
-			}
-			if artwork.EndDate == "" {
-				artwork.EndDate = strings.TrimSpace(m.EndDate)
-			}
-			artwork.InProgress = m.InProgress
+			m.applyTo(&artwork)
 		case ".txt", ".md":
 			// Read text content:
 			// - Prefer explicit names: bitacora.* and detalle/detail.*
@@ -384,12 +622,77 @@ func scanArtworkDirectory(id, path string) (Artwork, error) {
 	sort.Strings(artwork.Images)
 	sort.Strings(artwork.Videos)
 
-	// If Postgres is enabled, overlay editable fields from DB.
-	if pgPool != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-		row, err := db.GetArtwork(ctx, pgPool, id)
-		if err == nil && row != nil {
+	overlayArtworkRow(&artwork, overlay)
+
+	return artwork, nil
+}
+
+// artworkOverlay is what overlayArtworkRow reads from Postgres, by artwork
+// id. A catalog scan reads it for every artwork at once, one query per kind,
+// instead of querying per artwork.
+type artworkOverlay struct {
+	rows        map[string]db.ArtworkRow
+	tags        map[string][]db.TagRow
+	exhibitions map[string][]db.ExhibitionRow
+	entries     map[string][]db.BitacoraEntryRow
+}
+
+// loadArtworkOverlay reads the overlay of artwork id, or of every artwork
+// when id is empty. It returns nil without Postgres; what fails to load is
+// left out, as if the artwork had nothing stored.
+func loadArtworkOverlay(ctx context.Context, id string) *artworkOverlay {
+	if pgPool == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	o := &artworkOverlay{
+		rows:        map[string]db.ArtworkRow{},
+		tags:        map[string][]db.TagRow{},
+		exhibitions: map[string][]db.ExhibitionRow{},
+		entries:     map[string][]db.BitacoraEntryRow{},
+	}
+	if id != "" {
+		if row, err := db.GetArtwork(ctx, pgPool, id); err == nil && row != nil {
+			o.rows[id] = *row
+		}
+		if rows, err := db.ArtworkTags(ctx, pgPool, id); err == nil {
+			o.tags[id] = rows
+		}
+		if rows, err := db.ArtworkExhibitions(ctx, pgPool, id); err == nil {
+			o.exhibitions[id] = rows
+		}
+	} else {
+		if rows, err := db.ListArtworks(ctx, pgPool); err == nil {
+			for _, row := range rows {
+				o.rows[row.ID] = row
+			}
+		}
+		if tags, err := db.TagsByArtwork(ctx, pgPool); err == nil {
+			o.tags = tags
+		}
+		// Most recent first, like ArtworkExhibitions.
+		if rows, err := db.ListExhibitions(ctx, pgPool); err == nil {
+			for _, row := range rows {
+				for _, artworkID := range row.ArtworkIDs {
+					o.exhibitions[artworkID] = append(o.exhibitions[artworkID], row)
+				}
+			}
+		}
+	}
+	if rows, err := db.ListBitacoraEntries(ctx, pgPool, id); err == nil {
+		for _, row := range rows {
+			o.entries[row.ArtworkID] = append(o.entries[row.ArtworkID], row)
+		}
+	}
+	return o
+}
+
+// overlayArtworkRow applies the editable fields stored in Postgres (o, nil
+// without it) on top of what was scanned from storage, and fills defaults.
+func overlayArtworkRow(artwork *Artwork, o *artworkOverlay) {
+	if o != nil {
+		if row, ok := o.rows[artwork.ID]; ok {
 			if row.Title != "" {
 				artwork.Title = row.Title
 			}
@@ -410,7 +713,17 @@ func scanArtworkDirectory(id, path string) (Artwork, error) {
 			if row.PrimaryImage != "" {
 				artwork.PrimaryImage = row.PrimaryImage
 			}
+			artwork.Status = row.Status
+			artwork.PublishAt = row.PublishAt
+			overlaySalesRow(artwork, &row)
 		}
+		attachArtworkTags(artwork, o.tags[artwork.ID])
+		attachArtworkExhibitions(artwork, o.exhibitions[artwork.ID])
+	}
+
+	// Artworks without a DB row predate the publishing workflow: they are public.
+	if artwork.Status == "" {
+		artwork.Status = statusPublished
 	}
 
 	// Default primary image to first image if not set
@@ -418,23 +731,11 @@ func scanArtworkDirectory(id, path string) (Artwork, error) {
 		artwork.PrimaryImage = artwork.Images[0]
 	}
 
-	return artwork, nil
-}
-
-func adminAuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if adminToken == "" {
-			respondWithError(w, http.StatusInternalServerError, "ADMIN_TOKEN is not configured")
-			return
-		}
-		auth := r.Header.Get("Authorization")
-		const prefix = "Bearer "
-		if !strings.HasPrefix(auth, prefix) || strings.TrimSpace(strings.TrimPrefix(auth, prefix)) != adminToken {
-			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
-			return
-		}
-		next.ServeHTTP(w, r)
-	})
+	var entries []db.BitacoraEntryRow
+	if o != nil {
+		entries = o.entries[artwork.ID]
+	}
+	attachArtworkBitacora(artwork, entries)
 }
 
 func isSafeArtworkID(id string) bool {
@@ -447,6 +748,52 @@ func isSafeArtworkID(id string) bool {
 	return true
 }
 
+// artworkLocks serializes admin writes per artwork, so the If-Match check and
+// the write that follows cannot interleave with another request.
+var artworkLocks sync.Map
+
+func lockArtwork(id string) func() {
+	v, _ := artworkLocks.LoadOrStore(id, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// artworkETag identifies the state of an artwork as returned by the admin API.
+// It changes whenever any field or media file changes.
+func artworkETag(a Artwork) string {
+	b, _ := json.Marshal(a)
+	h := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(h[:16]) + `"`
+}
+
+// checkIfMatch enforces optimistic concurrency on artwork writes: it answers
+// 428 when If-Match is missing and 412 with the current state on mismatch.
+// Callers must hold the artwork lock.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, id string) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		respondWithError(w, http.StatusPreconditionRequired, "If-Match header is required")
+		return false
+	}
+	current, err := getArtworkByID(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Artwork not found")
+		return false
+	}
+	if !ifMatchSatisfied(ifMatch, artworkETag(current)) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", artworkETag(current))
+		w.WriteHeader(http.StatusPreconditionFailed)
+		json.NewEncoder(w).Encode(ConflictResponse{
+			Error:   "Artwork was modified by someone else",
+			Current: current,
+		})
+		return false
+	}
+	return true
+}
+
 func adminListArtworks(w http.ResponseWriter, r *http.Request) {
 	artworks, err := scanArtworks(r.Context())
 	if err != nil {
@@ -494,11 +841,11 @@ func adminCreateArtwork(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	title := strings.TrimSpace(payload.Title)
-	if title == "" {
-		respondWithError(w, http.StatusBadRequest, "Title is required")
+	if err := payload.validate(); err != nil {
+		respondWithAPIError(w, err)
 		return
 	}
+	title := strings.TrimSpace(payload.Title)
 
 	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
 	defer cancel()
@@ -516,6 +863,7 @@ func adminCreateArtwork(w http.ResponseWriter, r *http.Request) {
 
 	// Generate unique ID
 	id := generateArtworkID()
+	auditArtwork(r.Context(), id)
 
 	// Create folder in S3 or disk
 	if s3Store != nil {
@@ -534,25 +882,42 @@ func adminCreateArtwork(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Save to database
+	// New artworks start as drafts until published from the backoffice.
 	err = db.UpsertArtwork(ctx, pgPool, db.ArtworkRow{
-		ID:    id,
-		Title: title,
+		ID:     id,
+		Title:  title,
+		Status: statusDraft,
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to save artwork")
 		return
 	}
+	created := revisionSnapshot{
+		adminArtworkUpdate: adminArtworkUpdate{Title: title},
+		Status:             statusDraft,
+		Images:             []string{},
+		Videos:             []string{},
+		Tags:               []string{},
+		Series:             []string{},
+		Exhibitions:        []string{},
+		BitacoraEntries:    []BitacoraEntry{},
+	}
+	if err := recordRevision(ctx, pgPool, id, actorFromContext(r.Context()), revisionActionCreate, revisionSnapshot{}, created); err != nil {
+		log.Printf("Recording revision for %s failed: %v", id, err)
+	}
+	auditSnapshots(r.Context(), id, nil, created)
+
+	bumpCatalogVersion()
 
 	artwork := Artwork{
 		ID:     id,
 		Title:  title,
 		Images: []string{},
 		Videos: []string{},
+		Status: statusDraft,
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(artwork)
+	respondWithArtwork(w, http.StatusCreated, artwork)
 }
 
 func adminCheckTitle(w http.ResponseWriter, r *http.Request) {
@@ -594,8 +959,7 @@ func adminGetArtwork(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusNotFound, "Artwork not found")
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(artwork)
+	respondWithArtwork(w, http.StatusOK, artwork)
 }
 
 func adminUpsertArtwork(w http.ResponseWriter, r *http.Request) {
@@ -606,17 +970,16 @@ func adminUpsertArtwork(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	artworkPath := filepath.Join(artworksDir, id)
-	if s3Store != nil {
-		if err := s3Store.ensureArtworkPrefixExists(r.Context(), id); err != nil {
-			respondWithError(w, http.StatusNotFound, "Artwork not found")
-			return
-		}
-	} else {
-		if _, err := os.Stat(artworkPath); os.IsNotExist(err) {
-			respondWithError(w, http.StatusNotFound, "Artwork not found")
-			return
-		}
+	unlock := lockArtwork(id)
+	defer unlock()
+
+	if err := ensureArtworkExists(r.Context(), id); err != nil {
+		respondWithError(w, http.StatusNotFound, "Artwork not found")
+		return
+	}
+
+	if !checkIfMatch(w, r, id) {
+		return
 	}
 
 	var payload adminArtworkUpdate
@@ -625,100 +988,81 @@ func adminUpsertArtwork(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	meta := artworkMeta{
-		PaintedLocation: strings.TrimSpace(payload.PaintedLocation),
-		StartDate:       strings.TrimSpace(payload.StartDate),
-		EndDate:         strings.TrimSpace(payload.EndDate),
-		InProgress:      payload.InProgress,
+	if err := writeArtwork(r.Context(), id, payload, revisionActionUpdate); err != nil {
+		respondWithAPIError(w, err)
+		return
 	}
-	metaBytes, _ := json.MarshalIndent(meta, "", "  ")
-	if s3Store != nil {
-		key := id + "/meta.json"
-		if err := s3Store.putObject(r.Context(), key, strings.NewReader(string(append(metaBytes, '\n'))), "application/json"); err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to write meta.json")
-			return
-		}
-	} else {
-		if err := os.WriteFile(filepath.Join(artworkPath, "meta.json"), append(metaBytes, '\n'), 0644); err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to write meta.json")
-			return
-		}
+	bumpCatalogVersion()
+
+	updated, err := getArtworkByID(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to re-read artwork")
+		return
 	}
+	respondWithArtwork(w, http.StatusOK, updated)
+}
 
-	if s3Store != nil {
-		key := id + "/detalle.txt"
-		if strings.TrimSpace(payload.Detalle) == "" {
-			_ = s3Store.deleteObject(r.Context(), key)
-		} else {
-			if err := s3Store.putObject(r.Context(), key, strings.NewReader(payload.Detalle), "text/plain; charset=utf-8"); err != nil {
-				respondWithError(w, http.StatusInternalServerError, "Failed to write detalle.txt")
-				return
-			}
+// writeArtwork persists a full set of editable fields to meta.json, the text
+// files and Postgres, all or nothing: the payload is validated and the title
+// checked before anything is written, the DB row is written inside a
+// transaction that is only committed once every file is in place, and the
+// files are restored if a later step fails. Callers must hold the artwork lock.
+func writeArtwork(ctx context.Context, id string, payload adminArtworkUpdate, action string) error {
+	payload.normalizeSales()
+	if err := payload.validate(); err != nil {
+		return err
+	}
+	if !hasPermission(ctx, permSalesWrite) {
+		current, err := currentArtworkFields(ctx, id)
+		if err != nil {
+			return &apiError{Code: http.StatusInternalServerError, Message: "Failed to read artwork"}
 		}
-	} else {
-		if strings.TrimSpace(payload.Detalle) == "" {
-			_ = os.Remove(filepath.Join(artworkPath, "detalle.txt"))
-		} else {
-			if err := os.WriteFile(filepath.Join(artworkPath, "detalle.txt"), []byte(payload.Detalle), 0644); err != nil {
-				respondWithError(w, http.StatusInternalServerError, "Failed to write detalle.txt")
-				return
-			}
+		current.normalizeSales()
+		if !current.sameSales(payload) {
+			return &permissionError{Permission: permSalesWrite}
 		}
 	}
 
-	if s3Store != nil {
-		key := id + "/bitacora.txt"
-		if strings.TrimSpace(payload.Bitacora) == "" {
-			_ = s3Store.deleteObject(r.Context(), key)
-		} else {
-			if err := s3Store.putObject(r.Context(), key, strings.NewReader(payload.Bitacora), "text/plain; charset=utf-8"); err != nil {
-				respondWithError(w, http.StatusInternalServerError, "Failed to write bitacora.txt")
-				return
-			}
+	var tx pgx.Tx
+	var previous, next revisionSnapshot
+	if pgPool != nil {
+		current, err := getArtworkByID(ctx, id)
+		if err != nil {
+			return &apiError{Code: http.StatusInternalServerError, Message: "Failed to read artwork"}
 		}
-	} else {
-		if strings.TrimSpace(payload.Bitacora) == "" {
-			_ = os.Remove(filepath.Join(artworkPath, "bitacora.txt"))
-		} else {
-			if err := os.WriteFile(filepath.Join(artworkPath, "bitacora.txt"), []byte(payload.Bitacora), 0644); err != nil {
-				respondWithError(w, http.StatusInternalServerError, "Failed to write bitacora.txt")
-				return
-			}
+		previous, err = snapshotOf(ctx, current)
+		if err != nil {
+			return &apiError{Code: http.StatusInternalServerError, Message: "Failed to read artwork"}
 		}
-	}
+		next = previous
+		next.adminArtworkUpdate = payload
 
-	// Persist to Postgres (required for title/primaryImage)
-	if pgPool != nil {
-		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		ctxDB, cancel := context.WithTimeout(ctx, 10*time.Second)
 		defer cancel()
 
+		tx, err = pgPool.Begin(ctxDB)
+		if err != nil {
+			return &apiError{Code: http.StatusInternalServerError, Message: "Failed to save artwork"}
+		}
+		// No-op once committed.
+		defer tx.Rollback(context.Background())
+
 		// Validate title uniqueness if provided
 		title := strings.TrimSpace(payload.Title)
 		if title != "" {
-			unique, err := db.IsTitleUnique(ctx, pgPool, title, id)
+			unique, err := db.IsTitleUnique(ctxDB, tx, title, id)
 			if err != nil {
-				respondWithError(w, http.StatusInternalServerError, "Failed to check title uniqueness")
-				return
+				return &apiError{Code: http.StatusInternalServerError, Message: "Failed to check title uniqueness"}
 			}
 			if !unique {
-				respondWithError(w, http.StatusConflict, "Title already exists")
-				return
+				return &apiError{Code: http.StatusConflict, Message: "Title already exists"}
 			}
 		}
 
-		var sd *time.Time
-		var ed *time.Time
-		if strings.TrimSpace(payload.StartDate) != "" {
-			if t, err := time.Parse("2006-01-02", strings.TrimSpace(payload.StartDate)); err == nil {
-				sd = &t
-			}
-		}
-		if strings.TrimSpace(payload.EndDate) != "" {
-			if t, err := time.Parse("2006-01-02", strings.TrimSpace(payload.EndDate)); err == nil {
-				ed = &t
-			}
-		}
-		_ = db.UpsertArtwork(ctx, pgPool, db.ArtworkRow{
+		// Dates were validated above.
+		sd, _ := parseDate(payload.StartDate)
+		ed, _ := parseDate(payload.EndDate)
+		row := db.ArtworkRow{
 			ID:              id,
 			Title:           title,
 			PaintedLocation: strings.TrimSpace(payload.PaintedLocation),
@@ -726,23 +1070,77 @@ func adminUpsertArtwork(w http.ResponseWriter, r *http.Request) {
 			EndDate:         ed,
 			InProgress:      payload.InProgress,
 			Detalle:         payload.Detalle,
-			Bitacora:        payload.Bitacora,
 			PrimaryImage:    strings.TrimSpace(payload.PrimaryImage),
-		})
+		}
+		salesRowFields(&row, payload)
+		err = db.UpsertArtwork(ctxDB, tx, row)
+		if db.IsUniqueViolation(err) {
+			return &apiError{Code: http.StatusConflict, Message: "Title already exists"}
+		}
+		if err != nil {
+			return &apiError{Code: http.StatusInternalServerError, Message: "Failed to save artwork"}
+		}
+
+		if err := recordRevision(ctxDB, tx, id, actorFromContext(ctx), action, previous, next); err != nil {
+			return &apiError{Code: http.StatusInternalServerError, Message: "Failed to record revision"}
+		}
 	}
 
-	updated, err := getArtworkByID(r.Context(), id)
+	undo, err := applyFileChanges(ctx, id, artworkFileChanges(payload))
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to re-read artwork")
-		return
+		return err
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(updated)
+
+	if tx != nil {
+		ctxDB, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tx.Commit(ctxDB); err != nil {
+			undo()
+			return &apiError{Code: http.StatusInternalServerError, Message: "Failed to save artwork"}
+		}
+		auditSnapshots(ctx, id, previous, next)
+	}
+	return nil
+}
+
+// artworkFileChanges maps the editable fields to meta.json and detalle.txt; an
+// empty detalle removes its file. bitacora.txt is never written: it is only
+// kept as the source of the imported first bitácora entry.
+func artworkFileChanges(payload adminArtworkUpdate) []fileChange {
+	meta := artworkMeta{
+		PaintedLocation: strings.TrimSpace(payload.PaintedLocation),
+		StartDate:       strings.TrimSpace(payload.StartDate),
+		EndDate:         strings.TrimSpace(payload.EndDate),
+		InProgress:      payload.InProgress,
+		Technique:       payload.Technique,
+		Dimensions:      payload.Dimensions,
+		Price:           payload.Price,
+		Availability:    payload.Availability,
+	}
+	metaBytes, _ := json.MarshalIndent(meta, "", "  ")
+
+	detalle := fileChange{name: "detalle.txt"}
+	if strings.TrimSpace(payload.Detalle) != "" {
+		detalle.content = []byte(payload.Detalle)
+	}
+	return []fileChange{{name: "meta.json", content: append(metaBytes, '\n')}, detalle}
+}
+
+// ensureArtworkExists reports an error when the artwork folder (or bucket
+// prefix) does not exist.
+func ensureArtworkExists(ctx context.Context, id string) error {
+	if s3Store != nil {
+		return s3Store.ensureArtworkPrefixExists(ctx, id)
+	}
+	if _, err := os.Stat(filepath.Join(artworksDir, id)); err != nil {
+		return fmt.Errorf("artwork not found")
+	}
+	return nil
 }
 
 func getArtworkByID(ctx context.Context, id string) (Artwork, error) {
 	if s3Store != nil {
-		return s3Store.scanArtwork(ctx, id)
+		return s3Store.scanArtwork(ctx, id, loadArtworkOverlay(ctx, id))
 	}
 	artworkPath := filepath.Join(artworksDir, id)
 
@@ -750,7 +1148,7 @@ func getArtworkByID(ctx context.Context, id string) (Artwork, error) {
 		return Artwork{}, fmt.Errorf("artwork not found")
 	}
 
-	return scanArtworkDirectory(id, artworkPath)
+	return scanArtworkDirectory(id, artworkPath, loadArtworkOverlay(ctx, id))
 }
 
 func formatTitle(id string) string {
@@ -769,6 +1167,35 @@ func respondWithError(w http.ResponseWriter, code int, message string) {
 	json.NewEncoder(w).Encode(ErrorResponse{Error: message})
 }
 
+// respondWithAPIError answers 422 for validation errors, the status carried by
+// an *apiError, or 500.
+func respondWithAPIError(w http.ResponseWriter, err error) {
+	var ve validationErrors
+	if errors.As(err, &ve) {
+		respondWithValidationErrors(w, ve)
+		return
+	}
+	var pe *permissionError
+	if errors.As(err, &pe) {
+		respondWithPermissionError(w, pe.Permission)
+		return
+	}
+	var ae *apiError
+	if errors.As(err, &ae) {
+		respondWithError(w, ae.Code, ae.Message)
+		return
+	}
+	respondWithError(w, http.StatusInternalServerError, err.Error())
+}
+
+// respondWithArtwork writes an admin artwork response along with its ETag.
+func respondWithArtwork(w http.ResponseWriter, code int, artwork Artwork) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", artworkETag(artwork))
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(artwork)
+}
+
 func adminUploadImage(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -777,6 +1204,9 @@ func adminUploadImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	unlock := lockArtwork(id)
+	defer unlock()
+
 	artworkPath := filepath.Join(artworksDir, id)
 	if s3Store != nil {
 		if err := s3Store.ensureArtworkPrefixExists(r.Context(), id); err != nil {
@@ -816,7 +1246,8 @@ func adminUploadImage(w http.ResponseWriter, r *http.Request) {
 	if ext == "" {
 		ext = getExtensionFromMime(contentType)
 	}
-	safeFilename := fmt.Sprintf("%d_%s%s", time.Now().UnixNano(), sanitizeFilename(header.Filename), ext)
+	safeFilename := fmt.Sprintf("%d_%s%s", time.Now().UnixNano(), media.SafeName(header.Filename), ext)
+	before := snapshotArtwork(r.Context(), id)
 
 	if s3Store != nil {
 		key := id + "/" + safeFilename
@@ -840,6 +1271,8 @@ func adminUploadImage(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	bumpCatalogVersion()
+	recordArtworkChange(r.Context(), id, revisionActionUploadImage, before)
 
 	// Return updated artwork
 	updated, err := getArtworkByID(r.Context(), id)
@@ -847,8 +1280,7 @@ func adminUploadImage(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Failed to re-read artwork")
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(updated)
+	respondWithArtwork(w, http.StatusOK, updated)
 }
 
 func adminDeleteImage(w http.ResponseWriter, r *http.Request) {
@@ -861,6 +1293,9 @@ func adminDeleteImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	unlock := lockArtwork(id)
+	defer unlock()
+
 	// Validate filename (no path traversal)
 	if strings.Contains(filename, "/") || strings.Contains(filename, "\\") || strings.Contains(filename, "..") {
 		respondWithError(w, http.StatusBadRequest, "Invalid filename")
@@ -890,6 +1325,7 @@ func adminDeleteImage(w http.ResponseWriter, r *http.Request) {
 	deleteFromDisk := r.URL.Query().Get("deleteFile") == "true"
 
 	if deleteFromDisk {
+		before := snapshotArtwork(r.Context(), id)
 		if s3Store != nil {
 			key := id + "/" + filename
 			if err := s3Store.deleteObject(r.Context(), key); err != nil {
@@ -902,6 +1338,8 @@ func adminDeleteImage(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 		}
+		bumpCatalogVersion()
+		recordArtworkChange(r.Context(), id, revisionActionDeleteImage, before)
 	}
 
 	// Return updated artwork
@@ -910,8 +1348,7 @@ func adminDeleteImage(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Failed to re-read artwork")
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(updated)
+	respondWithArtwork(w, http.StatusOK, updated)
 }
 
 func isValidImageType(contentType string) bool {
@@ -936,27 +1373,3 @@ func getExtensionFromMime(contentType string) string {
 		return ".jpg"
 	}
 }
-
-func sanitizeFilename(filename string) string {
-	// Remove extension and path
-	base := filepath.Base(filename)
-	ext := filepath.Ext(base)
-	name := strings.TrimSuffix(base, ext)
-
-	// Keep only alphanumeric and hyphens
-	var result strings.Builder
-	for _, r := range name {
-		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
-			result.WriteRune(r)
-		}
-	}
-
-	s := result.String()
-	if len(s) > 50 {
-		s = s[:50]
-	}
-	if s == "" {
-		s = "image"
-	}
-	return s
-}
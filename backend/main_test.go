@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// rangeTestContent is 100 bytes, "0123456789" ten times.
+var rangeTestContent = func() []byte {
+	b := make([]byte, 100)
+	for i := range b {
+		b[i] = byte('0' + i%10)
+	}
+	return b
+}()
+
+func TestServeDiskMediaRanges(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "obra"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "obra", "clip.mp4"), rangeTestContent, 0644); err != nil {
+		t.Fatal(err)
+	}
+	prev := artworksDir
+	artworksDir = dir
+	t.Cleanup(func() { artworksDir = prev })
+
+	serve := func(rangeHeader string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/artworks/obra/videos/clip.mp4", nil)
+		if rangeHeader != "" {
+			r.Header.Set("Range", rangeHeader)
+		}
+		w := httptest.NewRecorder()
+		serveDiskMedia(w, r, "obra", "clip.mp4", "Video not found", "public, max-age=60")
+		return w
+	}
+
+	t.Run("full", func(t *testing.T) {
+		w := serve("")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", w.Code)
+		}
+		if got := w.Header().Get("Accept-Ranges"); got != "bytes" {
+			t.Errorf("Accept-Ranges = %q, want bytes", got)
+		}
+		if got := w.Header().Get("Content-Type"); got != "video/mp4" {
+			t.Errorf("Content-Type = %q, want video/mp4", got)
+		}
+		if w.Body.Len() != len(rangeTestContent) {
+			t.Errorf("body length = %d, want %d", w.Body.Len(), len(rangeTestContent))
+		}
+	})
+
+	t.Run("range", func(t *testing.T) {
+		w := serve("bytes=10-19")
+		if w.Code != http.StatusPartialContent {
+			t.Fatalf("status = %d, want 206", w.Code)
+		}
+		if got := w.Header().Get("Content-Range"); got != "bytes 10-19/100" {
+			t.Errorf("Content-Range = %q, want bytes 10-19/100", got)
+		}
+		if got := w.Header().Get("Content-Length"); got != "10" {
+			t.Errorf("Content-Length = %q, want 10", got)
+		}
+		if got := w.Body.String(); got != string(rangeTestContent[10:20]) {
+			t.Errorf("body = %q, want %q", got, rangeTestContent[10:20])
+		}
+	})
+
+	t.Run("suffix range", func(t *testing.T) {
+		w := serve("bytes=-5")
+		if w.Code != http.StatusPartialContent {
+			t.Fatalf("status = %d, want 206", w.Code)
+		}
+		if got := w.Header().Get("Content-Range"); got != "bytes 95-99/100" {
+			t.Errorf("Content-Range = %q, want bytes 95-99/100", got)
+		}
+	})
+
+	t.Run("unsatisfiable", func(t *testing.T) {
+		w := serve("bytes=200-300")
+		if w.Code != http.StatusRequestedRangeNotSatisfiable {
+			t.Fatalf("status = %d, want 416", w.Code)
+		}
+		if got := w.Header().Get("Content-Range"); got != "bytes */"+strconv.Itoa(len(rangeTestContent)) {
+			t.Errorf("Content-Range = %q, want bytes */100", got)
+		}
+	})
+}
+
+func TestAdminUpsertArtworkIfMatch(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "obra"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "obra", "a.jpg"), []byte("jpeg"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	prev := artworksDir
+	artworksDir = dir
+	t.Cleanup(func() { artworksDir = prev })
+
+	currentETag := func() string {
+		a, err := getArtworkByID(context.Background(), "obra")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return artworkETag(a)
+	}
+
+	tests := []struct {
+		name    string
+		ifMatch func(etag string) string
+		want    int
+	}{
+		{"missing", func(string) string { return "" }, http.StatusPreconditionRequired},
+		{"stale", func(string) string { return `"0123456789abcdef"` }, http.StatusPreconditionFailed},
+		{"weak", func(etag string) string { return "W/" + etag }, http.StatusPreconditionFailed},
+		{"current", func(etag string) string { return etag }, http.StatusOK},
+		{"list with current", func(etag string) string { return `"stale", ` + etag }, http.StatusOK},
+		{"any", func(string) string { return "*" }, http.StatusOK},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			etag := currentETag()
+			body := `{"paintedLocation": "Lima ` + strconv.Itoa(i) + `"}`
+			r := httptest.NewRequest(http.MethodPut, "/api/v1/admin/artworks/obra", strings.NewReader(body))
+			r = mux.SetURLVars(r, map[string]string{"id": "obra"})
+			if v := tt.ifMatch(etag); v != "" {
+				r.Header.Set("If-Match", v)
+			}
+			w := httptest.NewRecorder()
+			adminUpsertArtwork(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+
+			switch w.Code {
+			case http.StatusPreconditionFailed:
+				// The current state comes back, so the editor can merge.
+				if got := w.Header().Get("ETag"); got != etag {
+					t.Errorf("ETag = %q, want %q", got, etag)
+				}
+				var conflict ConflictResponse
+				if err := json.NewDecoder(w.Body).Decode(&conflict); err != nil || conflict.Current.ID != "obra" {
+					t.Errorf("body = %+v (%v), want the current artwork", conflict, err)
+				}
+				if currentETag() != etag {
+					t.Error("artwork changed on 412")
+				}
+			case http.StatusOK:
+				if got := w.Header().Get("ETag"); got == etag || got != currentETag() {
+					t.Errorf("ETag = %q, want the new one %q", got, currentETag())
+				}
+			default:
+				if currentETag() != etag {
+					t.Error("artwork changed without a precondition")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// Formats of detalle and bitácora in public responses (?format=).
+const (
+	textFormatMarkdown = "markdown" // source only (default)
+	textFormatHTML     = "html"
+	textFormatText     = "text"
+)
+
+var textFormats = []string{textFormatMarkdown, textFormatHTML, textFormatText}
+
+// markdown renders detalle and bitácora. Single line breaks are kept, since
+// most texts were written as plain text.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.Strikethrough, extension.Linkify),
+	goldmark.WithRendererOptions(html.WithHardWraps()),
+)
+
+// htmlPolicy is the allowlist applied to rendered Markdown: text formatting,
+// lists, quotes and http(s)/mailto links that open in a new tab. Raw HTML in
+// the source is escaped by goldmark before it gets here.
+var htmlPolicy = func() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "strong", "em", "del", "ul", "ol", "li", "blockquote",
+		"h1", "h2", "h3", "h4", "h5", "h6", "code", "pre", "hr")
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}()
+
+// Rendered holds detalle in the format asked with ?format=; bitácora entries
+// carry their own.
+type Rendered struct {
+	Format  string `json:"format"`
+	Detalle string `json:"detalle,omitempty"`
+}
+
+// parseTextFormat reads ?format=; ok is false when it is unknown.
+func parseTextFormat(q url.Values) (format string, ok bool) {
+	format = strings.TrimSpace(q.Get("format"))
+	if format == "" {
+		return textFormatMarkdown, true
+	}
+	return format, slices.Contains(textFormats, format)
+}
+
+func invalidTextFormatMessage() string {
+	return "Invalid format: must be one of " + strings.Join(textFormats, ", ")
+}
+
+// renderMarkdownHTML converts Markdown to sanitized HTML.
+func renderMarkdownHTML(src string) string {
+	if strings.TrimSpace(src) == "" {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(src), &buf); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(htmlPolicy.Sanitize(buf.String()))
+}
+
+// renderMarkdownText converts Markdown to plain text: one paragraph per
+// block, list items prefixed with "- ".
+func renderMarkdownText(src string) string {
+	if strings.TrimSpace(src) == "" {
+		return ""
+	}
+	source := []byte(src)
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	var blocks []string
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n.Kind() {
+		case ast.KindParagraph, ast.KindTextBlock, ast.KindHeading:
+			block := inlineText(n, source)
+			if _, inList := n.Parent().(*ast.ListItem); inList && n.PreviousSibling() == nil {
+				block = "- " + block
+			}
+			blocks = append(blocks, block)
+			return ast.WalkSkipChildren, nil
+		case ast.KindCodeBlock, ast.KindFencedCodeBlock:
+			var b strings.Builder
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				seg := lines.At(i)
+				b.Write(seg.Value(source))
+			}
+			blocks = append(blocks, strings.TrimRight(b.String(), "\n"))
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.Join(blocks, "\n\n")
+}
+
+// inlineText concatenates the text of the inline children of a block.
+func inlineText(block ast.Node, source []byte) string {
+	var b strings.Builder
+	ast.Walk(block, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Text:
+			b.Write(n.Segment.Value(source))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				b.WriteByte('\n')
+			}
+		case *ast.String:
+			b.Write(n.Value)
+		case *ast.CodeSpan:
+			for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+				if t, ok := c.(*ast.Text); ok {
+					b.Write(t.Segment.Value(source))
+				}
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.AutoLink:
+			b.Write(n.URL(source))
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.TrimSpace(b.String())
+}
+
+func renderMarkdown(src, format string) string {
+	switch format {
+	case textFormatHTML:
+		return renderMarkdownHTML(src)
+	case textFormatText:
+		return renderMarkdownText(src)
+	}
+	return ""
+}
+
+// renderArtworkTexts adds the rendered detalle and bitácora entries next to
+// their Markdown source. Nothing is added for markdown.
+func renderArtworkTexts(artworks []Artwork, format string) {
+	if format == textFormatMarkdown {
+		return
+	}
+	for i := range artworks {
+		a := &artworks[i]
+		a.Rendered = &Rendered{
+			Format:  format,
+			Detalle: renderMarkdown(a.Detalle, format),
+		}
+		for j := range a.BitacoraEntries {
+			a.BitacoraEntries[j].Rendered = renderMarkdown(a.BitacoraEntries[j].Text, format)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdownHTML(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"empty", "  \n", ""},
+		{"formatting", "**hola** _mundo_", "<p><strong>hola</strong> <em>mundo</em></p>"},
+		{"hard wraps", "línea 1\nlínea 2", "<p>línea 1<br>\nlínea 2</p>"},
+		{"list", "- uno\n- dos", "<ul>\n<li>uno</li>\n<li>dos</li>\n</ul>"},
+		{"link", "[sitio](https://example.com)",
+			`<p><a href="https://example.com" rel="nofollow noopener" target="_blank">sitio</a></p>`},
+		{"code is escaped", "`<b>`", "<p><code>&lt;b&gt;</code></p>"},
+		{"script block", "<script>alert(1)</script>", ""},
+		{"inline script", "hola<script>alert(1)</script>", "<p>holaalert(1)</p>"},
+		{"event handler", "<img src=x onerror=alert(1)>", ""},
+		{"raw link with handler", `<a href="https://e.com" onclick="x()">e</a>`, "<p>e</p>"},
+		{"javascript link", "[x](javascript:alert(1))", "<p>x</p>"},
+		{"data link", "[x](data:text/html;base64,PHNjcmlwdD4=)", "<p>x</p>"},
+		{"iframe", `<iframe src="https://e.com"></iframe>`, ""},
+		{"image", "![i](https://e.com/a.png)", "<p></p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderMarkdownHTML(tt.src)
+			if got != tt.want {
+				t.Errorf("renderMarkdownHTML(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+			for _, bad := range []string{"<script", "javascript:", "onerror", "onclick", "<iframe", "<img", "data:"} {
+				if strings.Contains(strings.ToLower(got), bad) {
+					t.Errorf("renderMarkdownHTML(%q) = %q, contains %q", tt.src, got, bad)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderMarkdownText(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"formatting", "**hola** _mundo_", "hola mundo"},
+		{"paragraphs", "uno\n\ndos", "uno\n\ndos"},
+		{"line breaks", "línea 1\nlínea 2", "línea 1\nlínea 2"},
+		{"list", "- uno\n- dos", "- uno\n\n- dos"},
+		{"link text", "[sitio](https://example.com)", "sitio"},
+		{"code span", "`<b>`", "<b>"},
+		{"html block", "<script>alert(1)</script>", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderMarkdownText(tt.src); got != tt.want {
+				t.Errorf("renderMarkdownText(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
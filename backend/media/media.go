@@ -0,0 +1,128 @@
+package media
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// contentTypes is the single extension -> MIME table used by the API server
+// and cmd/migrate, so disk and bucket modes agree on what they serve.
+var contentTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".mp4":  "video/mp4",
+	".webm": "video/webm",
+	".mov":  "video/quicktime",
+	".json": "application/json",
+	".txt":  "text/plain; charset=utf-8",
+	".md":   "text/markdown; charset=utf-8",
+}
+
+// ContentType returns the MIME type for filename based on its extension.
+func ContentType(filename string) string {
+	if ct, ok := contentTypes[strings.ToLower(path.Ext(filename))]; ok {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+const (
+	// Uploaded files are never overwritten (they get a fresh timestamped
+	// name), so browsers and CDNs can keep them forever.
+	immutableCacheControl = "public, max-age=31536000, immutable"
+
+	// Legacy files (copied by hand or by cmd/migrate) may be replaced in place,
+	// so keep them short-lived and let clients revalidate with the ETag.
+	defaultCacheControl = "public, max-age=300"
+)
+
+// IsImmutable reports whether filename follows the upload naming scheme
+// "<unix-nanos>_<name><ext>" used by adminUploadImage.
+func IsImmutable(filename string) bool {
+	base := path.Base(filename)
+	i := strings.IndexByte(base, '_')
+	if i < 10 {
+		return false
+	}
+	for _, r := range base[:i] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// SafeName returns the name part of the upload naming scheme for filename:
+// its base name without extension, keeping only letters, digits, hyphens and
+// underscores, at most 50 characters, or "image" when nothing is left.
+func SafeName(filename string) string {
+	base := filepath.Base(filename)
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+
+	var result strings.Builder
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			result.WriteRune(r)
+		}
+	}
+
+	s := result.String()
+	if len(s) > 50 {
+		s = s[:50]
+	}
+	if s == "" {
+		s = "image"
+	}
+	return s
+}
+
+// CacheControl returns the Cache-Control header value for a media file.
+func CacheControl(filename string) string {
+	if IsImmutable(filename) {
+		return immutableCacheControl
+	}
+	return defaultCacheControl
+}
+
+type etagEntry struct {
+	size    int64
+	modTime time.Time
+	etag    string
+}
+
+// etags caches file hashes by path; an entry is reused while the file keeps
+// the same size and modification time.
+var etags sync.Map
+
+// FileETag returns a strong ETag derived from the SHA-256 of the file contents.
+func FileETag(filePath string, info os.FileInfo) (string, error) {
+	if v, ok := etags.Load(filePath); ok {
+		e := v.(etagEntry)
+		if e.size == info.Size() && e.modTime.Equal(info.ModTime()) {
+			return e.etag, nil
+		}
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+	etags.Store(filePath, etagEntry{size: info.Size(), modTime: info.ModTime(), etag: etag})
+	return etag, nil
+}
@@ -0,0 +1,72 @@
+// Package money holds the amount type shared by the API server and
+// cmd/import-catalog, so prices are read and written the same way in both.
+package money
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Amount is a sum of money in hundredths of the currency unit, so that prices
+// are exact. In JSON it is a decimal string, "1234.10"; numbers are accepted
+// as well, which is what meta.json files and clients sent before.
+type Amount int64
+
+// ErrInvalid is returned for anything but a plain decimal.
+var ErrInvalid = errors.New("amount must be a decimal number with at most 2 decimals")
+
+// Parse reads a decimal such as "1234.1" without going through a float.
+func Parse(s string) (Amount, error) {
+	negative := strings.HasPrefix(s, "-")
+	whole, frac, hasPoint := strings.Cut(strings.TrimPrefix(s, "-"), ".")
+	if whole == "" || len(whole) > 15 || len(frac) > 2 || (hasPoint && frac == "") {
+		return 0, ErrInvalid
+	}
+	var n int64
+	for _, c := range whole + (frac + "00")[:2] {
+		if c < '0' || c > '9' {
+			return 0, ErrInvalid
+		}
+		n = n*10 + int64(c-'0')
+	}
+	if negative {
+		n = -n
+	}
+	return Amount(n), nil
+}
+
+func (a Amount) String() string {
+	sign, n := "", int64(a)
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	cents := strconv.FormatInt(n%100, 10)
+	if len(cents) == 1 {
+		cents = "0" + cents
+	}
+	return sign + strconv.FormatInt(n/100, 10) + "." + cents
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return strconv.AppendQuote(nil, a.String()), nil
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		var err error
+		if s, err = strconv.Unquote(s); err != nil {
+			return ErrInvalid
+		}
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
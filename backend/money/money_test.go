@@ -0,0 +1,36 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAmountJSON(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`"1234.10"`, `"1234.10"`},
+		{`1234.10`, `"1234.10"`},
+		{`"0.1"`, `"0.10"`},
+		{`2400`, `"2400.00"`},
+		{`"-5.05"`, `"-5.05"`},
+	}
+	for _, tt := range tests {
+		var a Amount
+		if err := json.Unmarshal([]byte(tt.in), &a); err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.in, err)
+			continue
+		}
+		got, _ := json.Marshal(a)
+		if string(got) != tt.want {
+			t.Errorf("%s round-trips to %s, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{`"1.234"`, `"1."`, `".5"`, `"1e3"`, `1e3`, `"12,50"`, `""`, `true`} {
+		var a Amount
+		if err := json.Unmarshal([]byte(in), &a); err == nil {
+			t.Errorf("Unmarshal(%s) = %v, want an error", in, a)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"alexis-art-backend/db"
+)
+
+// applyMergePatch applies an RFC 7386 JSON Merge Patch to base: members absent
+// from the patch are left untouched and explicit nulls clear the field.
+func applyMergePatch[T any](base T, patch map[string]any) (T, error) {
+	var out T
+	b, err := json.Marshal(base)
+	if err != nil {
+		return out, err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return out, err
+	}
+	b, err = json.Marshal(mergePatch(doc, patch))
+	if err != nil {
+		return out, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&out); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatch(t[k], v)
+	}
+	return t
+}
+
+// currentArtworkFields returns the stored editable fields of an artwork, i.e.
+// what a PUT would have to send to leave it unchanged.
+func currentArtworkFields(ctx context.Context, id string) (adminArtworkUpdate, error) {
+	a, err := getArtworkByID(ctx, id)
+	if err != nil {
+		return adminArtworkUpdate{}, err
+	}
+	return editableFields(ctx, a)
+}
+
+// editableFields is currentArtworkFields for an artwork already read.
+func editableFields(ctx context.Context, a Artwork) (adminArtworkUpdate, error) {
+	fields := adminArtworkUpdate{
+		PaintedLocation: a.PaintedLocation,
+		StartDate:       a.StartDate,
+		EndDate:         a.EndDate,
+		InProgress:      a.InProgress,
+		Detalle:         a.Detalle,
+		Technique:       a.Technique,
+		Dimensions:      a.Dimensions,
+		Price:           a.Price,
+		Availability:    a.Availability,
+	}
+	// Title and primary image are derived (from the ID / first image) unless
+	// stored in Postgres; don't persist the derived values.
+	if pgPool != nil {
+		ctxDB, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		row, err := db.GetArtwork(ctxDB, pgPool, a.ID)
+		if err != nil {
+			return adminArtworkUpdate{}, err
+		}
+		if row != nil {
+			fields.Title = row.Title
+			fields.PrimaryImage = row.PrimaryImage
+		}
+	}
+	return fields, nil
+}
+
+func adminPatchArtwork(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if !isSafeArtworkID(id) {
+		respondWithError(w, http.StatusBadRequest, "Invalid artwork id")
+		return
+	}
+
+	unlock := lockArtwork(id)
+	defer unlock()
+
+	if err := ensureArtworkExists(r.Context(), id); err != nil {
+		respondWithError(w, http.StatusNotFound, "Artwork not found")
+		return
+	}
+
+	// A partial update only touches the fields it names, so If-Match is
+	// optional here; when sent, it is enforced like on PUT.
+	if r.Header.Get("If-Match") != "" && !checkIfMatch(w, r, id) {
+		return
+	}
+
+	var patch map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil || patch == nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON (expected a merge patch object)")
+		return
+	}
+
+	base, err := currentArtworkFields(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to read artwork")
+		return
+	}
+	payload, err := applyMergePatch(base, patch)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid patch: "+err.Error())
+		return
+	}
+
+	if err := writeArtwork(r.Context(), id, payload, revisionActionUpdate); err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+	bumpCatalogVersion()
+
+	updated, err := getArtworkByID(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to re-read artwork")
+		return
+	}
+	respondWithArtwork(w, http.StatusOK, updated)
+}
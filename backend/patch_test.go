@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	height, width, wider := 40.0, 30.0, 35.0
+	base := adminArtworkUpdate{
+		Title:           "Sol de Lima",
+		PaintedLocation: "Lima",
+		InProgress:      true,
+		Dimensions:      &Dimensions{Height: &height, Width: &width, Unit: "cm"},
+		Availability:    "available",
+	}
+	with := func(change func(*adminArtworkUpdate)) adminArtworkUpdate {
+		p := base
+		d := *base.Dimensions
+		p.Dimensions = &d
+		change(&p)
+		return p
+	}
+
+	tests := []struct {
+		name    string
+		patch   map[string]any
+		want    adminArtworkUpdate
+		wantErr bool
+	}{
+		{"empty patch", map[string]any{}, base, false},
+		{"absent members untouched", map[string]any{"paintedLocation": "Cusco"},
+			with(func(p *adminArtworkUpdate) { p.PaintedLocation = "Cusco" }), false},
+		{"null clears", map[string]any{"paintedLocation": nil, "inProgress": nil},
+			with(func(p *adminArtworkUpdate) { p.PaintedLocation = ""; p.InProgress = false }), false},
+		{"nested members merge", map[string]any{"dimensions": map[string]any{"width": wider}},
+			with(func(p *adminArtworkUpdate) { p.Dimensions.Width = &wider }), false},
+		{"nested null clears the member", map[string]any{"dimensions": map[string]any{"height": nil}},
+			with(func(p *adminArtworkUpdate) { p.Dimensions.Height = nil }), false},
+		{"null clears an object", map[string]any{"dimensions": nil},
+			with(func(p *adminArtworkUpdate) { p.Dimensions = nil }), false},
+		{"unknown member", map[string]any{"titulo": "x"}, adminArtworkUpdate{}, true},
+		{"wrong type", map[string]any{"inProgress": "yes"}, adminArtworkUpdate{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyMergePatch(base, tt.patch)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("applyMergePatch = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applyMergePatch = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if base.PaintedLocation != "Lima" || *base.Dimensions.Width != width {
+		t.Error("applyMergePatch changed the base")
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"alexis-art-backend/db"
+)
+
+const (
+	defaultPreviewTTLHours = 7 * 24
+	maxPreviewTTLHours     = 30 * 24
+	maxPreviewNoteLength   = 200
+
+	// Previews are personal: never store them in shared caches.
+	previewCacheControl = "private, no-store"
+)
+
+// previewSecret signs preview tokens (PREVIEW_SECRET, or derived from
+// ADMIN_TOKEN).
+var previewSecret []byte
+
+type adminPreviewLinkCreate struct {
+	// 0 means defaultPreviewTTLHours.
+	ExpiresInHours int    `json:"expiresInHours"`
+	Note           string `json:"note"`
+}
+
+type PreviewLinkResponse struct {
+	ID        string     `json:"id"`
+	ArtworkID string     `json:"artworkId"`
+	Note      string     `json:"note,omitempty"`
+	CreatedBy string     `json:"createdBy"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	RevokedBy string     `json:"revokedBy,omitempty"`
+	Active    bool       `json:"active"`
+	Token     string     `json:"token"`
+	// API path of the artwork with the token attached. Media URLs need the
+	// same ?preview= parameter.
+	Path string `json:"path"`
+}
+
+type PreviewLinkListResponse struct {
+	Links []PreviewLinkResponse `json:"links"`
+	Total int                   `json:"total"`
+}
+
+func (p adminPreviewLinkCreate) validate() error {
+	var errs validationErrors
+	if p.ExpiresInHours < 0 || p.ExpiresInHours > maxPreviewTTLHours {
+		errs.add("expiresInHours", codeInvalidValue, "must be between 0 (default of "+strconv.Itoa(defaultPreviewTTLHours)+") and "+strconv.Itoa(maxPreviewTTLHours))
+	}
+	errs.checkLength("note", strings.TrimSpace(p.Note), maxPreviewNoteLength)
+	return errs.err()
+}
+
+func previewSignature(linkID, artworkID string, expires int64) string {
+	mac := hmac.New(sha256.New, previewSecret)
+	fmt.Fprintf(mac, "%s\n%s\n%d", linkID, artworkID, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// previewToken builds "<link id>.<expiry unix>.<signature>". The token is
+// bound to one artwork through the signature.
+func previewToken(link db.PreviewLinkRow) string {
+	expires := link.ExpiresAt.Unix()
+	return link.ID + "." + strconv.FormatInt(expires, 10) + "." + previewSignature(link.ID, link.ArtworkID, expires)
+}
+
+// hasValidPreview reports whether the request carries a valid preview token
+// for the artwork (?preview= or X-Preview-Token): correctly signed, not
+// expired and not revoked.
+func hasValidPreview(r *http.Request, artworkID string) bool {
+	token := r.URL.Query().Get("preview")
+	if token == "" {
+		token = r.Header.Get("X-Preview-Token")
+	}
+	if token == "" || pgPool == nil {
+		return false
+	}
+	linkID, expires, ok := verifyPreviewToken(token, artworkID, time.Now())
+	if !ok {
+		return false
+	}
+
+	// Signature is fine; check the link was not revoked.
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+	link, err := db.GetPreviewLink(ctx, pgPool, linkID)
+	if err != nil || link == nil {
+		return false
+	}
+	return link.ArtworkID == artworkID && link.RevokedAt == nil && link.ExpiresAt.Unix() == expires
+}
+
+// verifyPreviewToken checks the form, expiry and signature of a token for
+// the artwork and returns the link it names; revocation is checked by the
+// caller.
+func verifyPreviewToken(token, artworkID string, now time.Time) (linkID string, expires int64, ok bool) {
+	if len(previewSecret) == 0 {
+		return "", 0, false
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", 0, false
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || now.Unix() > expires {
+		return "", 0, false
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(previewSignature(parts[0], artworkID, expires))) {
+		return "", 0, false
+	}
+	return parts[0], expires, true
+}
+
+func previewLinkResponse(link db.PreviewLinkRow) PreviewLinkResponse {
+	token := previewToken(link)
+	return PreviewLinkResponse{
+		ID:        link.ID,
+		ArtworkID: link.ArtworkID,
+		Note:      link.Note,
+		CreatedBy: link.CreatedBy,
+		CreatedAt: link.CreatedAt,
+		ExpiresAt: link.ExpiresAt,
+		RevokedAt: link.RevokedAt,
+		RevokedBy: link.RevokedBy,
+		Active:    link.RevokedAt == nil && time.Now().Before(link.ExpiresAt),
+		Token:     token,
+		Path:      "/api/v1/artworks/" + url.PathEscape(link.ArtworkID) + "?preview=" + url.QueryEscape(token),
+	}
+}
+
+func adminCreatePreviewLink(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	if len(previewSecret) == 0 {
+		respondWithError(w, http.StatusInternalServerError, "PREVIEW_SECRET is not configured")
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if !isSafeArtworkID(id) {
+		respondWithError(w, http.StatusBadRequest, "Invalid artwork id")
+		return
+	}
+	if err := ensureArtworkExists(r.Context(), id); err != nil {
+		respondWithError(w, http.StatusNotFound, "Artwork not found")
+		return
+	}
+
+	var payload adminPreviewLinkCreate
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if err := payload.validate(); err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+	hours := payload.ExpiresInHours
+	if hours == 0 {
+		hours = defaultPreviewTTLHours
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	link, err := db.InsertPreviewLink(ctx, pgPool, db.PreviewLinkRow{
+		ID:        generateArtworkID(),
+		ArtworkID: id,
+		Note:      strings.TrimSpace(payload.Note),
+		CreatedBy: actorFromContext(r.Context()),
+		// Tokens carry the expiry in whole seconds.
+		ExpiresAt: time.Now().Add(time.Duration(hours) * time.Hour).Truncate(time.Second),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create preview link")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(previewLinkResponse(*link))
+}
+
+func adminListPreviewLinks(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if !isSafeArtworkID(id) {
+		respondWithError(w, http.StatusBadRequest, "Invalid artwork id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	rows, err := db.ListPreviewLinks(ctx, pgPool, id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list preview links")
+		return
+	}
+
+	links := make([]PreviewLinkResponse, 0, len(rows))
+	for _, row := range rows {
+		links = append(links, previewLinkResponse(row))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PreviewLinkListResponse{Links: links, Total: len(links)})
+}
+
+func adminRevokePreviewLink(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	linkID := mux.Vars(r)["linkId"]
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	revoked, err := db.RevokePreviewLink(ctx, pgPool, linkID, actorFromContext(r.Context()))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke preview link")
+		return
+	}
+	if !revoked {
+		respondWithError(w, http.StatusNotFound, "Preview link not found or already revoked")
+		return
+	}
+
+	link, err := db.GetPreviewLink(ctx, pgPool, linkID)
+	if err != nil || link == nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to re-read preview link")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(previewLinkResponse(*link))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"alexis-art-backend/db"
+)
+
+func TestVerifyPreviewToken(t *testing.T) {
+	prev := previewSecret
+	previewSecret = []byte("test-preview-secret")
+	t.Cleanup(func() { previewSecret = prev })
+
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	link := db.PreviewLinkRow{ID: "link1", ArtworkID: "obra", ExpiresAt: now.Add(time.Hour)}
+	token := previewToken(link)
+	parts := strings.Split(token, ".")
+	later := strconv.FormatInt(link.ExpiresAt.Add(24*time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		token     string
+		artworkID string
+		now       time.Time
+		secret    string
+		want      bool
+	}{
+		{"valid", token, "obra", now, "", true},
+		{"valid until expiry", token, "obra", link.ExpiresAt, "", true},
+		{"expired", token, "obra", link.ExpiresAt.Add(time.Second), "", false},
+		{"other artwork", token, "otra", now, "", false},
+		{"tampered signature", parts[0] + "." + parts[1] + "." + strings.Repeat("A", len(parts[2])), "obra", now, "", false},
+		{"extended expiry", parts[0] + "." + later + "." + parts[2], "obra", now, "", false},
+		{"other link", "link2." + parts[1] + "." + parts[2], "obra", now, "", false},
+		{"malformed", parts[0] + "." + parts[2], "obra", now, "", false},
+		{"non-numeric expiry", parts[0] + ".soon." + parts[2], "obra", now, "", false},
+		{"other secret", token, "obra", now, "rotated-secret", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.secret != "" {
+				previewSecret = []byte(tt.secret)
+				t.Cleanup(func() { previewSecret = []byte("test-preview-secret") })
+			}
+			linkID, expires, ok := verifyPreviewToken(tt.token, tt.artworkID, tt.now)
+			if ok != tt.want {
+				t.Fatalf("verifyPreviewToken ok = %v, want %v", ok, tt.want)
+			}
+			if ok && (linkID != link.ID || expires != link.ExpiresAt.Unix()) {
+				t.Errorf("verifyPreviewToken = %q, %d, want %q, %d", linkID, expires, link.ID, link.ExpiresAt.Unix())
+			}
+		})
+	}
+
+	t.Run("no secret", func(t *testing.T) {
+		previewSecret = nil
+		t.Cleanup(func() { previewSecret = []byte("test-preview-secret") })
+		if _, _, ok := verifyPreviewToken(token, "obra", now); ok {
+			t.Error("verifyPreviewToken accepted a token without a secret")
+		}
+	})
+}
+
+func TestAdminPreviewLinkCreateValidate(t *testing.T) {
+	tests := []struct {
+		hours int
+		want  bool
+	}{
+		{0, true}, // default
+		{1, true},
+		{maxPreviewTTLHours, true},
+		{-1, false},
+		{maxPreviewTTLHours + 1, false},
+	}
+	for _, tt := range tests {
+		err := adminPreviewLinkCreate{ExpiresInHours: tt.hours}.validate()
+		if (err == nil) != tt.want {
+			t.Errorf("validate(%d hours) = %v, want valid %v", tt.hours, err, tt.want)
+		}
+	}
+}
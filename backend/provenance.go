@@ -0,0 +1,390 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"alexis-art-backend/db"
+	"alexis-art-backend/money"
+)
+
+// Provenance records are private: they are only served by the admin API and
+// never embedded in Artwork responses.
+
+// How an owner acquired an artwork.
+const (
+	saleChannelDirect     = "direct"
+	saleChannelGallery    = "gallery"
+	saleChannelOnline     = "online"
+	saleChannelAuction    = "auction"
+	saleChannelCommission = "commission"
+	saleChannelGift       = "gift"
+	saleChannelOther      = "other"
+)
+
+var saleChannels = []string{saleChannelDirect, saleChannelGallery, saleChannelOnline, saleChannelAuction, saleChannelCommission, saleChannelGift, saleChannelOther}
+
+// Whether the artwork is currently lent by the owner of the record.
+const (
+	loanStatusNone     = "none"
+	loanStatusOnLoan   = "on_loan"
+	loanStatusReturned = "returned"
+)
+
+var loanStatuses = []string{loanStatusNone, loanStatusOnLoan, loanStatusReturned}
+
+const (
+	maxOwnerNameLength       = 200
+	maxOwnerContactLength    = 500
+	maxProvenanceNotesLength = 5000
+)
+
+type ProvenanceOwner struct {
+	Name    string `json:"name"`
+	Email   string `json:"email,omitempty"`
+	Phone   string `json:"phone,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+type ProvenanceRecord struct {
+	ID          int64           `json:"id"`
+	ArtworkID   string          `json:"artworkId"`
+	Owner       ProvenanceOwner `json:"owner"`
+	AcquiredOn  string          `json:"acquiredOn,omitempty"`
+	Price       *Price          `json:"price,omitempty"`
+	SaleChannel string          `json:"saleChannel,omitempty"`
+	Location    string          `json:"location,omitempty"`
+	LoanStatus  string          `json:"loanStatus"`
+	Notes       string          `json:"notes,omitempty"`
+	CreatedBy   string          `json:"createdBy"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+}
+
+type ProvenanceListResponse struct {
+	Records []ProvenanceRecord `json:"records"`
+	Total   int                `json:"total"`
+}
+
+type adminProvenancePayload struct {
+	Owner       ProvenanceOwner `json:"owner"`
+	AcquiredOn  string          `json:"acquiredOn"`
+	Price       *Price          `json:"price"`
+	SaleChannel string          `json:"saleChannel"`
+	Location    string          `json:"location"`
+	LoanStatus  string          `json:"loanStatus"`
+	Notes       string          `json:"notes"`
+}
+
+func (p *adminProvenancePayload) normalize() {
+	for _, f := range []*string{&p.Owner.Name, &p.Owner.Email, &p.Owner.Phone, &p.Owner.Address, &p.AcquiredOn, &p.SaleChannel, &p.Location, &p.LoanStatus} {
+		*f = strings.TrimSpace(*f)
+	}
+	if p.LoanStatus == "" {
+		p.LoanStatus = loanStatusNone
+	}
+	if p.Price != nil {
+		p.Price.Currency = strings.ToUpper(strings.TrimSpace(p.Price.Currency))
+		if p.Price.Currency == "" {
+			p.Price.Currency = defaultCurrency
+		}
+	}
+}
+
+func (p adminProvenancePayload) validate() error {
+	var errs validationErrors
+	if p.Owner.Name == "" {
+		errs.add("owner.name", codeRequired, "is required")
+	}
+	errs.checkLength("owner.name", p.Owner.Name, maxOwnerNameLength)
+	if p.Owner.Email != "" {
+		if _, err := mail.ParseAddress(p.Owner.Email); err != nil {
+			errs.add("owner.email", codeInvalidValue, "must be a valid email address")
+		}
+	}
+	errs.checkLength("owner.email", p.Owner.Email, maxOwnerContactLength)
+	errs.checkLength("owner.phone", p.Owner.Phone, maxOwnerContactLength)
+	errs.checkLength("owner.address", p.Owner.Address, maxOwnerContactLength)
+	errs.checkDate("acquiredOn", p.AcquiredOn)
+	if p.Price != nil {
+		if p.Price.Amount < 0 || p.Price.Amount > maxPrice*100 {
+			errs.add("price.amount", codeInvalidValue, "must be between 0 and "+strconv.Itoa(maxPrice))
+		}
+		if !isCurrencyCode(p.Price.Currency) {
+			errs.add("price.currency", codeInvalidValue, "must be a 3-letter ISO 4217 code")
+		}
+	}
+	if p.SaleChannel != "" && !slices.Contains(saleChannels, p.SaleChannel) {
+		errs.add("saleChannel", codeInvalidValue, "must be one of: "+strings.Join(saleChannels, ", "))
+	}
+	errs.checkLength("location", p.Location, maxLocationLength)
+	if !slices.Contains(loanStatuses, p.LoanStatus) {
+		errs.add("loanStatus", codeInvalidValue, "must be one of: "+strings.Join(loanStatuses, ", "))
+	}
+	errs.checkLength("notes", p.Notes, maxProvenanceNotesLength)
+	return errs.err()
+}
+
+func (p adminProvenancePayload) row(artworkID string) db.ProvenanceRow {
+	row := db.ProvenanceRow{
+		ArtworkID:    artworkID,
+		OwnerName:    p.Owner.Name,
+		OwnerEmail:   p.Owner.Email,
+		OwnerPhone:   p.Owner.Phone,
+		OwnerAddress: p.Owner.Address,
+		SaleChannel:  p.SaleChannel,
+		Location:     p.Location,
+		LoanStatus:   p.LoanStatus,
+		Notes:        p.Notes,
+	}
+	row.AcquiredOn, _ = parseDate(p.AcquiredOn)
+	if p.Price != nil {
+		amount := int64(p.Price.Amount)
+		row.Price, row.Currency = &amount, p.Price.Currency
+	}
+	return row
+}
+
+func provenanceRecord(row db.ProvenanceRow) ProvenanceRecord {
+	rec := ProvenanceRecord{
+		ID:        row.ID,
+		ArtworkID: row.ArtworkID,
+		Owner: ProvenanceOwner{
+			Name:    row.OwnerName,
+			Email:   row.OwnerEmail,
+			Phone:   row.OwnerPhone,
+			Address: row.OwnerAddress,
+		},
+		SaleChannel: row.SaleChannel,
+		Location:    row.Location,
+		LoanStatus:  row.LoanStatus,
+		Notes:       row.Notes,
+		CreatedBy:   row.CreatedBy,
+		CreatedAt:   row.CreatedAt,
+		UpdatedAt:   row.UpdatedAt,
+	}
+	if row.AcquiredOn != nil {
+		rec.AcquiredOn = row.AcquiredOn.Format(dateLayout)
+	}
+	if row.Price != nil {
+		rec.Price = &Price{Amount: money.Amount(*row.Price), Currency: row.Currency}
+	}
+	return rec
+}
+
+// provenanceTarget reads the artwork id and, when present, the record id from
+// the URL.
+func provenanceTarget(w http.ResponseWriter, r *http.Request) (string, int64, bool) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return "", 0, false
+	}
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if !isSafeArtworkID(id) {
+		respondWithError(w, http.StatusBadRequest, "Invalid artwork id")
+		return "", 0, false
+	}
+	var recordID int64
+	if s, ok := vars["recordId"]; ok {
+		var err error
+		if recordID, err = strconv.ParseInt(s, 10, 64); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid record id")
+			return "", 0, false
+		}
+	}
+	return id, recordID, true
+}
+
+func adminListProvenance(w http.ResponseWriter, r *http.Request) {
+	id, _, ok := provenanceTarget(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	rows, err := db.ListProvenance(ctx, pgPool, id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list provenance")
+		return
+	}
+	records := make([]ProvenanceRecord, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, provenanceRecord(row))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, no-store")
+	json.NewEncoder(w).Encode(ProvenanceListResponse{Records: records, Total: len(records)})
+}
+
+func adminCreateProvenance(w http.ResponseWriter, r *http.Request) {
+	id, _, ok := provenanceTarget(w, r)
+	if !ok {
+		return
+	}
+	var payload adminProvenancePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	payload.normalize()
+	if err := payload.validate(); err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+	if err := ensureArtworkExists(r.Context(), id); err != nil {
+		respondWithError(w, http.StatusNotFound, "Artwork not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	row := payload.row(id)
+	row.CreatedBy = actorFromContext(r.Context())
+	recordID, err := db.InsertProvenance(ctx, pgPool, row)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create provenance record")
+		return
+	}
+	saved, err := db.GetProvenance(ctx, pgPool, id, recordID)
+	if err != nil || saved == nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to re-read provenance record")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(provenanceRecord(*saved))
+}
+
+func adminUpdateProvenance(w http.ResponseWriter, r *http.Request) {
+	id, recordID, ok := provenanceTarget(w, r)
+	if !ok {
+		return
+	}
+	var payload adminProvenancePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	payload.normalize()
+	if err := payload.validate(); err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	row := payload.row(id)
+	row.ID = recordID
+	updated, err := db.UpdateProvenance(ctx, pgPool, row)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update provenance record")
+		return
+	}
+	if !updated {
+		respondWithError(w, http.StatusNotFound, "Provenance record not found")
+		return
+	}
+	saved, err := db.GetProvenance(ctx, pgPool, id, recordID)
+	if err != nil || saved == nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to re-read provenance record")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(provenanceRecord(*saved))
+}
+
+func adminDeleteProvenance(w http.ResponseWriter, r *http.Request) {
+	id, recordID, ok := provenanceTarget(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	deleted, err := db.DeleteProvenance(ctx, pgPool, id, recordID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete provenance record")
+		return
+	}
+	if !deleted {
+		respondWithError(w, http.StatusNotFound, "Provenance record not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var provenanceCSVHeader = []string{
+	"artwork_id", "record_id", "owner_name", "owner_email", "owner_phone", "owner_address",
+	"acquired_on", "price", "currency", "sale_channel", "location", "loan_status", "notes",
+	"created_by", "created_at", "updated_at",
+}
+
+// adminExportProvenance answers GET /admin/provenance.csv with the records of
+// every artwork, or of one with ?artworkId=.
+func adminExportProvenance(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	artworkID := strings.TrimSpace(r.URL.Query().Get("artworkId"))
+	if artworkID != "" && !isSafeArtworkID(artworkID) {
+		respondWithError(w, http.StatusBadRequest, "Invalid artwork id")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	rows, err := db.ListProvenance(ctx, pgPool, artworkID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list provenance")
+		return
+	}
+
+	filename := "provenance.csv"
+	if artworkID != "" {
+		filename = "provenance-" + artworkID + ".csv"
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Cache-Control", "private, no-store")
+	// The BOM makes spreadsheet apps read accents as UTF-8.
+	w.Write([]byte("\ufeff"))
+
+	out := csv.NewWriter(w)
+	out.Write(provenanceCSVHeader)
+	for _, row := range rows {
+		acquiredOn, price := "", ""
+		if row.AcquiredOn != nil {
+			acquiredOn = row.AcquiredOn.Format(dateLayout)
+		}
+		if row.Price != nil {
+			price = money.Amount(*row.Price).String()
+		}
+		record := []string{
+			row.ArtworkID, strconv.FormatInt(row.ID, 10), row.OwnerName, row.OwnerEmail, row.OwnerPhone, row.OwnerAddress,
+			acquiredOn, price, row.Currency, row.SaleChannel, row.Location, row.LoanStatus, row.Notes,
+			row.CreatedBy, row.CreatedAt.UTC().Format(time.RFC3339), row.UpdatedAt.UTC().Format(time.RFC3339),
+		}
+		for i := range record {
+			record[i] = csvSafe(record[i])
+		}
+		out.Write(record)
+	}
+	out.Flush()
+}
+
+// csvSafe keeps spreadsheet apps from evaluating free text as a formula.
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
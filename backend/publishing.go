@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"alexis-art-backend/db"
+)
+
+// Publishing status of an artwork. Only published artworks whose publish_at
+// (if any) has passed are visible through the public API.
+const (
+	statusDraft     = "draft"
+	statusPublished = "published"
+	statusArchived  = "archived"
+)
+
+type adminPublishRequest struct {
+	// RFC 3339 timestamp; omitted or empty publishes immediately.
+	PublishAt string `json:"publishAt"`
+}
+
+func isVisibleStatus(status string, publishAt *time.Time, now time.Time) bool {
+	if status != "" && status != statusPublished {
+		return false
+	}
+	return publishAt == nil || !publishAt.After(now)
+}
+
+func (a Artwork) isPublic() bool {
+	return isVisibleStatus(a.Status, a.PublishAt, time.Now())
+}
+
+func publicArtworks(artworks []Artwork) []Artwork {
+	out := make([]Artwork, 0, len(artworks))
+	for _, a := range artworks {
+		if a.isPublic() {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// isArtworkPublic checks visibility from the DB row alone, without scanning
+// storage, so media routes stay cheap. It fails closed on DB errors.
+func isArtworkPublic(ctx context.Context, id string) bool {
+	if pgPool == nil {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	row, err := db.GetArtwork(ctx, pgPool, id)
+	if err != nil {
+		return false
+	}
+	if row == nil {
+		return true
+	}
+	return isVisibleStatus(row.Status, row.PublishAt, time.Now())
+}
+
+func adminPublishArtwork(w http.ResponseWriter, r *http.Request) {
+	var payload adminPublishRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	var publishAt *time.Time
+	if v := strings.TrimSpace(payload.PublishAt); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			var errs validationErrors
+			errs.add("publishAt", codeInvalidDate, "must be an RFC 3339 timestamp, e.g. 2025-03-01T19:00:00+01:00")
+			respondWithValidationErrors(w, errs)
+			return
+		}
+		publishAt = &t
+	}
+	setArtworkStatus(w, r, statusPublished, publishAt, revisionActionPublish)
+}
+
+func adminUnpublishArtwork(w http.ResponseWriter, r *http.Request) {
+	setArtworkStatus(w, r, statusDraft, nil, revisionActionUnpublish)
+}
+
+func adminArchiveArtwork(w http.ResponseWriter, r *http.Request) {
+	setArtworkStatus(w, r, statusArchived, nil, revisionActionArchive)
+}
+
+func setArtworkStatus(w http.ResponseWriter, r *http.Request, status string, publishAt *time.Time, action string) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if !isSafeArtworkID(id) {
+		respondWithError(w, http.StatusBadRequest, "Invalid artwork id")
+		return
+	}
+
+	unlock := lockArtwork(id)
+	defer unlock()
+
+	if err := ensureArtworkExists(r.Context(), id); err != nil {
+		respondWithError(w, http.StatusNotFound, "Artwork not found")
+		return
+	}
+	// Same precondition as PUT: publishing a version the editor has not seen
+	// is as much a lost update as overwriting it.
+	if !checkIfMatch(w, r, id) {
+		return
+	}
+	before := snapshotArtwork(r.Context(), id)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	if err := db.SetArtworkStatus(ctx, pgPool, id, status, publishAt); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update status")
+		return
+	}
+	bumpCatalogVersion()
+	recordArtworkChange(r.Context(), id, action, before)
+
+	updated, err := getArtworkByID(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to re-read artwork")
+		return
+	}
+	respondWithArtwork(w, http.StatusOK, updated)
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"alexis-art-backend/db"
+)
+
+const (
+	revisionActionCreate       = "create"
+	revisionActionUpdate       = "update"
+	revisionActionRollback     = "rollback"
+	revisionActionPublish      = "publish"
+	revisionActionUnpublish    = "unpublish"
+	revisionActionArchive      = "archive"
+	revisionActionUploadImage  = "upload_image"
+	revisionActionDeleteImage  = "delete_image"
+	revisionActionTags         = "tags"
+	revisionActionSeries       = "series"
+	revisionActionExhibitions  = "exhibitions"
+	revisionActionBitacora     = "bitacora"
+	revisionActionTranslations = "translations"
+)
+
+// revisionSnapshot is the state of an artwork a revision stores: the editable
+// fields, which a rollback restores, and what the other admin routes change,
+// which is kept for the history only.
+type revisionSnapshot struct {
+	adminArtworkUpdate
+	Status          string          `json:"status"`
+	PublishAt       *time.Time      `json:"publishAt"`
+	Images          []string        `json:"images"`
+	Videos          []string        `json:"videos"`
+	Tags            []string        `json:"tags"`        // slugs
+	Series          []string        `json:"series"`      // slugs
+	Exhibitions     []string        `json:"exhibitions"` // slugs
+	BitacoraEntries []BitacoraEntry `json:"bitacoraEntries"`
+	// By locale; the entries have only the translated ones.
+	Translations map[string]adminTranslationPayload `json:"translations"`
+}
+
+// snapshotOf builds the revision snapshot of an artwork already read.
+func snapshotOf(ctx context.Context, a Artwork) (revisionSnapshot, error) {
+	fields, err := editableFields(ctx, a)
+	if err != nil {
+		return revisionSnapshot{}, err
+	}
+	s := revisionSnapshot{
+		adminArtworkUpdate: fields,
+		Status:             a.Status,
+		PublishAt:          a.PublishAt,
+		Images:             a.Images,
+		Videos:             a.Videos,
+		Tags:               []string{},
+		Exhibitions:        []string{},
+		BitacoraEntries:    a.BitacoraEntries,
+		Translations:       map[string]adminTranslationPayload{},
+	}
+	if s.Videos == nil {
+		s.Videos = []string{}
+	}
+	if s.BitacoraEntries == nil {
+		s.BitacoraEntries = []BitacoraEntry{}
+	}
+	for _, t := range a.Tags {
+		s.Tags = append(s.Tags, t.Slug)
+	}
+	for _, e := range a.Exhibitions {
+		s.Exhibitions = append(s.Exhibitions, e.Slug)
+	}
+	ctxDB, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if s.Series, err = db.ArtworkSeriesSlugs(ctxDB, pgPool, a.ID); err != nil {
+		return revisionSnapshot{}, err
+	}
+	translations, err := db.ListTranslations(ctxDB, pgPool, a.ID, "")
+	if err != nil {
+		return revisionSnapshot{}, err
+	}
+	entries, err := db.ListBitacoraTranslations(ctxDB, pgPool, a.ID, "")
+	if err != nil {
+		return revisionSnapshot{}, err
+	}
+	for _, t := range translations {
+		s.Translations[t.Locale] = adminTranslationPayload{Title: t.Title, Detalle: t.Detalle, BitacoraEntries: []EntryTranslation{}}
+	}
+	for _, e := range entries {
+		t := s.Translations[e.Locale]
+		if t.BitacoraEntries == nil {
+			t.BitacoraEntries = []EntryTranslation{}
+		}
+		t.BitacoraEntries = append(t.BitacoraEntries, EntryTranslation{ID: e.EntryID, Text: e.Body})
+		s.Translations[e.Locale] = t
+	}
+	return s, nil
+}
+
+// snapshotArtwork reads the state of an artwork before a change made outside
+// writeArtwork, for recordArtworkChange. Callers hold the artwork lock. It
+// returns nil without Postgres, or when the artwork cannot be read: the change
+// then goes ahead unrecorded.
+func snapshotArtwork(ctx context.Context, id string) *revisionSnapshot {
+	if pgPool == nil {
+		return nil
+	}
+	a, err := getArtworkByID(ctx, id)
+	if err != nil {
+		return nil
+	}
+	s, err := snapshotOf(ctx, a)
+	if err != nil {
+		log.Printf("Reading revision snapshot of %s failed: %v", id, err)
+		return nil
+	}
+	return &s
+}
+
+// recordArtworkChange records a revision for a change made outside
+// writeArtwork, from before (see snapshotArtwork) to the state the artwork has
+// now. The change is already stored, so failures are only logged.
+func recordArtworkChange(ctx context.Context, id, action string, before *revisionSnapshot) {
+	if before == nil {
+		return
+	}
+	after := snapshotArtwork(ctx, id)
+	if after == nil {
+		return
+	}
+	auditSnapshots(ctx, id, before, after)
+	ctxDB, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	if err := recordRevision(ctxDB, pgPool, id, actorFromContext(ctx), action, *before, *after); err != nil {
+		log.Printf("Recording revision for %s failed: %v", id, err)
+	}
+}
+
+// fieldChange is one entry of a revision diff.
+type fieldChange = db.FieldChange
+
+type RevisionResponse struct {
+	ID        int64                  `json:"id"`
+	ArtworkID string                 `json:"artworkId"`
+	Actor     string                 `json:"actor"`
+	Action    string                 `json:"action"`
+	CreatedAt time.Time              `json:"createdAt"`
+	Diff      map[string]fieldChange `json:"diff"`
+	// As stored: older revisions lack the fields added since.
+	Snapshot json.RawMessage `json:"snapshot,omitempty"`
+}
+
+type RevisionListResponse struct {
+	Revisions []RevisionResponse `json:"revisions"`
+	Total     int                `json:"total"`
+}
+
+type RevisionCompareResponse struct {
+	From int64                  `json:"from"`
+	To   int64                  `json:"to"`
+	Diff map[string]fieldChange `json:"diff"`
+}
+
+// recordRevision stores the new state of an artwork and what changed. Nothing
+// is recorded when nothing changed.
+func recordRevision(ctx context.Context, q db.Querier, id, actor, action string, previous, next revisionSnapshot) error {
+	return db.RecordRevision(ctx, q, id, actor, action, previous, next, action == revisionActionCreate)
+}
+
+func revisionResponse(row db.RevisionRow, withSnapshot bool) RevisionResponse {
+	resp := RevisionResponse{
+		ID:        row.ID,
+		ArtworkID: row.ArtworkID,
+		Actor:     row.Actor,
+		Action:    row.Action,
+		CreatedAt: row.CreatedAt,
+		Diff:      map[string]fieldChange{},
+	}
+	json.Unmarshal(row.Diff, &resp.Diff)
+	if withSnapshot {
+		resp.Snapshot = json.RawMessage(row.Snapshot)
+	}
+	return resp
+}
+
+// loadRevision reads revision {rev} of artwork {id} from the route, answering
+// the error itself when it cannot.
+func loadRevision(w http.ResponseWriter, r *http.Request, id, rev string) (*db.RevisionRow, bool) {
+	revID, err := strconv.ParseInt(rev, 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid revision id")
+		return nil, false
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	row, err := db.GetRevision(ctx, pgPool, id, revID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to read revision")
+		return nil, false
+	}
+	if row == nil {
+		respondWithError(w, http.StatusNotFound, "Revision not found")
+		return nil, false
+	}
+	return row, true
+}
+
+func adminListRevisions(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if !isSafeArtworkID(id) {
+		respondWithError(w, http.StatusBadRequest, "Invalid artwork id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	rows, err := db.ListRevisions(ctx, pgPool, id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list revisions")
+		return
+	}
+
+	revisions := make([]RevisionResponse, 0, len(rows))
+	for _, row := range rows {
+		revisions = append(revisions, revisionResponse(row, false))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RevisionListResponse{Revisions: revisions, Total: len(revisions)})
+}
+
+func adminGetRevision(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	vars := mux.Vars(r)
+	row, ok := loadRevision(w, r, vars["id"], vars["rev"])
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisionResponse(*row, true))
+}
+
+// adminCompareRevisions diffs the snapshots of two revisions (?from=&to=).
+func adminCompareRevisions(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	id := mux.Vars(r)["id"]
+	from, ok := loadRevision(w, r, id, r.URL.Query().Get("from"))
+	if !ok {
+		return
+	}
+	to, ok := loadRevision(w, r, id, r.URL.Query().Get("to"))
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RevisionCompareResponse{
+		From: from.ID,
+		To:   to.ID,
+		Diff: db.DiffFields(json.RawMessage(from.Snapshot), json.RawMessage(to.Snapshot)),
+	})
+}
+
+// rollbackFields returns the editable fields a rollback to snapshot writes:
+// those the snapshot has, and the current value of fields added after it was
+// taken (e.g. the sales fields for revisions older than them).
+func rollbackFields(current adminArtworkUpdate, snapshot []byte) (adminArtworkUpdate, error) {
+	var stored map[string]json.RawMessage
+	if err := json.Unmarshal(snapshot, &stored); err != nil {
+		return adminArtworkUpdate{}, err
+	}
+	fields := map[string]json.RawMessage{}
+	b, _ := json.Marshal(current)
+	json.Unmarshal(b, &fields)
+	for k := range fields {
+		if v, ok := stored[k]; ok {
+			fields[k] = v
+		}
+	}
+	b, _ = json.Marshal(fields)
+	var payload adminArtworkUpdate
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return adminArtworkUpdate{}, err
+	}
+	return payload, nil
+}
+
+// adminRollbackRevision restores the editable fields of a revision, writing
+// through to meta.json, the text files and Postgres. Status, media and
+// relations are not restored. The rollback is itself a revision.
+func adminRollbackRevision(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if !isSafeArtworkID(id) {
+		respondWithError(w, http.StatusBadRequest, "Invalid artwork id")
+		return
+	}
+
+	unlock := lockArtwork(id)
+	defer unlock()
+
+	if err := ensureArtworkExists(r.Context(), id); err != nil {
+		respondWithError(w, http.StatusNotFound, "Artwork not found")
+		return
+	}
+	if r.Header.Get("If-Match") != "" && !checkIfMatch(w, r, id) {
+		return
+	}
+
+	row, ok := loadRevision(w, r, id, vars["rev"])
+	if !ok {
+		return
+	}
+	current, err := currentArtworkFields(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to read artwork")
+		return
+	}
+	payload, err := rollbackFields(current, row.Snapshot)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to read revision")
+		return
+	}
+
+	if err := writeArtwork(r.Context(), id, payload, revisionActionRollback); err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+	bumpCatalogVersion()
+
+	updated, err := getArtworkByID(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to re-read artwork")
+		return
+	}
+	respondWithArtwork(w, http.StatusOK, updated)
+}
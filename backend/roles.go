@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+)
+
+// permission names one kind of admin action. Every admin route requires
+// exactly one (see main), except /me which any signed-in caller can use.
+type permission string
+
+const (
+	permDraftsRead    permission = "drafts:read"    // list and read artworks, drafts included
+	permArtworksRead  permission = "artworks:read"  // every other admin GET: revisions, taxonomy, exports...
+	permArtworksWrite permission = "artworks:write" // edit texts and metadata, publish, organize
+	permMediaUpload   permission = "media:upload"
+	permContentDelete permission = "content:delete" // delete images, tags, series, entries...; archive artworks
+	permSalesWrite    permission = "sales:write"    // change price or availability
+	permProvenance    permission = "provenance:manage"
+	permCertificates  permission = "certificates:manage"
+	permUsersManage   permission = "users:manage"
+	permAPIKeys       permission = "api-keys:manage"
+	permAuditRead     permission = "audit:read"
+)
+
+const (
+	roleOwner  = "owner"
+	roleEditor = "editor"
+	roleViewer = "viewer"
+)
+
+var roles = []string{roleOwner, roleEditor, roleViewer}
+
+var rolePermissions = map[string][]permission{
+	roleViewer: {permDraftsRead, permArtworksRead},
+	roleEditor: {permDraftsRead, permArtworksRead, permArtworksWrite, permMediaUpload},
+	roleOwner: {permDraftsRead, permArtworksRead, permArtworksWrite, permMediaUpload, permContentDelete, permSalesWrite,
+		permProvenance, permCertificates, permUsersManage, permAPIKeys, permAuditRead},
+}
+
+// permissionError is returned when the role of the caller lacks a permission.
+type permissionError struct {
+	Permission permission
+}
+
+func (e *permissionError) Error() string {
+	return "Forbidden: missing permission " + string(e.Permission)
+}
+
+// permissions come from the role, or from the scopes for an API key.
+func (id adminIdentity) permissions() []permission {
+	if id.APIKeyID != "" {
+		return scopesPermissions(id.Scopes)
+	}
+	return rolePermissions[id.Role]
+}
+
+func (id adminIdentity) can(perm permission) bool {
+	return slices.Contains(id.permissions(), perm)
+}
+
+func hasPermission(ctx context.Context, perm permission) bool {
+	id, ok := identityFromContext(ctx)
+	return ok && id.can(perm)
+}
+
+func respondWithPermissionError(w http.ResponseWriter, perm permission) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Error:             (&permissionError{Permission: perm}).Error(),
+		MissingPermission: string(perm),
+	})
+}
+
+// requirePermission wraps an admin handler so it only runs for roles that
+// have perm.
+func requirePermission(perm permission, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !hasPermission(r.Context(), perm) {
+			respondWithPermissionError(w, perm)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// routePermissions is the permission every admin route requires; "" means any
+// signed-in caller.
+var routePermissions = map[string]permission{
+	"GET /artworks":                                permDraftsRead,
+	"POST /artworks":                               permArtworksWrite,
+	"GET /artworks/{id}":                           permDraftsRead,
+	"PUT /artworks/{id}":                           permArtworksWrite,
+	"PATCH /artworks/{id}":                         permArtworksWrite,
+	"POST /artworks/{id}/images":                   permMediaUpload,
+	"DELETE /artworks/{id}/images/{filename}":      permContentDelete,
+	"GET /artworks/check-title":                    permArtworksRead,
+	"POST /artworks/{id}/publish":                  permArtworksWrite,
+	"POST /artworks/{id}/unpublish":                permArtworksWrite,
+	"POST /artworks/{id}/archive":                  permContentDelete,
+	"GET /artworks/{id}/preview-links":             permArtworksRead,
+	"POST /artworks/{id}/preview-links":            permArtworksWrite,
+	"DELETE /preview-links/{linkId}":               permArtworksWrite,
+	"GET /artworks/{id}/revisions":                 permArtworksRead,
+	"GET /artworks/{id}/revisions/compare":         permArtworksRead,
+	"GET /artworks/{id}/revisions/{rev}":           permArtworksRead,
+	"POST /artworks/{id}/revisions/{rev}/rollback": permArtworksWrite,
+	"GET /artworks/{id}/provenance":                permProvenance,
+	"POST /artworks/{id}/provenance":               permProvenance,
+	"PUT /artworks/{id}/provenance/{recordId}":     permProvenance,
+	"DELETE /artworks/{id}/provenance/{recordId}":  permProvenance,
+	"GET /provenance.csv":                          permProvenance,
+	"GET /artworks/{id}/certificates":              permCertificates,
+	"POST /artworks/{id}/certificates":             permCertificates,
+	"GET /certificates/{serial}/pdf":               permCertificates,
+	"DELETE /certificates/{serial}":                permCertificates,
+	"GET /catalog.{format}":                        permArtworksRead,
+	"GET /artworks/{id}/translations":              permArtworksRead,
+	"PUT /artworks/{id}/translations/{locale}":     permArtworksWrite,
+	"DELETE /artworks/{id}/translations/{locale}":  permContentDelete,
+	"GET /translations/missing":                    permArtworksRead,
+	"GET /artworks/{id}/bitacora":                  permArtworksRead,
+	"POST /artworks/{id}/bitacora":                 permArtworksWrite,
+	"PUT /artworks/{id}/bitacora/{entryId}":        permArtworksWrite,
+	"DELETE /artworks/{id}/bitacora/{entryId}":     permContentDelete,
+	"GET /tags":                                   permArtworksRead,
+	"POST /tags":                                  permArtworksWrite,
+	"PUT /tags/{tagId}":                           permArtworksWrite,
+	"DELETE /tags/{tagId}":                        permContentDelete,
+	"PUT /artworks/{id}/tags":                     permArtworksWrite,
+	"GET /exhibitions":                            permArtworksRead,
+	"POST /exhibitions":                           permArtworksWrite,
+	"GET /exhibitions/{slug}":                     permArtworksRead,
+	"PUT /exhibitions/{slug}":                     permArtworksWrite,
+	"DELETE /exhibitions/{slug}":                  permContentDelete,
+	"PUT /exhibitions/{slug}/artworks/{id}":       permArtworksWrite,
+	"DELETE /exhibitions/{slug}/artworks/{id}":    permArtworksWrite,
+	"GET /series":                                 permArtworksRead,
+	"POST /series":                                permArtworksWrite,
+	"GET /series/{slug}":                          permArtworksRead,
+	"PUT /series/{slug}":                          permArtworksWrite,
+	"DELETE /series/{slug}":                       permContentDelete,
+	"PUT /series/{slug}/artworks/{id}":            permArtworksWrite,
+	"DELETE /series/{slug}/artworks/{id}":         permArtworksWrite,
+	"GET /api-keys":                               permAPIKeys,
+	"POST /api-keys":                              permAPIKeys,
+	"DELETE /api-keys/{keyId}":                    permAPIKeys,
+	"GET /audit":                                  permAuditRead,
+	"GET /me":                                     "",
+	"PUT /me/password":                            "",
+	"GET /users":                                  permUsersManage,
+	"POST /users":                                 permUsersManage,
+	"GET /users/{userId}":                         permUsersManage,
+	"PUT /users/{userId}":                         permUsersManage,
+	"GET /users/{userId}/sessions":                permUsersManage,
+	"DELETE /users/{userId}/sessions":             permUsersManage,
+	"DELETE /users/{userId}/sessions/{sessionId}": permUsersManage,
+}
+
+// routeVars are the values the test requests use for route variables.
+var routeVars = map[string]string{
+	"id": "obra", "filename": "a.jpg", "rev": "1", "format": "html", "linkId": "link1",
+	"recordId": "1", "serial": "AA-1", "locale": "en", "entryId": "1", "tagId": "1",
+	"slug": "serie", "keyId": "key1", "userId": "1", "sessionId": "sess1",
+}
+
+// adminTestRouter serves the admin routes to identity, without
+// authentication or audit.
+func adminTestRouter(t *testing.T, identity *adminIdentity) *mux.Router {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "obra"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "obra", "a.jpg"), []byte("jpeg"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	prev := artworksDir
+	artworksDir = dir
+	t.Cleanup(func() { artworksDir = prev })
+
+	r := mux.NewRouter()
+	admin := r.PathPrefix("/api/v1/admin").Subrouter()
+	admin.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityContextKey, *identity)))
+		})
+	})
+	registerAdminRoutes(admin)
+	return r
+}
+
+// adminRoutes lists every registered admin route as "METHOD template" with a
+// request path for it.
+func adminRoutes(t *testing.T, r *mux.Router) map[string]string {
+	t.Helper()
+	routes := map[string]string{}
+	err := r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil // the subrouter
+		}
+		tmpl, _ := route.GetPathTemplate()
+		names, _ := route.GetVarNames()
+		var pairs []string
+		for _, name := range names {
+			pairs = append(pairs, name, routeVars[name])
+		}
+		u, err := route.URL(pairs...)
+		if err != nil {
+			return err
+		}
+		key := strings.TrimPrefix(tmpl, "/api/v1/admin")
+		for _, name := range names {
+			// "{rev:[0-9]+}" -> "{rev}"
+			if i := strings.Index(key, "{"+name+":"); i >= 0 {
+				end := strings.Index(key[i:], "}")
+				key = key[:i] + "{" + name + "}" + key[i+end+1:]
+			}
+		}
+		for _, m := range methods {
+			routes[m+" "+key] = u.Path
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return routes
+}
+
+// serveWithoutDatabase serves req, treating a panic from a handler that
+// reached the nil pgPool as having got past the permission check.
+func serveWithoutDatabase(h http.Handler, w *httptest.ResponseRecorder, req *http.Request) {
+	defer func() {
+		if recover() != nil {
+			w.Code = http.StatusInternalServerError
+		}
+	}()
+	h.ServeHTTP(w, req)
+}
+
+func TestAdminRoutePermissions(t *testing.T) {
+	identities := map[string]adminIdentity{
+		"viewer":               {UserID: 1, Email: "v@example.com", Role: roleViewer},
+		"editor":               {UserID: 2, Email: "e@example.com", Role: roleEditor},
+		"owner":                {UserID: 3, Email: "o@example.com", Role: roleOwner},
+		"key read:drafts":      {APIKeyID: "k1", Name: "k1", Scopes: []string{scopeReadDrafts}},
+		"key write:media":      {APIKeyID: "k2", Name: "k2", Scopes: []string{scopeWriteMedia}},
+		"key write:metadata":   {APIKeyID: "k3", Name: "k3", Scopes: []string{scopeWriteMetadata}},
+		"key without scopes":   {APIKeyID: "k4", Name: "k4"},
+		"key unknown scope":    {APIKeyID: "k5", Name: "k5", Scopes: []string{"admin"}},
+		"account without role": {UserID: 4, Email: "n@example.com"},
+	}
+
+	for name, identity := range identities {
+		t.Run(name, func(t *testing.T) {
+			r := adminTestRouter(t, &identity)
+			routes := adminRoutes(t, r)
+			for route := range routes {
+				if _, ok := routePermissions[route]; !ok {
+					t.Errorf("route %s is not in routePermissions", route)
+				}
+			}
+			for route, perm := range routePermissions {
+				path, ok := routes[route]
+				if !ok {
+					t.Errorf("route %s is not registered", route)
+					continue
+				}
+				method, _, _ := strings.Cut(route, " ")
+				req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+				w := httptest.NewRecorder()
+				serveWithoutDatabase(r, w, req)
+
+				allowed := perm == "" || identity.can(perm)
+				var resp ErrorResponse
+				json.Unmarshal(w.Body.Bytes(), &resp)
+				denied := w.Code == http.StatusForbidden && resp.MissingPermission == string(perm)
+				if allowed && denied {
+					t.Errorf("%s: denied, want allowed", route)
+				}
+				if !allowed && !denied {
+					t.Errorf("%s: status %d %s, want 403 missing %s", route, w.Code, w.Body, perm)
+				}
+			}
+		})
+	}
+}
+
+func TestRolePermissions(t *testing.T) {
+	all := []permission{permDraftsRead, permArtworksRead, permArtworksWrite, permMediaUpload, permContentDelete,
+		permSalesWrite, permProvenance, permCertificates, permUsersManage, permAPIKeys, permAuditRead}
+	want := map[string][]permission{
+		roleViewer: {permDraftsRead, permArtworksRead},
+		roleEditor: {permDraftsRead, permArtworksRead, permArtworksWrite, permMediaUpload},
+		roleOwner:  all,
+	}
+	for _, role := range roles {
+		for _, perm := range all {
+			id := adminIdentity{UserID: 1, Role: role}
+			if got := id.can(perm); got != slices.Contains(want[role], perm) {
+				t.Errorf("%s can %s = %v, want %v", role, perm, got, !got)
+			}
+		}
+	}
+	// ADMIN_TOKEN has no account and acts as owner.
+	if !(adminIdentity{Role: roleOwner}).can(permUsersManage) {
+		t.Error("ADMIN_TOKEN cannot manage users")
+	}
+}
+
+// Price and availability need sales:write on top of artworks:write.
+func TestSalesWritePermission(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity adminIdentity
+		body     string
+		want     int
+	}{
+		{"editor without sales", adminIdentity{UserID: 2, Role: roleEditor}, `{"technique": "óleo"}`, http.StatusOK},
+		{"editor sets price", adminIdentity{UserID: 2, Role: roleEditor}, `{"price": {"amount": "100", "currency": "EUR"}}`, http.StatusForbidden},
+		{"editor sets availability", adminIdentity{UserID: 2, Role: roleEditor}, `{"availability": "sold"}`, http.StatusForbidden},
+		{"metadata key sets price", adminIdentity{APIKeyID: "k3", Scopes: []string{scopeWriteMetadata}}, `{"price": {"amount": "100"}}`, http.StatusForbidden},
+		{"owner sets price", adminIdentity{UserID: 3, Role: roleOwner}, `{"price": {"amount": "100", "currency": "EUR"}}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := adminTestRouter(t, &tt.identity)
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/admin/artworks/obra", strings.NewReader(tt.body))
+			req.Header.Set("If-Match", "*")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if w.Code == http.StatusForbidden {
+				var resp ErrorResponse
+				json.Unmarshal(w.Body.Bytes(), &resp)
+				if resp.MissingPermission != string(permSalesWrite) {
+					t.Errorf("missingPermission = %q, want %s", resp.MissingPermission, permSalesWrite)
+				}
+			}
+		})
+	}
+}
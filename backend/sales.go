@@ -0,0 +1,254 @@
+package main
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"alexis-art-backend/db"
+	"alexis-art-backend/money"
+)
+
+// Availability of an artwork for sale.
+const (
+	availabilityAvailable         = "available"
+	availabilityReserved          = "reserved"
+	availabilitySold              = "sold"
+	availabilityNotForSale        = "not_for_sale"
+	availabilityPrivateCollection = "private_collection"
+)
+
+var availabilities = []string{
+	availabilityAvailable,
+	availabilityReserved,
+	availabilitySold,
+	availabilityNotForSale,
+	availabilityPrivateCollection,
+}
+
+const (
+	maxTechniqueLength = 200
+	maxDimension       = 100000
+	maxPrice           = 100000000 // in whole units of the currency
+
+	defaultDimensionUnit = "cm"
+	defaultCurrency      = "EUR"
+)
+
+var dimensionUnits = []string{"cm", "in"}
+
+// Dimensions are the physical size of a work; any of them may be unknown.
+type Dimensions struct {
+	Height *float64 `json:"height,omitempty"`
+	Width  *float64 `json:"width,omitempty"`
+	Depth  *float64 `json:"depth,omitempty"`
+	Unit   string   `json:"unit,omitempty"`
+}
+
+type Price struct {
+	Amount   money.Amount `json:"amount"`
+	Currency string       `json:"currency"` // ISO 4217, e.g. EUR
+}
+
+func (d *Dimensions) empty() bool {
+	return d == nil || (d.Height == nil && d.Width == nil && d.Depth == nil)
+}
+
+// normalizeSales trims the sales fields and fills in default units, so the
+// same value is stored in meta.json, Postgres and the revision history.
+func (p *adminArtworkUpdate) normalizeSales() {
+	p.Technique = strings.TrimSpace(p.Technique)
+	p.Availability = strings.TrimSpace(p.Availability)
+	if p.Dimensions.empty() {
+		p.Dimensions = nil
+	} else {
+		d := *p.Dimensions
+		d.Unit = strings.ToLower(strings.TrimSpace(d.Unit))
+		if d.Unit == "" {
+			d.Unit = defaultDimensionUnit
+		}
+		p.Dimensions = &d
+	}
+	if p.Price != nil {
+		price := *p.Price
+		price.Currency = strings.ToUpper(strings.TrimSpace(price.Currency))
+		if price.Currency == "" {
+			price.Currency = defaultCurrency
+		}
+		p.Price = &price
+	}
+}
+
+// sameSales reports whether both updates have the same price and
+// availability.
+func (p adminArtworkUpdate) sameSales(o adminArtworkUpdate) bool {
+	if p.Availability != o.Availability {
+		return false
+	}
+	if p.Price == nil || o.Price == nil {
+		return p.Price == o.Price
+	}
+	return *p.Price == *o.Price
+}
+
+func (v *validationErrors) checkSales(p adminArtworkUpdate) {
+	v.checkLength("technique", strings.TrimSpace(p.Technique), maxTechniqueLength)
+
+	if d := p.Dimensions; d != nil {
+		for _, dim := range []struct {
+			field string
+			value *float64
+		}{
+			{"dimensions.height", d.Height},
+			{"dimensions.width", d.Width},
+			{"dimensions.depth", d.Depth},
+		} {
+			if dim.value != nil && (*dim.value <= 0 || *dim.value > maxDimension) {
+				v.add(dim.field, codeInvalidValue, "must be a positive number")
+			}
+		}
+		if unit := strings.ToLower(strings.TrimSpace(d.Unit)); unit != "" && !slices.Contains(dimensionUnits, unit) {
+			v.add("dimensions.unit", codeInvalidValue, "must be one of: "+strings.Join(dimensionUnits, ", "))
+		}
+	}
+
+	if p.Price != nil {
+		if p.Price.Amount < 0 || p.Price.Amount > maxPrice*100 {
+			v.add("price.amount", codeInvalidValue, "must be between 0 and "+strconv.Itoa(maxPrice))
+		}
+		if c := strings.TrimSpace(p.Price.Currency); c != "" && !isCurrencyCode(c) {
+			v.add("price.currency", codeInvalidValue, "must be a 3-letter ISO 4217 code")
+		}
+	}
+
+	if a := strings.TrimSpace(p.Availability); a != "" && !slices.Contains(availabilities, a) {
+		v.add("availability", codeInvalidValue, "must be one of: "+strings.Join(availabilities, ", "))
+	}
+}
+
+func isCurrencyCode(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for _, c := range strings.ToUpper(s) {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// salesRowFields copies the sales fields of a payload into a DB row.
+func salesRowFields(row *db.ArtworkRow, p adminArtworkUpdate) {
+	row.Technique = p.Technique
+	row.Availability = p.Availability
+	if d := p.Dimensions; d != nil {
+		row.Height, row.Width, row.Depth = d.Height, d.Width, d.Depth
+		row.DimensionUnit = d.Unit
+	}
+	if p.Price != nil {
+		amount := int64(p.Price.Amount)
+		row.Price = &amount
+		row.Currency = p.Price.Currency
+	}
+}
+
+// overlaySalesRow applies the sales fields stored in Postgres; unset columns
+// keep the values read from meta.json.
+func overlaySalesRow(artwork *Artwork, row *db.ArtworkRow) {
+	if row.Technique != "" {
+		artwork.Technique = row.Technique
+	}
+	if row.Height != nil || row.Width != nil || row.Depth != nil {
+		artwork.Dimensions = &Dimensions{Height: row.Height, Width: row.Width, Depth: row.Depth, Unit: row.DimensionUnit}
+	}
+	if row.Price != nil {
+		artwork.Price = &Price{Amount: money.Amount(*row.Price), Currency: row.Currency}
+	}
+	if row.Availability != "" {
+		artwork.Availability = row.Availability
+	}
+}
+
+// publicView hides what should not be public: the price of a work that is
+// not on the market (sold, in a private collection or not for sale).
+func (a Artwork) publicView() Artwork {
+	if a.Availability != "" && a.Availability != availabilityAvailable && a.Availability != availabilityReserved {
+		a.Price = nil
+	}
+	return a
+}
+
+// salesFilter selects artworks for the public list:
+// ?availability=available,reserved&technique=óleo&minPrice=100&maxPrice=900&currency=EUR
+type salesFilter struct {
+	availability []string
+	technique    string
+	minPrice     *money.Amount
+	maxPrice     *money.Amount
+	currency     string
+}
+
+// parseSalesFilter reads the filter from the query string; the returned
+// string describes the first invalid parameter.
+func parseSalesFilter(q url.Values) (salesFilter, string) {
+	var f salesFilter
+	if v := strings.TrimSpace(q.Get("availability")); v != "" {
+		for _, a := range strings.Split(v, ",") {
+			a = strings.TrimSpace(a)
+			if !slices.Contains(availabilities, a) {
+				return f, "Invalid availability: must be one of " + strings.Join(availabilities, ", ")
+			}
+			f.availability = append(f.availability, a)
+		}
+	}
+	f.technique = strings.ToLower(strings.TrimSpace(q.Get("technique")))
+	for _, p := range []struct {
+		name string
+		dst  **money.Amount
+	}{{"minPrice", &f.minPrice}, {"maxPrice", &f.maxPrice}} {
+		v := strings.TrimSpace(q.Get(p.name))
+		if v == "" {
+			continue
+		}
+		n, err := money.Parse(v)
+		if err != nil || n < 0 {
+			return f, "Invalid " + p.name
+		}
+		*p.dst = &n
+	}
+	if c := strings.TrimSpace(q.Get("currency")); c != "" {
+		if !isCurrencyCode(c) {
+			return f, "Invalid currency"
+		}
+		f.currency = strings.ToUpper(c)
+	}
+	return f, ""
+}
+
+// match is applied to the public view, so price filters never reveal the
+// price of a sold work.
+func (f salesFilter) match(a Artwork) bool {
+	if len(f.availability) > 0 && !slices.Contains(f.availability, a.Availability) {
+		return false
+	}
+	if f.technique != "" && !strings.Contains(strings.ToLower(a.Technique), f.technique) {
+		return false
+	}
+	if f.minPrice != nil || f.maxPrice != nil || f.currency != "" {
+		if a.Price == nil {
+			return false
+		}
+		if f.currency != "" && a.Price.Currency != f.currency {
+			return false
+		}
+		if f.minPrice != nil && a.Price.Amount < *f.minPrice {
+			return false
+		}
+		if f.maxPrice != nil && a.Price.Amount > *f.maxPrice {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+
+	"alexis-art-backend/db"
+)
+
+const (
+	maxSlugLength        = 100
+	maxDescriptionLength = 5000
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+var stripAccents = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// slugify turns a title into a URL slug: "Paisajes del Sur" -> "paisajes-del-sur".
+func slugify(s string) string {
+	s, _, _ = transform.String(stripAccents, strings.ToLower(s))
+	var b strings.Builder
+	dash := false
+	for _, r := range s {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	slug := b.String()
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimRight(slug[:maxSlugLength], "-")
+	}
+	return slug
+}
+
+type adminSeriesPayload struct {
+	Slug           string `json:"slug"`
+	Title          string `json:"title"`
+	Description    string `json:"description"`
+	CoverArtworkID string `json:"coverArtworkId"`
+	CoverImage     string `json:"coverImage"`
+	SortOrder      int    `json:"sortOrder"`
+	// Omitted leaves the artworks of the series unchanged.
+	ArtworkIDs *[]string `json:"artworkIds"`
+}
+
+type SeriesResponse struct {
+	Slug           string    `json:"slug"`
+	Title          string    `json:"title"`
+	Description    string    `json:"description,omitempty"`
+	CoverArtworkID string    `json:"coverArtworkId,omitempty"`
+	CoverImage     string    `json:"coverImage,omitempty"`
+	CoverURL       string    `json:"coverUrl,omitempty"`
+	SortOrder      int       `json:"sortOrder"`
+	ArtworkIDs     []string  `json:"artworkIds"`
+	ArtworkCount   int       `json:"artworkCount"`
+	Artworks       []Artwork `json:"artworks,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+type SeriesListResponse struct {
+	Series []SeriesResponse `json:"series"`
+	Total  int              `json:"total"`
+}
+
+func (p *adminSeriesPayload) normalize() {
+	p.Title = strings.TrimSpace(p.Title)
+	p.Slug = strings.TrimSpace(p.Slug)
+	if p.Slug == "" {
+		p.Slug = slugify(p.Title)
+	}
+	p.CoverArtworkID = strings.TrimSpace(p.CoverArtworkID)
+	p.CoverImage = strings.TrimSpace(p.CoverImage)
+	if p.ArtworkIDs != nil {
+		ids := make([]string, 0, len(*p.ArtworkIDs))
+		for _, id := range *p.ArtworkIDs {
+			ids = append(ids, strings.TrimSpace(id))
+		}
+		p.ArtworkIDs = &ids
+	}
+}
+
+func (p adminSeriesPayload) validate() error {
+	var errs validationErrors
+	if p.Title == "" {
+		errs.add("title", codeRequired, "is required")
+	}
+	errs.checkLength("title", p.Title, maxTitleLength)
+	if !slugPattern.MatchString(p.Slug) || len(p.Slug) > maxSlugLength {
+		errs.add("slug", codeInvalidValue, "must be lowercase letters, digits and dashes (at most 100)")
+	}
+	errs.checkLength("description", p.Description, maxDescriptionLength)
+	if p.CoverArtworkID != "" && !isSafeArtworkID(p.CoverArtworkID) {
+		errs.add("coverArtworkId", codeInvalidValue, "must be an artwork id")
+	}
+	if p.CoverImage != "" {
+		if p.CoverArtworkID == "" {
+			errs.add("coverImage", codeInconsistent, "requires coverArtworkId")
+		} else if !isSafeArtworkID(p.CoverImage) {
+			errs.add("coverImage", codeInvalidValue, "must be a filename of one of the artwork images")
+		}
+	}
+	if p.ArtworkIDs != nil {
+		seen := map[string]bool{}
+		for _, id := range *p.ArtworkIDs {
+			if !isSafeArtworkID(id) {
+				errs.add("artworkIds", codeInvalidValue, "contains an invalid artwork id")
+				break
+			}
+			if seen[id] {
+				errs.add("artworkIds", codeInvalidValue, "contains "+id+" more than once")
+				break
+			}
+			seen[id] = true
+		}
+	}
+	return errs.err()
+}
+
+func imageURL(artworkID, filename string) string {
+	return "/api/v1/artworks/" + url.PathEscape(artworkID) + "/images/" + url.PathEscape(filename)
+}
+
+// seriesResponse builds the response for a series. When hidden is not nil
+// (public responses), artworks that are not public are left out, and so is a
+// cover taken from one of them.
+func seriesResponse(row db.SeriesRow, hidden map[string]bool) SeriesResponse {
+	resp := SeriesResponse{
+		Slug:           row.Slug,
+		Title:          row.Title,
+		Description:    row.Description,
+		CoverArtworkID: row.CoverArtworkID,
+		CoverImage:     row.CoverImage,
+		SortOrder:      row.SortOrder,
+		ArtworkIDs:     make([]string, 0, len(row.ArtworkIDs)),
+		CreatedAt:      row.CreatedAt,
+		UpdatedAt:      row.UpdatedAt,
+	}
+	for _, id := range row.ArtworkIDs {
+		if !hidden[id] {
+			resp.ArtworkIDs = append(resp.ArtworkIDs, id)
+		}
+	}
+	resp.ArtworkCount = len(resp.ArtworkIDs)
+	if hidden[resp.CoverArtworkID] {
+		resp.CoverArtworkID, resp.CoverImage = "", ""
+	}
+	if resp.CoverArtworkID != "" && resp.CoverImage != "" {
+		resp.CoverURL = imageURL(resp.CoverArtworkID, resp.CoverImage)
+	}
+	return resp
+}
+
+// withDefaultCovers uses the primary image of the first artwork for the
+// series that have no cover of their own. Storage is scanned once, and only
+// if some series needs it.
+func withDefaultCovers(ctx context.Context, list []SeriesResponse) {
+	var byID map[string]Artwork
+	for i := range list {
+		s := &list[i]
+		if s.CoverURL != "" || len(s.ArtworkIDs) == 0 {
+			continue
+		}
+		if byID == nil {
+			artworks, err := scanArtworks(ctx)
+			if err != nil {
+				return
+			}
+			byID = make(map[string]Artwork, len(artworks))
+			for _, a := range artworks {
+				byID[a.ID] = a
+			}
+		}
+		if a, ok := byID[s.ArtworkIDs[0]]; ok && a.PrimaryImage != "" {
+			s.CoverURL = imageURL(a.ID, a.PrimaryImage)
+		}
+	}
+}
+
+func getSeriesList(w http.ResponseWriter, r *http.Request) {
+	list := []SeriesResponse{}
+	if pgPool != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		defer cancel()
+		rows, err := db.ListSeries(ctx, pgPool)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to list series")
+			return
+		}
+		hidden, err := db.HiddenArtworkIDs(ctx, pgPool)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to list series")
+			return
+		}
+		for _, row := range rows {
+			list = append(list, seriesResponse(row, hidden))
+		}
+		withDefaultCovers(r.Context(), list)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SeriesListResponse{Series: list, Total: len(list)})
+}
+
+// getSeries returns a series with its public artworks, in series order.
+func getSeries(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusNotFound, "Series not found")
+		return
+	}
+	format, ok := parseTextFormat(r.URL.Query())
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, invalidTextFormatMessage())
+		return
+	}
+	slug := mux.Vars(r)["slug"]
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	row, err := db.GetSeries(ctx, pgPool, slug)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to read series")
+		return
+	}
+	if row == nil {
+		respondWithError(w, http.StatusNotFound, "Series not found")
+		return
+	}
+	hidden, err := db.HiddenArtworkIDs(ctx, pgPool)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to read series")
+		return
+	}
+
+	resp := seriesResponse(*row, hidden)
+	resp.Artworks = []Artwork{}
+	for _, id := range resp.ArtworkIDs {
+		a, err := getArtworkByID(r.Context(), id)
+		if err != nil || !a.isPublic() {
+			// Deleted from storage since it was added to the series.
+			continue
+		}
+		resp.Artworks = append(resp.Artworks, a.publicView())
+	}
+	locale := requestLocale(r)
+	localizeArtworks(r.Context(), locale, resp.Artworks)
+	renderArtworkTexts(resp.Artworks, format)
+	if resp.CoverURL == "" && len(resp.Artworks) > 0 && resp.Artworks[0].PrimaryImage != "" {
+		resp.CoverURL = imageURL(resp.Artworks[0].ID, resp.Artworks[0].PrimaryImage)
+	}
+
+	setLocaleHeaders(w, locale)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func adminListSeries(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	rows, err := db.ListSeries(ctx, pgPool)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list series")
+		return
+	}
+
+	list := make([]SeriesResponse, 0, len(rows))
+	for _, row := range rows {
+		list = append(list, seriesResponse(row, nil))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SeriesListResponse{Series: list, Total: len(list)})
+}
+
+func adminGetSeries(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	row, err := db.GetSeries(ctx, pgPool, mux.Vars(r)["slug"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to read series")
+		return
+	}
+	if row == nil {
+		respondWithError(w, http.StatusNotFound, "Series not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(seriesResponse(*row, nil))
+}
+
+func adminCreateSeries(w http.ResponseWriter, r *http.Request) {
+	saveSeries(w, r, "")
+}
+
+func adminUpdateSeries(w http.ResponseWriter, r *http.Request) {
+	saveSeries(w, r, mux.Vars(r)["slug"])
+}
+
+var seriesCollection = artworkCollection[db.SeriesRow]{
+	name:           "Series",
+	noun:           "series",
+	revisionAction: revisionActionSeries,
+	get:            db.GetSeries,
+	lock:           db.LockSeries,
+	insert:         db.InsertSeries,
+	update: func(ctx context.Context, q db.Querier, id int64, row db.SeriesRow) error {
+		row.ID = id
+		return db.UpdateSeries(ctx, q, row)
+	},
+	setArtworks:   db.SetSeriesArtworks,
+	addArtwork:    db.AddSeriesArtwork,
+	removeArtwork: db.RemoveSeriesArtwork,
+	slug:          func(row db.SeriesRow) string { return row.Slug },
+	artworkIDs:    func(row db.SeriesRow) []string { return row.ArtworkIDs },
+	response:      func(row db.SeriesRow) any { return seriesResponse(row, nil) },
+}
+
+// saveSeries creates a series (slug == "") or replaces the fields of an
+// existing one, together with its artworks when the payload lists them.
+func saveSeries(w http.ResponseWriter, r *http.Request, slug string) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	var payload adminSeriesPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	payload.normalize()
+	if err := payload.validate(); err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+
+	seriesCollection.save(w, r, slug, db.SeriesRow{
+		Slug:           payload.Slug,
+		Title:          payload.Title,
+		Description:    payload.Description,
+		CoverArtworkID: payload.CoverArtworkID,
+		CoverImage:     payload.CoverImage,
+		SortOrder:      payload.SortOrder,
+	}, payload.ArtworkIDs)
+}
+
+func adminDeleteSeries(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	deleted, err := db.DeleteSeries(ctx, pgPool, mux.Vars(r)["slug"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete series")
+		return
+	}
+	if !deleted {
+		respondWithError(w, http.StatusNotFound, "Series not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// adminAddSeriesArtwork appends an artwork to a series (no-op if present).
+func adminAddSeriesArtwork(w http.ResponseWriter, r *http.Request) {
+	seriesCollection.changeArtwork(w, r, true)
+}
+
+func adminRemoveSeriesArtwork(w http.ResponseWriter, r *http.Request) {
+	seriesCollection.changeArtwork(w, r, false)
+}
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
+	"net/http"
 	"net/url"
 	"os"
 	"path"
@@ -15,11 +17,12 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 
-	"alexis-art-backend/db"
+	"alexis-art-backend/media"
 )
 
 type s3ArtworksStore struct {
@@ -33,6 +36,10 @@ type s3ArtworksStore struct {
 
 	// If publicBaseURL is empty, we presign for this TTL.
 	presignTTL time.Duration
+
+	// If set, media routes stream objects through the backend instead of
+	// redirecting to a (short-lived) presigned/public URL.
+	proxyMedia bool
 }
 
 func envAny(keys ...string) string {
@@ -87,6 +94,10 @@ func newS3ArtworksStoreFromEnv() (*s3ArtworksStore, error) {
 		}
 	}
 
+	// ARTWORKS_MEDIA_MODE=proxy streams media through the backend (stable URLs,
+	// cacheable by browsers/CDNs); anything else keeps the redirect behavior.
+	proxyMedia := strings.EqualFold(strings.TrimSpace(os.Getenv("ARTWORKS_MEDIA_MODE")), "proxy")
+
 	var cfg aws.Config
 	var err error
 	if accessKey != "" && secretKey != "" {
@@ -116,6 +127,7 @@ func newS3ArtworksStoreFromEnv() (*s3ArtworksStore, error) {
 		bucket:        bucket,
 		publicBaseURL: publicBaseURL,
 		presignTTL:    time.Duration(ttlSeconds) * time.Second,
+		proxyMedia:    proxyMedia,
 	}, nil
 }
 
@@ -192,7 +204,7 @@ func (s *s3ArtworksStore) listArtworkIDs(ctx context.Context) ([]string, error)
 	return ids, nil
 }
 
-func (s *s3ArtworksStore) scanArtwork(ctx context.Context, id string) (Artwork, error) {
+func (s *s3ArtworksStore) scanArtwork(ctx context.Context, id string, overlay *artworkOverlay) (Artwork, error) {
 	artwork := Artwork{
 		ID:     id,
 		Title:  formatTitle(id),
@@ -243,16 +255,7 @@ func (s *s3ArtworksStore) scanArtwork(ctx context.Context, id string) (Artwork,
 				if err := json.Unmarshal(b, &m); err != nil {
 					continue
 				}
-				if artwork.PaintedLocation == "" {
-					artwork.PaintedLocation = strings.TrimSpace(m.PaintedLocation)
-				}
-				if artwork.StartDate == "" {
-					artwork.StartDate = strings.TrimSpace(m.StartDate)
-				}
-				if artwork.EndDate == "" {
-					artwork.EndDate = strings.TrimSpace(m.EndDate)
-				}
-				artwork.InProgress = m.InProgress
+				m.applyTo(&artwork)
 			case ".txt", ".md":
 				b, err := s.getObjectBytes(ctx, key, 1<<20)
 				if err != nil {
@@ -282,38 +285,7 @@ func (s *s3ArtworksStore) scanArtwork(ctx context.Context, id string) (Artwork,
 	sort.Strings(artwork.Videos)
 
 	// Overlay DB fields if enabled (same behavior as disk).
-	if pgPool != nil {
-		ctxDB, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-		row, err := db.GetArtwork(ctxDB, pgPool, id)
-		if err == nil && row != nil {
-			if row.Title != "" {
-				artwork.Title = row.Title
-			}
-			artwork.PaintedLocation = row.PaintedLocation
-			if row.StartDate != nil {
-				artwork.StartDate = row.StartDate.Format("2006-01-02")
-			}
-			if row.EndDate != nil {
-				artwork.EndDate = row.EndDate.Format("2006-01-02")
-			}
-			artwork.InProgress = row.InProgress
-			if row.Detalle != "" {
-				artwork.Detalle = row.Detalle
-			}
-			if row.Bitacora != "" {
-				artwork.Bitacora = row.Bitacora
-			}
-			if row.PrimaryImage != "" {
-				artwork.PrimaryImage = row.PrimaryImage
-			}
-		}
-	}
-
-	// Default primary image to first image if not set
-	if artwork.PrimaryImage == "" && len(artwork.Images) > 0 {
-		artwork.PrimaryImage = artwork.Images[0]
-	}
+	overlayArtworkRow(&artwork, overlay)
 
 	return artwork, nil
 }
@@ -323,9 +295,10 @@ func (s *s3ArtworksStore) scanArtworks(ctx context.Context) ([]Artwork, error) {
 	if err != nil {
 		return nil, err
 	}
+	overlay := loadArtworkOverlay(ctx, "")
 	var out []Artwork
 	for _, id := range ids {
-		a, err := s.scanArtwork(ctx, id)
+		a, err := s.scanArtwork(ctx, id, overlay)
 		if err != nil {
 			continue
 		}
@@ -336,6 +309,35 @@ func (s *s3ArtworksStore) scanArtworks(ctx context.Context) ([]Artwork, error) {
 	return out, nil
 }
 
+// fingerprint writes key, ETag and size of every object under prefix to w
+// and returns the most recent LastModified. It only lists, never downloads.
+func (s *s3ArtworksStore) fingerprint(ctx context.Context, prefix string, w io.Writer) (time.Time, error) {
+	var last time.Time
+	var token *string
+	for {
+		out, err := s.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(s.bucket),
+			Prefix:            aws.String(prefix),
+			ContinuationToken: token,
+		})
+		if err != nil {
+			return last, err
+		}
+		for _, obj := range out.Contents {
+			fmt.Fprintf(w, "%s:%s:%d\n", aws.ToString(obj.Key), aws.ToString(obj.ETag), aws.ToInt64(obj.Size))
+			if obj.LastModified != nil && obj.LastModified.After(last) {
+				last = *obj.LastModified
+			}
+		}
+		if aws.ToBool(out.IsTruncated) && out.NextContinuationToken != nil {
+			token = out.NextContinuationToken
+			continue
+		}
+		break
+	}
+	return last, nil
+}
+
 func (s *s3ArtworksStore) keyFor(id, filename string) (string, error) {
 	if !isSafeArtworkID(id) {
 		return "", errors.New("invalid artwork id")
@@ -357,6 +359,88 @@ func (s *s3ArtworksStore) objectURL(ctx context.Context, id, filename string) (s
 	return s.presignedURL(ctx, key)
 }
 
+// serveObject streams an artwork file from the bucket, forwarding the
+// conditional/range headers to S3 and the validators back to the client.
+func (s *s3ArtworksStore) serveObject(w http.ResponseWriter, r *http.Request, id, filename, cacheControl string) {
+	key, err := s.keyFor(id, filename)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid path")
+		return
+	}
+
+	in := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+	// S3 has no If-Range support: when present, ignore Range and send the full
+	// object, which is always a valid answer.
+	if v := r.Header.Get("Range"); v != "" && r.Header.Get("If-Range") == "" {
+		in.Range = aws.String(v)
+	}
+	if v := r.Header.Get("If-None-Match"); v != "" {
+		in.IfNoneMatch = aws.String(v)
+	} else if v := r.Header.Get("If-Modified-Since"); v != "" {
+		if t, err := http.ParseTime(v); err == nil {
+			in.IfModifiedSince = aws.Time(t)
+		}
+	}
+
+	out, err := s.client.GetObject(r.Context(), in)
+	if err != nil {
+		var re *awshttp.ResponseError
+		if !errors.As(err, &re) {
+			respondWithError(w, http.StatusBadGateway, "Failed to read object")
+			return
+		}
+		switch re.HTTPStatusCode() {
+		case http.StatusNotModified:
+			if etag := re.Response.Header.Get("ETag"); etag != "" {
+				w.Header().Set("ETag", etag)
+			}
+			w.Header().Set("Cache-Control", cacheControl)
+			w.WriteHeader(http.StatusNotModified)
+		case http.StatusNotFound:
+			respondWithError(w, http.StatusNotFound, "File not found")
+		case http.StatusRequestedRangeNotSatisfiable:
+			if cr := re.Response.Header.Get("Content-Range"); cr != "" {
+				w.Header().Set("Content-Range", cr)
+			}
+			respondWithError(w, http.StatusRequestedRangeNotSatisfiable, "Range not satisfiable")
+		default:
+			respondWithError(w, http.StatusBadGateway, "Failed to read object")
+		}
+		return
+	}
+	defer out.Body.Close()
+
+	h := w.Header()
+	h.Set("Content-Type", media.ContentType(filename))
+	if out.ContentLength != nil {
+		h.Set("Content-Length", strconv.FormatInt(*out.ContentLength, 10))
+	}
+	if etag := aws.ToString(out.ETag); etag != "" {
+		h.Set("ETag", etag)
+	}
+	if out.LastModified != nil {
+		h.Set("Last-Modified", out.LastModified.UTC().Format(http.TimeFormat))
+	}
+	h.Set("Accept-Ranges", "bytes")
+	h.Set("Cache-Control", cacheControl)
+
+	status := http.StatusOK
+	if cr := aws.ToString(out.ContentRange); cr != "" {
+		h.Set("Content-Range", cr)
+		status = http.StatusPartialContent
+	}
+	w.WriteHeader(status)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(w, out.Body); err != nil {
+		log.Printf("Streaming %s failed: %v", key, err)
+	}
+}
+
 func (s *s3ArtworksStore) putObject(ctx context.Context, key string, body io.Reader, contentType string) error {
 	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
 		Bucket:      aws.String(s.bucket),
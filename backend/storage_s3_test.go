@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// fakeS3 serves a single object the way S3 does for GetObject: Range is
+// honoured, and errors come back as XML.
+func fakeS3(t *testing.T, bucket, key string, content []byte) *httptest.Server {
+	t.Helper()
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+bucket+"/"+key {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+			return
+		}
+		rec := httptest.NewRecorder()
+		http.ServeContent(rec, r, key, modified, bytes.NewReader(content))
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.Header().Set("ETag", `"abc123"`)
+		if rec.Code == http.StatusRequestedRangeNotSatisfiable {
+			w.Header().Set("Content-Type", "application/xml")
+			w.Header().Del("Content-Length")
+			w.WriteHeader(rec.Code)
+			w.Write([]byte(`<Error><Code>InvalidRange</Code><Message>The requested range is not satisfiable</Message></Error>`))
+			return
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	}))
+}
+
+func TestServeObjectRanges(t *testing.T) {
+	srv := fakeS3(t, "art", "obra/clip.mp4", rangeTestContent)
+	defer srv.Close()
+
+	store := &s3ArtworksStore{
+		bucket:     "art",
+		proxyMedia: true,
+		client: s3.New(s3.Options{
+			Region:       "us-east-1",
+			BaseEndpoint: aws.String(srv.URL),
+			UsePathStyle: true,
+			Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		}),
+	}
+
+	serve := func(filename, rangeHeader string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/artworks/obra/videos/"+filename, nil)
+		if rangeHeader != "" {
+			r.Header.Set("Range", rangeHeader)
+		}
+		w := httptest.NewRecorder()
+		store.serveObject(w, r, "obra", filename, "public, max-age=60")
+		return w
+	}
+
+	t.Run("full", func(t *testing.T) {
+		w := serve("clip.mp4", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", w.Code)
+		}
+		if got := w.Header().Get("Accept-Ranges"); got != "bytes" {
+			t.Errorf("Accept-Ranges = %q, want bytes", got)
+		}
+		if got := w.Header().Get("Cache-Control"); got != "public, max-age=60" {
+			t.Errorf("Cache-Control = %q", got)
+		}
+		if !bytes.Equal(w.Body.Bytes(), rangeTestContent) {
+			t.Errorf("body = %q, want the whole object", w.Body.String())
+		}
+	})
+
+	t.Run("range", func(t *testing.T) {
+		w := serve("clip.mp4", "bytes=10-19")
+		if w.Code != http.StatusPartialContent {
+			t.Fatalf("status = %d, want 206", w.Code)
+		}
+		if got := w.Header().Get("Content-Range"); got != "bytes 10-19/100" {
+			t.Errorf("Content-Range = %q, want bytes 10-19/100", got)
+		}
+		if got := w.Header().Get("Content-Length"); got != "10" {
+			t.Errorf("Content-Length = %q, want 10", got)
+		}
+		if got := w.Body.String(); got != string(rangeTestContent[10:20]) {
+			t.Errorf("body = %q, want %q", got, rangeTestContent[10:20])
+		}
+	})
+
+	t.Run("unsatisfiable", func(t *testing.T) {
+		w := serve("clip.mp4", "bytes=200-300")
+		if w.Code != http.StatusRequestedRangeNotSatisfiable {
+			t.Fatalf("status = %d, want 416", w.Code)
+		}
+		if got := w.Header().Get("Content-Range"); got != "bytes */100" {
+			t.Errorf("Content-Range = %q, want bytes */100", got)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		w := serve("nope.mp4", "")
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("status = %d, want 404", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "File not found") {
+			t.Errorf("body = %q", w.Body.String())
+		}
+	})
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"alexis-art-backend/db"
+)
+
+// Kinds of tags.
+const (
+	tagKindTheme     = "theme"
+	tagKindSubject   = "subject"
+	tagKindPalette   = "palette"
+	tagKindTechnique = "technique"
+)
+
+var tagKinds = []string{tagKindTheme, tagKindSubject, tagKindPalette, tagKindTechnique}
+
+const maxTagNameLength = 100
+
+// Tag is a tag as embedded in Artwork responses.
+type Tag struct {
+	ID   int64  `json:"id"`
+	Kind string `json:"kind"`
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+type TagResponse struct {
+	Tag
+	Count     int       `json:"count"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type TagListResponse struct {
+	Tags  []TagResponse `json:"tags"`
+	Total int           `json:"total"`
+}
+
+type adminTagPayload struct {
+	Kind string `json:"kind"`
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+type adminArtworkTagsPayload struct {
+	TagIDs []int64 `json:"tagIds"`
+}
+
+func (p *adminTagPayload) normalize() {
+	p.Kind = strings.TrimSpace(p.Kind)
+	p.Name = strings.TrimSpace(p.Name)
+	p.Slug = strings.TrimSpace(p.Slug)
+	if p.Slug == "" {
+		p.Slug = slugify(p.Name)
+	}
+}
+
+func (p adminTagPayload) validate() error {
+	var errs validationErrors
+	if !slices.Contains(tagKinds, p.Kind) {
+		errs.add("kind", codeInvalidValue, "must be one of: "+strings.Join(tagKinds, ", "))
+	}
+	if p.Name == "" {
+		errs.add("name", codeRequired, "is required")
+	}
+	errs.checkLength("name", p.Name, maxTagNameLength)
+	if !slugPattern.MatchString(p.Slug) || len(p.Slug) > maxSlugLength {
+		errs.add("slug", codeInvalidValue, "must be lowercase letters, digits and dashes (at most 100)")
+	}
+	return errs.err()
+}
+
+func tagFromRow(row db.TagRow) Tag {
+	return Tag{ID: row.ID, Kind: row.Kind, Slug: row.Slug, Name: row.Name}
+}
+
+func tagResponse(row db.TagRow) TagResponse {
+	return TagResponse{Tag: tagFromRow(row), Count: row.Count, CreatedAt: row.CreatedAt, UpdatedAt: row.UpdatedAt}
+}
+
+// hasTags reports whether an artwork carries every tag filter. A filter is a
+// slug ("azul", any kind) or kind and slug ("palette:azul").
+func (a Artwork) hasTags(filters []string) bool {
+	for _, f := range filters {
+		kind, slug, scoped := strings.Cut(f, ":")
+		if !scoped {
+			kind, slug = "", f
+		}
+		found := false
+		for _, t := range a.Tags {
+			if t.Slug == slug && (kind == "" || t.Kind == kind) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// attachArtworkTags sets the tags of an artwork from its rows.
+func attachArtworkTags(artwork *Artwork, rows []db.TagRow) {
+	artwork.Tags = nil
+	for _, row := range rows {
+		artwork.Tags = append(artwork.Tags, tagFromRow(row))
+	}
+}
+
+func parseTagID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["tagId"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid tag id")
+		return 0, false
+	}
+	return id, true
+}
+
+// listTags answers GET /tags for both the public API (only public artworks
+// are counted) and the admin API.
+func listTags(w http.ResponseWriter, r *http.Request, publicOnly bool) {
+	kind := strings.TrimSpace(r.URL.Query().Get("kind"))
+	if kind != "" && !slices.Contains(tagKinds, kind) {
+		respondWithError(w, http.StatusBadRequest, "Invalid kind: must be one of "+strings.Join(tagKinds, ", "))
+		return
+	}
+	tags := []TagResponse{}
+	if pgPool != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		defer cancel()
+		rows, err := db.ListTags(ctx, pgPool, kind, publicOnly)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to list tags")
+			return
+		}
+		for _, row := range rows {
+			if publicOnly && row.Count == 0 {
+				continue
+			}
+			tags = append(tags, tagResponse(row))
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TagListResponse{Tags: tags, Total: len(tags)})
+}
+
+func getTags(w http.ResponseWriter, r *http.Request) {
+	listTags(w, r, true)
+}
+
+func adminListTags(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	listTags(w, r, false)
+}
+
+func adminCreateTag(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	var payload adminTagPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	payload.normalize()
+	if err := payload.validate(); err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	row, err := db.InsertTag(ctx, pgPool, db.TagRow{Kind: payload.Kind, Slug: payload.Slug, Name: payload.Name})
+	if db.IsUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Tag already exists")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create tag")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tagResponse(*row))
+}
+
+func adminUpdateTag(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	id, ok := parseTagID(w, r)
+	if !ok {
+		return
+	}
+	var payload adminTagPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	payload.normalize()
+	if err := payload.validate(); err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	updated, err := db.UpdateTag(ctx, pgPool, db.TagRow{ID: id, Kind: payload.Kind, Slug: payload.Slug, Name: payload.Name})
+	if db.IsUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Tag already exists")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update tag")
+		return
+	}
+	if !updated {
+		respondWithError(w, http.StatusNotFound, "Tag not found")
+		return
+	}
+	bumpCatalogVersion()
+
+	row, err := db.GetTag(ctx, pgPool, id)
+	if err != nil || row == nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to re-read tag")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tagResponse(*row))
+}
+
+func adminDeleteTag(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	id, ok := parseTagID(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	deleted, err := db.DeleteTag(ctx, pgPool, id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete tag")
+		return
+	}
+	if !deleted {
+		respondWithError(w, http.StatusNotFound, "Tag not found")
+		return
+	}
+	bumpCatalogVersion()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// adminSetArtworkTags replaces the tags of an artwork: {"tagIds": [1, 4]}.
+func adminSetArtworkTags(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if !isSafeArtworkID(id) {
+		respondWithError(w, http.StatusBadRequest, "Invalid artwork id")
+		return
+	}
+	var payload adminArtworkTagsPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	unlock := lockArtwork(id)
+	defer unlock()
+
+	if err := ensureArtworkExists(r.Context(), id); err != nil {
+		respondWithError(w, http.StatusNotFound, "Artwork not found")
+		return
+	}
+	before := snapshotArtwork(r.Context(), id)
+	tagIDs := make([]int64, 0, len(payload.TagIDs))
+	for _, tagID := range payload.TagIDs {
+		if !slices.Contains(tagIDs, tagID) {
+			tagIDs = append(tagIDs, tagID)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	ok, err := db.SetArtworkTags(ctx, pgPool, id, tagIDs)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save tags")
+		return
+	}
+	if !ok {
+		respondWithValidationErrors(w, validationErrors{{Field: "tagIds", Code: codeInvalidValue, Message: "contains an unknown tag id"}})
+		return
+	}
+	bumpCatalogVersion()
+	recordArtworkChange(r.Context(), id, revisionActionTags, before)
+
+	rows, err := db.ArtworkTags(ctx, pgPool, id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to re-read tags")
+		return
+	}
+	tags := make([]Tag, 0, len(rows))
+	for _, row := range rows {
+		tags = append(tags, tagFromRow(row))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]Tag{"tags": tags})
+}
@@ -0,0 +1,469 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/text/language"
+
+	"alexis-art-backend/db"
+)
+
+// Spanish is the source language: it is what artworks, meta.json and the
+// text files hold. Other locales are translations stored in Postgres.
+const (
+	localeES      = "es"
+	localeEN      = "en"
+	defaultLocale = localeES
+)
+
+var supportedLocales = []string{localeES, localeEN}
+
+// localeMatcher picks the best supported locale; the first one is the
+// fallback.
+var localeMatcher = language.NewMatcher([]language.Tag{language.Spanish, language.English})
+
+type TranslationResponse struct {
+	Locale  string `json:"locale"`
+	Title   string `json:"title"`
+	Detalle string `json:"detalle"`
+	// One item per entry of the bitácora, in timeline order; text is empty
+	// when the entry is not translated.
+	BitacoraEntries []EntryTranslation `json:"bitacoraEntries"`
+	Missing         []string           `json:"missing"`
+	UpdatedBy       string             `json:"updatedBy,omitempty"`
+	UpdatedAt       *time.Time         `json:"updatedAt,omitempty"`
+}
+
+type TranslationListResponse struct {
+	ArtworkID    string                `json:"artworkId"`
+	Translations []TranslationResponse `json:"translations"`
+}
+
+type MissingTranslation struct {
+	ArtworkID string   `json:"artworkId"`
+	Title     string   `json:"title"`
+	Status    string   `json:"status"`
+	Locale    string   `json:"locale"`
+	Missing   []string `json:"missing"`
+}
+
+type MissingTranslationListResponse struct {
+	Artworks []MissingTranslation `json:"artworks"`
+	Total    int                  `json:"total"`
+}
+
+// EntryTranslation is the translated text of one bitácora entry.
+type EntryTranslation struct {
+	ID   int64  `json:"id"`
+	Text string `json:"text"`
+}
+
+type adminTranslationPayload struct {
+	Title   string `json:"title"`
+	Detalle string `json:"detalle"`
+	// Entries left out (or with empty text) have no translation.
+	BitacoraEntries []EntryTranslation `json:"bitacoraEntries"`
+}
+
+// validate checks the payload against the entries the artwork has, and
+// returns the entry translations by id.
+func (p adminTranslationPayload) validate(source Artwork) (map[int64]string, error) {
+	var errs validationErrors
+	errs.checkLength("title", p.Title, maxTitleLength)
+	bodies := map[int64]string{}
+	for _, e := range p.BitacoraEntries {
+		if !slices.ContainsFunc(source.BitacoraEntries, func(x BitacoraEntry) bool { return x.ID == e.ID }) {
+			errs.add("bitacoraEntries", codeInvalidValue, "must be entries of the artwork")
+			break
+		}
+		if _, dup := bodies[e.ID]; dup {
+			errs.add("bitacoraEntries", codeInvalidValue, "lists entry "+strconv.FormatInt(e.ID, 10)+" more than once")
+			break
+		}
+		errs.checkLength("bitacoraEntries."+strconv.FormatInt(e.ID, 10), e.Text, maxBitacoraEntryLength)
+		if strings.TrimSpace(e.Text) != "" {
+			bodies[e.ID] = strings.TrimSpace(e.Text)
+		}
+	}
+	return bodies, errs.err()
+}
+
+// requestLocale resolves the locale of a public response: ?lang= first, then
+// Accept-Language, then Spanish.
+func requestLocale(r *http.Request) string {
+	var tags []language.Tag
+	if lang := strings.TrimSpace(r.URL.Query().Get("lang")); lang != "" {
+		tags, _, _ = language.ParseAcceptLanguage(lang)
+	} else if accept := r.Header.Get("Accept-Language"); accept != "" {
+		tags, _, _ = language.ParseAcceptLanguage(accept)
+	}
+	if len(tags) == 0 {
+		return defaultLocale
+	}
+	_, i, confidence := localeMatcher.Match(tags...)
+	if confidence == language.No {
+		return defaultLocale
+	}
+	return supportedLocales[i]
+}
+
+// setLocaleHeaders tells caches that the response depends on the language.
+func setLocaleHeaders(w http.ResponseWriter, locale string) {
+	w.Header().Set("Content-Language", locale)
+	w.Header().Add("Vary", "Accept-Language")
+}
+
+// applyTranslation replaces the Spanish text fields that have a translation.
+func (a *Artwork) applyTranslation(t db.TranslationRow) {
+	if t.Title != "" {
+		a.Title = t.Title
+	}
+	if t.Detalle != "" {
+		a.Detalle = t.Detalle
+	}
+}
+
+// applyEntryTranslations replaces the text of the bitácora entries that have
+// a translation.
+func (a *Artwork) applyEntryTranslations(bodies map[int64]string) {
+	for i := range a.BitacoraEntries {
+		if body, ok := bodies[a.BitacoraEntries[i].ID]; ok && body != "" {
+			a.BitacoraEntries[i].Text = body
+		}
+	}
+}
+
+// localizeArtworks translates artworks in place. Fields without a
+// translation keep their Spanish text.
+func localizeArtworks(ctx context.Context, locale string, artworks []Artwork) {
+	if pgPool == nil || locale == defaultLocale || len(artworks) == 0 {
+		return
+	}
+	artworkID := ""
+	if len(artworks) == 1 {
+		artworkID = artworks[0].ID
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	rows, err := db.ListTranslations(ctx, pgPool, artworkID, locale)
+	if err != nil {
+		return
+	}
+	entryRows, err := db.ListBitacoraTranslations(ctx, pgPool, artworkID, locale)
+	if err != nil {
+		return
+	}
+	byID := make(map[string]db.TranslationRow, len(rows))
+	for _, row := range rows {
+		byID[row.ArtworkID] = row
+	}
+	entries := entryTranslationsByArtwork(entryRows)
+	for i := range artworks {
+		if t, ok := byID[artworks[i].ID]; ok {
+			artworks[i].applyTranslation(t)
+		}
+		artworks[i].applyEntryTranslations(entries[artworks[i].ID])
+	}
+}
+
+// entryTranslationsByArtwork groups entry translations of one locale as
+// artwork id -> entry id -> text.
+func entryTranslationsByArtwork(rows []db.BitacoraTranslationRow) map[string]map[int64]string {
+	out := map[string]map[int64]string{}
+	for _, row := range rows {
+		if out[row.ArtworkID] == nil {
+			out[row.ArtworkID] = map[int64]string{}
+		}
+		out[row.ArtworkID][row.EntryID] = row.Body
+	}
+	return out
+}
+
+// missingFields lists the fields that have Spanish text but no translation;
+// an untranslated bitácora entry is reported as bitacoraEntries.<id>.
+func missingFields(source Artwork, t db.TranslationRow, entries map[int64]string) []string {
+	missing := []string{}
+	for _, f := range []struct{ name, source, translated string }{
+		{"title", source.Title, t.Title},
+		{"detalle", source.Detalle, t.Detalle},
+	} {
+		if strings.TrimSpace(f.source) != "" && f.translated == "" {
+			missing = append(missing, f.name)
+		}
+	}
+	for _, e := range source.BitacoraEntries {
+		if strings.TrimSpace(e.Text) != "" && entries[e.ID] == "" {
+			missing = append(missing, "bitacoraEntries."+strconv.FormatInt(e.ID, 10))
+		}
+	}
+	return missing
+}
+
+func translationResponse(source Artwork, t db.TranslationRow, entries map[int64]string, stored bool) TranslationResponse {
+	resp := TranslationResponse{
+		Locale:          t.Locale,
+		Title:           t.Title,
+		Detalle:         t.Detalle,
+		BitacoraEntries: make([]EntryTranslation, 0, len(source.BitacoraEntries)),
+		Missing:         missingFields(source, t, entries),
+	}
+	for _, e := range source.BitacoraEntries {
+		resp.BitacoraEntries = append(resp.BitacoraEntries, EntryTranslation{ID: e.ID, Text: entries[e.ID]})
+	}
+	if stored {
+		resp.UpdatedBy = t.UpdatedBy
+		resp.UpdatedAt = &t.UpdatedAt
+	}
+	return resp
+}
+
+// translationTarget reads and checks the artwork and the locale of the URL.
+func translationTarget(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return "", "", false
+	}
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if !isSafeArtworkID(id) {
+		respondWithError(w, http.StatusBadRequest, "Invalid artwork id")
+		return "", "", false
+	}
+	locale, hasLocale := vars["locale"]
+	if hasLocale && (locale == defaultLocale || !slices.Contains(supportedLocales, locale)) {
+		respondWithError(w, http.StatusBadRequest, "Invalid locale: must be one of "+strings.Join(supportedLocales[1:], ", ")+
+			" (Spanish is edited on the artwork itself)")
+		return "", "", false
+	}
+	return id, locale, true
+}
+
+// adminListTranslations returns every translatable locale of an artwork,
+// including the ones not started yet.
+func adminListTranslations(w http.ResponseWriter, r *http.Request) {
+	id, _, ok := translationTarget(w, r)
+	if !ok {
+		return
+	}
+	source, err := getArtworkByID(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Artwork not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	rows, err := db.ListTranslations(ctx, pgPool, id, "")
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list translations")
+		return
+	}
+	entryRows, err := db.ListBitacoraTranslations(ctx, pgPool, id, "")
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list translations")
+		return
+	}
+	stored := map[string]db.TranslationRow{}
+	for _, row := range rows {
+		stored[row.Locale] = row
+	}
+	entries := map[string]map[int64]string{}
+	for _, row := range entryRows {
+		if entries[row.Locale] == nil {
+			entries[row.Locale] = map[int64]string{}
+		}
+		entries[row.Locale][row.EntryID] = row.Body
+	}
+
+	resp := TranslationListResponse{ArtworkID: id, Translations: []TranslationResponse{}}
+	for _, locale := range supportedLocales {
+		if locale == defaultLocale {
+			continue
+		}
+		row, ok := stored[locale]
+		if !ok {
+			row = db.TranslationRow{ArtworkID: id, Locale: locale}
+		}
+		resp.Translations = append(resp.Translations, translationResponse(source, row, entries[locale], ok))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func adminPutTranslation(w http.ResponseWriter, r *http.Request) {
+	id, locale, ok := translationTarget(w, r)
+	if !ok {
+		return
+	}
+	var payload adminTranslationPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	payload.Title = strings.TrimSpace(payload.Title)
+
+	unlock := lockArtwork(id)
+	defer unlock()
+
+	source, err := getArtworkByID(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Artwork not found")
+		return
+	}
+	bodies, err := payload.validate(source)
+	if err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+	before := snapshotArtwork(r.Context(), id)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	tx, err := pgPool.Begin(ctx)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save translation")
+		return
+	}
+	defer tx.Rollback(context.Background())
+
+	actor := actorFromContext(r.Context())
+	row := db.TranslationRow{
+		ArtworkID: id,
+		Locale:    locale,
+		Title:     payload.Title,
+		Detalle:   payload.Detalle,
+		UpdatedBy: actor,
+	}
+	if err := db.UpsertTranslation(ctx, tx, row); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save translation")
+		return
+	}
+	if err := db.SetBitacoraTranslations(ctx, tx, id, locale, bodies, actor); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save translation")
+		return
+	}
+	rows, err := db.ListTranslations(ctx, tx, id, locale)
+	if err != nil || len(rows) == 0 {
+		respondWithError(w, http.StatusInternalServerError, "Failed to re-read translation")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save translation")
+		return
+	}
+	bumpCatalogVersion()
+	recordArtworkChange(r.Context(), id, revisionActionTranslations, before)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(translationResponse(source, rows[0], bodies, true))
+}
+
+func adminDeleteTranslation(w http.ResponseWriter, r *http.Request) {
+	id, locale, ok := translationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	unlock := lockArtwork(id)
+	defer unlock()
+	before := snapshotArtwork(r.Context(), id)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	tx, err := pgPool.Begin(ctx)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete translation")
+		return
+	}
+	defer tx.Rollback(context.Background())
+	deleted, err := db.DeleteTranslation(ctx, tx, id, locale)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete translation")
+		return
+	}
+	deletedEntries, err := db.DeleteBitacoraTranslations(ctx, tx, id, locale)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete translation")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete translation")
+		return
+	}
+	if !deleted && deletedEntries == 0 {
+		respondWithError(w, http.StatusNotFound, "Translation not found")
+		return
+	}
+	bumpCatalogVersion()
+	recordArtworkChange(r.Context(), id, revisionActionTranslations, before)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// adminListMissingTranslations lists the artworks (drafts included) with text
+// that is not translated yet, for every locale or for ?locale=.
+func adminListMissingTranslations(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	locales := supportedLocales[1:]
+	if locale := strings.TrimSpace(r.URL.Query().Get("locale")); locale != "" {
+		if locale == defaultLocale || !slices.Contains(supportedLocales, locale) {
+			respondWithError(w, http.StatusBadRequest, "Invalid locale: must be one of "+strings.Join(supportedLocales[1:], ", "))
+			return
+		}
+		locales = []string{locale}
+	}
+
+	artworks, err := scanArtworks(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	rows, err := db.ListTranslations(ctx, pgPool, "", "")
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list translations")
+		return
+	}
+	entryRows, err := db.ListBitacoraTranslations(ctx, pgPool, "", "")
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list translations")
+		return
+	}
+	stored := map[string]db.TranslationRow{}
+	for _, row := range rows {
+		stored[row.ArtworkID+"/"+row.Locale] = row
+	}
+	entries := map[string]map[int64]string{}
+	for _, row := range entryRows {
+		key := row.ArtworkID + "/" + row.Locale
+		if entries[key] == nil {
+			entries[key] = map[int64]string{}
+		}
+		entries[key][row.EntryID] = row.Body
+	}
+
+	result := []MissingTranslation{}
+	for _, a := range artworks {
+		for _, locale := range locales {
+			row, ok := stored[a.ID+"/"+locale]
+			if !ok {
+				row = db.TranslationRow{ArtworkID: a.ID, Locale: locale}
+			}
+			if missing := missingFields(a, row, entries[a.ID+"/"+locale]); len(missing) > 0 {
+				result = append(result, MissingTranslation{ArtworkID: a.ID, Title: a.Title, Status: a.Status, Locale: locale, Missing: missing})
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MissingTranslationListResponse{Artworks: result, Total: len(result)})
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"alexis-art-backend/db"
+)
+
+const (
+	minPasswordLength = 12
+	maxPasswordLength = 256
+	maxUserNameLength = 200
+)
+
+type AdminUserResponse struct {
+	ID          int64      `json:"id"`
+	Email       string     `json:"email"`
+	Name        string     `json:"name"`
+	Role        string     `json:"role"`
+	Disabled    bool       `json:"disabled"`
+	LastLoginAt *time.Time `json:"lastLoginAt,omitempty"`
+	CreatedBy   string     `json:"createdBy"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+type AdminUserListResponse struct {
+	Users []AdminUserResponse `json:"users"`
+	Total int                 `json:"total"`
+}
+
+type AdminSessionResponse struct {
+	ID          string    `json:"id"`
+	UserAgent   string    `json:"userAgent"`
+	IP          string    `json:"ip"`
+	CreatedAt   time.Time `json:"createdAt"`
+	RefreshedAt time.Time `json:"refreshedAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	Current     bool      `json:"current"`
+}
+
+type AdminSessionListResponse struct {
+	Sessions []AdminSessionResponse `json:"sessions"`
+	Total    int                    `json:"total"`
+}
+
+// MeResponse describes the caller. User is only set for accounts.
+type MeResponse struct {
+	Actor       string             `json:"actor"`
+	Role        string             `json:"role"`
+	Permissions []permission       `json:"permissions"`
+	User        *AdminUserResponse `json:"user,omitempty"`
+}
+
+type adminUserPayload struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+	Role  string `json:"role"`
+	// Required when creating; on update an empty password keeps the current one.
+	Password string `json:"password"`
+	Disabled bool   `json:"disabled"`
+}
+
+type passwordChangePayload struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+func (p *adminUserPayload) normalize() {
+	p.Email = strings.ToLower(strings.TrimSpace(p.Email))
+	p.Name = strings.TrimSpace(p.Name)
+	p.Role = strings.TrimSpace(p.Role)
+}
+
+func (p adminUserPayload) validate(creating bool) error {
+	var errs validationErrors
+	if p.Email == "" {
+		errs.add("email", codeRequired, "is required")
+	} else if addr, err := mail.ParseAddress(p.Email); err != nil || addr.Address != p.Email {
+		errs.add("email", codeInvalidValue, "must be a valid email address")
+	}
+	errs.checkLength("name", p.Name, maxUserNameLength)
+	if p.Role == "" {
+		errs.add("role", codeRequired, "is required")
+	} else if !slices.Contains(roles, p.Role) {
+		errs.add("role", codeInvalidValue, "must be one of "+strings.Join(roles, ", "))
+	}
+	if creating && p.Password == "" {
+		errs.add("password", codeRequired, "is required")
+	}
+	if p.Password != "" {
+		errs.checkNewPassword("password", p.Password)
+	}
+	return errs.err()
+}
+
+func (v *validationErrors) checkNewPassword(field, password string) {
+	if len([]rune(password)) < minPasswordLength {
+		v.add(field, codeInvalidValue, "must be at least "+strconv.Itoa(minPasswordLength)+" characters")
+	}
+	v.checkLength(field, password, maxPasswordLength)
+}
+
+func adminUserResponse(u db.AdminUserRow) AdminUserResponse {
+	return AdminUserResponse{
+		ID:          u.ID,
+		Email:       u.Email,
+		Name:        u.Name,
+		Role:        u.Role,
+		Disabled:    u.DisabledAt != nil,
+		LastLoginAt: u.LastLoginAt,
+		CreatedBy:   u.CreatedBy,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+	}
+}
+
+// userTarget reads the account id of the URL.
+func userTarget(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return 0, false
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["userId"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user id")
+		return 0, false
+	}
+	return id, true
+}
+
+func adminGetMe(w http.ResponseWriter, r *http.Request) {
+	identity, _ := identityFromContext(r.Context())
+	resp := MeResponse{Actor: identity.actor(), Role: identity.Role, Permissions: identity.permissions()}
+	if identity.UserID != 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		defer cancel()
+		user, err := db.GetAdminUser(ctx, pgPool, identity.UserID)
+		if err != nil || user == nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to read user")
+			return
+		}
+		u := adminUserResponse(*user)
+		resp.User = &u
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// adminChangeOwnPassword changes the password of the caller and ends their
+// other sessions.
+func adminChangeOwnPassword(w http.ResponseWriter, r *http.Request) {
+	identity, _ := identityFromContext(r.Context())
+	if identity.UserID == 0 {
+		respondWithError(w, http.StatusBadRequest, "Only user accounts have a password")
+		return
+	}
+	var payload passwordChangePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	var errs validationErrors
+	if payload.CurrentPassword == "" {
+		errs.add("currentPassword", codeRequired, "is required")
+	}
+	errs.checkNewPassword("newPassword", payload.NewPassword)
+	if err := errs.err(); err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	user, err := db.GetAdminUser(ctx, pgPool, identity.UserID)
+	if err != nil || user == nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to read user")
+		return
+	}
+	if !checkPassword(user.PasswordHash, payload.CurrentPassword) {
+		respondWithError(w, http.StatusForbidden, "Current password is incorrect")
+		return
+	}
+	user.PasswordHash = hashPassword(payload.NewPassword)
+	if _, err := db.UpdateAdminUser(ctx, pgPool, *user); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to change password")
+		return
+	}
+	if _, err := db.RevokeAdminUserSessions(ctx, pgPool, user.ID, identity.SessionID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to end other sessions")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func adminListUsers(w http.ResponseWriter, r *http.Request) {
+	if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	rows, err := db.ListAdminUsers(ctx, pgPool)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list users")
+		return
+	}
+	users := make([]AdminUserResponse, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, adminUserResponse(row))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AdminUserListResponse{Users: users, Total: len(users)})
+}
+
+func adminGetUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := userTarget(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	user, err := db.GetAdminUser(ctx, pgPool, id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to read user")
+		return
+	}
+	if user == nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(adminUserResponse(*user))
+}
+
+func adminCreateUser(w http.ResponseWriter, r *http.Request) {
+	saveUser(w, r, false)
+}
+
+func adminUpdateUser(w http.ResponseWriter, r *http.Request) {
+	saveUser(w, r, true)
+}
+
+// saveUser creates an account or replaces its email, name, role and disabled
+// state (and password, when given). Disabling an account ends its sessions.
+func saveUser(w http.ResponseWriter, r *http.Request, update bool) {
+	var id int64
+	if update {
+		var ok bool
+		if id, ok = userTarget(w, r); !ok {
+			return
+		}
+	} else if pgPool == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database not configured")
+		return
+	}
+	var payload adminUserPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	payload.normalize()
+	if err := payload.validate(!update); err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+	identity, _ := identityFromContext(r.Context())
+	if update && id == identity.UserID && (payload.Disabled || payload.Role != identity.Role) {
+		respondWithError(w, http.StatusBadRequest, "You cannot disable your own account or change your own role")
+		return
+	}
+	row := db.AdminUserRow{
+		ID:        id,
+		Email:     payload.Email,
+		Name:      payload.Name,
+		Role:      payload.Role,
+		CreatedBy: identity.actor(),
+	}
+	if payload.Password != "" {
+		row.PasswordHash = hashPassword(payload.Password)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	tx, err := pgPool.Begin(ctx)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save user")
+		return
+	}
+	defer tx.Rollback(context.Background())
+
+	status := http.StatusCreated
+	if update {
+		status = http.StatusOK
+		existing, err := db.GetAdminUser(ctx, tx, id)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to read user")
+			return
+		}
+		if existing == nil {
+			respondWithError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		row.DisabledAt = existing.DisabledAt
+		if !payload.Disabled {
+			row.DisabledAt = nil
+		} else if row.DisabledAt == nil {
+			now := time.Now()
+			row.DisabledAt = &now
+		}
+		_, err = db.UpdateAdminUser(ctx, tx, row)
+		if err == nil && (payload.Disabled || payload.Password != "") {
+			_, err = db.RevokeAdminUserSessions(ctx, tx, id, "")
+		}
+	} else {
+		id, err = db.InsertAdminUser(ctx, tx, row)
+	}
+	if db.IsUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Email already in use")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save user")
+		return
+	}
+	saved, err := db.GetAdminUser(ctx, tx, id)
+	if err != nil || saved == nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to re-read user")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save user")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(adminUserResponse(*saved))
+}
+
+func adminListUserSessions(w http.ResponseWriter, r *http.Request) {
+	id, ok := userTarget(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	rows, err := db.ListAdminSessions(ctx, pgPool, id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list sessions")
+		return
+	}
+	identity, _ := identityFromContext(r.Context())
+	sessions := make([]AdminSessionResponse, 0, len(rows))
+	for _, s := range rows {
+		sessions = append(sessions, AdminSessionResponse{
+			ID:          s.ID,
+			UserAgent:   s.UserAgent,
+			IP:          s.IP,
+			CreatedAt:   s.CreatedAt,
+			RefreshedAt: s.RefreshedAt,
+			ExpiresAt:   s.ExpiresAt,
+			Current:     s.ID == identity.SessionID,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AdminSessionListResponse{Sessions: sessions, Total: len(sessions)})
+}
+
+// adminRevokeUserSessions logs an account out everywhere.
+func adminRevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	id, ok := userTarget(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	if _, err := db.RevokeAdminUserSessions(ctx, pgPool, id, ""); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke sessions")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// adminRevokeUserSession ends one session of an account.
+func adminRevokeUserSession(w http.ResponseWriter, r *http.Request) {
+	id, ok := userTarget(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	sessions, err := db.ListAdminSessions(ctx, pgPool, id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke session")
+		return
+	}
+	sessionID := mux.Vars(r)["sessionId"]
+	for _, s := range sessions {
+		if s.ID != sessionID {
+			continue
+		}
+		if _, err := db.RevokeAdminSession(ctx, pgPool, s.ID, ""); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to revoke session")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	respondWithError(w, http.StatusNotFound, "Session not found")
+}
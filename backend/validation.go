@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxTitleLength    = 200
+	maxLocationLength = 200
+	dateLayout        = "2006-01-02"
+)
+
+// Field error codes returned in ErrorResponse.Fields. Clients should switch on
+// these rather than on the (human readable) messages.
+const (
+	codeRequired       = "required"
+	codeTooLong        = "too_long"
+	codeInvalidDate    = "invalid_date"
+	codeEndBeforeStart = "end_before_start"
+	codeInconsistent   = "inconsistent"
+	codeInvalidValue   = "invalid_value"
+	codeReadOnly       = "read_only"
+)
+
+// FieldError describes one invalid field of an admin payload.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// validationErrors collects field errors; a non-empty value is returned as an
+// error and answered with 422 by respondWithAPIError.
+type validationErrors []FieldError
+
+func (v *validationErrors) add(field, code, message string) {
+	*v = append(*v, FieldError{Field: field, Code: code, Message: message})
+}
+
+func (v validationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, e := range v {
+		msgs[i] = e.Field + ": " + e.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// err returns v as an error, or nil when there is nothing to report.
+func (v validationErrors) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+func respondWithValidationErrors(w http.ResponseWriter, errs validationErrors) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: "Validation failed", Fields: errs})
+}
+
+func (v *validationErrors) checkLength(field, value string, max int) {
+	if utf8.RuneCountInString(value) > max {
+		v.add(field, codeTooLong, "must be at most "+strconv.Itoa(max)+" characters")
+	}
+}
+
+// parseDate parses an optional YYYY-MM-DD date; ok is false when malformed.
+func parseDate(value string) (t *time.Time, ok bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, true
+	}
+	parsed, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return nil, false
+	}
+	return &parsed, true
+}
+
+// checkDate validates an optional YYYY-MM-DD date and returns it parsed.
+func (v *validationErrors) checkDate(field, value string) *time.Time {
+	t, ok := parseDate(value)
+	if !ok {
+		v.add(field, codeInvalidDate, "must be a valid date in YYYY-MM-DD format")
+	}
+	return t
+}
+
+func (p adminArtworkCreate) validate() error {
+	var errs validationErrors
+	title := strings.TrimSpace(p.Title)
+	if title == "" {
+		errs.add("title", codeRequired, "is required")
+	}
+	errs.checkLength("title", title, maxTitleLength)
+	return errs.err()
+}
+
+func (p adminArtworkUpdate) validate() error {
+	var errs validationErrors
+	errs.checkLength("title", strings.TrimSpace(p.Title), maxTitleLength)
+	errs.checkLength("paintedLocation", strings.TrimSpace(p.PaintedLocation), maxLocationLength)
+
+	start := errs.checkDate("startDate", p.StartDate)
+	end := errs.checkDate("endDate", p.EndDate)
+	if start != nil && end != nil && end.Before(*start) {
+		errs.add("endDate", codeEndBeforeStart, "must be on or after startDate")
+	}
+	if p.InProgress && end != nil {
+		errs.add("inProgress", codeInconsistent, "an artwork in progress cannot have an endDate")
+	}
+
+	if img := strings.TrimSpace(p.PrimaryImage); img != "" {
+		if strings.Contains(img, "/") || strings.Contains(img, "\\") || strings.Contains(img, "..") {
+			errs.add("primaryImage", codeInvalidValue, "must be a filename of one of the artwork images")
+		}
+	}
+
+	if p.Bitacora != nil {
+		errs.add("bitacora", codeReadOnly, "is edited as dated entries at /api/v1/admin/artworks/{id}/bitacora")
+	}
+
+	errs.checkSales(p)
+	return errs.err()
+}
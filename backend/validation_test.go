@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAdminArtworkUpdateValidate(t *testing.T) {
+	zero, tall := 0.0, 120.0
+	bitacora := "texto"
+
+	type fieldCode struct{ field, code string }
+	tests := []struct {
+		name    string
+		payload adminArtworkUpdate
+		want    []fieldCode
+	}{
+		{"valid", adminArtworkUpdate{
+			Title: "Sol de Lima", StartDate: "2023-01-10", EndDate: "2023-03-01",
+			Dimensions: &Dimensions{Height: &tall, Unit: "cm"}, Price: &Price{Amount: 150000, Currency: "EUR"},
+			Availability: "available",
+		}, nil},
+		{"empty", adminArtworkUpdate{}, nil},
+		{"title too long", adminArtworkUpdate{Title: strings.Repeat("a", maxTitleLength+1)},
+			[]fieldCode{{"title", codeTooLong}}},
+		{"title length counts characters", adminArtworkUpdate{Title: strings.Repeat("ñ", maxTitleLength)}, nil},
+		{"location too long", adminArtworkUpdate{PaintedLocation: strings.Repeat("a", maxLocationLength+1)},
+			[]fieldCode{{"paintedLocation", codeTooLong}}},
+		{"invalid dates", adminArtworkUpdate{StartDate: "10/01/2023", EndDate: "2023-02-30"},
+			[]fieldCode{{"startDate", codeInvalidDate}, {"endDate", codeInvalidDate}}},
+		{"end before start", adminArtworkUpdate{StartDate: "2023-03-01", EndDate: "2023-01-10"},
+			[]fieldCode{{"endDate", codeEndBeforeStart}}},
+		{"in progress with end date", adminArtworkUpdate{InProgress: true, EndDate: "2023-01-10"},
+			[]fieldCode{{"inProgress", codeInconsistent}}},
+		{"primary image path", adminArtworkUpdate{PrimaryImage: "../otra/a.jpg"},
+			[]fieldCode{{"primaryImage", codeInvalidValue}}},
+		{"bitacora is read-only", adminArtworkUpdate{Bitacora: &bitacora},
+			[]fieldCode{{"bitacora", codeReadOnly}}},
+		{"dimensions", adminArtworkUpdate{Dimensions: &Dimensions{Height: &zero, Unit: "m"}},
+			[]fieldCode{{"dimensions.height", codeInvalidValue}, {"dimensions.unit", codeInvalidValue}}},
+		{"price", adminArtworkUpdate{Price: &Price{Amount: -1, Currency: "euro"}},
+			[]fieldCode{{"price.amount", codeInvalidValue}, {"price.currency", codeInvalidValue}}},
+		{"availability", adminArtworkUpdate{Availability: "gone"},
+			[]fieldCode{{"availability", codeInvalidValue}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []fieldCode
+			if err := tt.payload.validate(); err != nil {
+				errs, ok := err.(validationErrors)
+				if !ok {
+					t.Fatalf("validate = %T %v, want validationErrors", err, err)
+				}
+				for _, e := range errs {
+					got = append(got, fieldCode{e.Field, e.Code})
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRespondWithValidationErrors(t *testing.T) {
+	var errs validationErrors
+	errs.add("endDate", codeEndBeforeStart, "must be on or after startDate")
+	w := httptest.NewRecorder()
+	respondWithAPIError(w, errs.err())
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", w.Code)
+	}
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	want := []FieldError{{Field: "endDate", Code: codeEndBeforeStart, Message: "must be on or after startDate"}}
+	if !reflect.DeepEqual(resp.Fields, want) {
+		t.Errorf("fields = %+v, want %+v", resp.Fields, want)
+	}
+}
@@ -65,6 +65,10 @@ export async function POST(req: NextRequest, ctx: { params: { path: string[] } }
   return proxy(req, ctx.params)
 }
 
+export async function PATCH(req: NextRequest, ctx: { params: { path: string[] } }) {
+  return proxy(req, ctx.params)
+}
+
 export async function DELETE(req: NextRequest, ctx: { params: { path: string[] } }) {
   return proxy(req, ctx.params)
 }
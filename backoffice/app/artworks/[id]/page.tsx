@@ -4,13 +4,15 @@ import { useEffect, useMemo, useRef, useState } from 'react'
 import { useParams, useRouter } from 'next/navigation'
 import Link from 'next/link'
 import { getToken } from '@/lib/auth'
-import type { AdminUpdate, Artwork } from '@/lib/api'
+import type { AdminUpdate, Artwork, Availability, BitacoraEntry, Dimensions, ErrorResponse } from '@/lib/api'
 
 export default function ArtworkEditPage() {
   const params = useParams<{ id: string }>()
   const router = useRouter()
   const id = useMemo(() => decodeURIComponent(params.id), [params.id])
   const fileInputRef = useRef<HTMLInputElement>(null)
+  // Version of the artwork last seen by this page; sent as If-Match on save.
+  const etagRef = useRef<string>('')
 
   const [artwork, setArtwork] = useState<Artwork | null>(null)
   const [form, setForm] = useState<AdminUpdate>({
@@ -20,9 +22,15 @@ export default function ArtworkEditPage() {
     endDate: '',
     inProgress: false,
     detalle: '',
-    bitacora: '',
     primaryImage: '',
+    technique: '',
+    dimensions: null,
+    price: null,
+    availability: '',
   })
+  // Bitacora entries being edited, by id, and the entry being written.
+  const [entryDrafts, setEntryDrafts] = useState<Record<number, BitacoraEntry>>({})
+  const [newEntry, setNewEntry] = useState<BitacoraEntry>({ date: '', text: '' })
   const [status, setStatus] = useState<string>('')
   const [titleError, setTitleError] = useState('')
   const [uploading, setUploading] = useState(false)
@@ -41,16 +49,24 @@ export default function ArtworkEditPage() {
     loadArtwork()
   }, [id, router, token])
 
-  const loadArtwork = async () => {
+  // fetchArtwork reloads the artwork and its ETag without touching the form.
+  const fetchArtwork = async (): Promise<Artwork | null> => {
     const res = await fetch(`/api/v1/admin/artworks/${encodeURIComponent(id)}`, {
       headers: { Authorization: `Bearer ${token}` },
     })
     if (!res.ok) {
       setStatus(`Error cargando: HTTP ${res.status}`)
-      return
+      return null
     }
+    etagRef.current = res.headers.get('ETag') || ''
     const a = (await res.json()) as Artwork
     setArtwork(a)
+    return a
+  }
+
+  const loadArtwork = async () => {
+    const a = await fetchArtwork()
+    if (!a) return
     setForm({
       title: a.title || '',
       paintedLocation: a.paintedLocation || '',
@@ -58,8 +74,11 @@ export default function ArtworkEditPage() {
       endDate: a.endDate || '',
       inProgress: !!a.inProgress,
       detalle: a.detalle || '',
-      bitacora: a.bitacora || '',
       primaryImage: a.primaryImage || '',
+      technique: a.technique || '',
+      dimensions: a.dimensions || null,
+      price: a.price || null,
+      availability: a.availability || '',
     })
   }
 
@@ -97,18 +116,29 @@ export default function ArtworkEditPage() {
       headers: {
         'Content-Type': 'application/json',
         Authorization: `Bearer ${token}`,
+        'If-Match': etagRef.current,
       },
       body: JSON.stringify(form),
     })
+    if (res.status === 412) {
+      setStatus('Otra persona modifico esta obra. Recarga la pagina para ver los cambios antes de guardar.')
+      return
+    }
     if (res.status === 409) {
       setTitleError('Este nombre ya existe')
       setStatus('')
       return
     }
+    if (res.status === 422) {
+      const err = (await res.json()) as ErrorResponse
+      setStatus(`Datos invalidos: ${(err.fields || []).map((f) => `${f.field}: ${f.message}`).join('; ')}`)
+      return
+    }
     if (!res.ok) {
       setStatus(`Error guardando: HTTP ${res.status}`)
       return
     }
+    etagRef.current = res.headers.get('ETag') || ''
     const updated = (await res.json()) as Artwork
     setArtwork(updated)
     setStatus('Guardado!')
@@ -138,6 +168,7 @@ export default function ArtworkEditPage() {
           setUploading(false)
           return
         }
+        etagRef.current = res.headers.get('ETag') || ''
         const updated = (await res.json()) as Artwork
         setArtwork(updated)
         // Update primary image in form if not set
@@ -173,6 +204,7 @@ export default function ArtworkEditPage() {
       return
     }
 
+    etagRef.current = res.headers.get('ETag') || ''
     const updated = (await res.json()) as Artwork
     setArtwork(updated)
 
@@ -186,6 +218,75 @@ export default function ArtworkEditPage() {
     setTimeout(() => setStatus(''), 2500)
   }
 
+  const changeStatus = async (action: 'publish' | 'unpublish') => {
+    setStatus(action === 'publish' ? 'Publicando...' : 'Despublicando...')
+    const res = await fetch(`/api/v1/admin/artworks/${encodeURIComponent(id)}/${action}`, {
+      method: 'POST',
+      headers: { Authorization: `Bearer ${token}`, 'If-Match': etagRef.current },
+    })
+    if (res.status === 412) {
+      setStatus('Otra persona modifico esta obra. Recarga la pagina para ver los cambios antes de publicar.')
+      return
+    }
+    if (!res.ok) {
+      setStatus(`Error: HTTP ${res.status}`)
+      return
+    }
+    etagRef.current = res.headers.get('ETag') || ''
+    setArtwork((await res.json()) as Artwork)
+    setStatus(action === 'publish' ? 'Publicada!' : 'Guardada como borrador')
+    setTimeout(() => setStatus(''), 2500)
+  }
+
+  // Bitacora entries change the artwork (and its ETag), so it is re-read
+  // after each one; unsaved edits of the form are kept.
+  const saveEntry = async (entry: BitacoraEntry) => {
+    setStatus('Guardando bitacora...')
+    const base = `/api/v1/admin/artworks/${encodeURIComponent(id)}/bitacora`
+    const res = await fetch(entry.id ? `${base}/${entry.id}` : base, {
+      method: entry.id ? 'PUT' : 'POST',
+      headers: { 'Content-Type': 'application/json', Authorization: `Bearer ${token}` },
+      body: JSON.stringify({ date: entry.date, text: entry.text, media: (entry.media || []).map((m) => m.file) }),
+    })
+    if (res.status === 422) {
+      const err = (await res.json()) as ErrorResponse
+      setStatus(`Datos invalidos: ${(err.fields || []).map((f) => `${f.field}: ${f.message}`).join('; ')}`)
+      return
+    }
+    if (!res.ok) {
+      setStatus(`Error guardando bitacora: HTTP ${res.status}`)
+      return
+    }
+    if (entry.id) {
+      const entryId = entry.id
+      setEntryDrafts((drafts) => {
+        const next = { ...drafts }
+        delete next[entryId]
+        return next
+      })
+    } else {
+      setNewEntry({ date: '', text: '' })
+    }
+    await fetchArtwork()
+    setStatus('Bitacora guardada!')
+    setTimeout(() => setStatus(''), 2500)
+  }
+
+  const deleteEntry = async (entryId: number) => {
+    setStatus('Eliminando entrada...')
+    const res = await fetch(`/api/v1/admin/artworks/${encodeURIComponent(id)}/bitacora/${entryId}`, {
+      method: 'DELETE',
+      headers: { Authorization: `Bearer ${token}` },
+    })
+    if (!res.ok) {
+      setStatus(`Error eliminando entrada: HTTP ${res.status}`)
+      return
+    }
+    await fetchArtwork()
+    setStatus('Entrada eliminada!')
+    setTimeout(() => setStatus(''), 2500)
+  }
+
   const setPrimaryImage = (filename: string) => {
     setForm((f) => ({ ...f, primaryImage: filename }))
   }
@@ -194,12 +295,23 @@ export default function ArtworkEditPage() {
     <div className="container">
       <div className="row" style={{ justifyContent: 'space-between', alignItems: 'center' }}>
         <div style={{ flex: 1 }}>
-          <div style={{ opacity: 0.5, fontSize: 12, marginBottom: 4 }}>ID: {id}</div>
+          <div style={{ opacity: 0.5, fontSize: 12, marginBottom: 4 }}>
+            ID: {id} · Estado: {artwork?.status === 'published' ? 'publicada' : artwork?.status === 'archived' ? 'archivada' : 'borrador'}
+          </div>
         </div>
         <div className="row" style={{ gap: 10, alignItems: 'center' }}>
           <Link className="btn" href="/artworks">
             Volver
           </Link>
+          {artwork?.status === 'published' ? (
+            <button className="btn" onClick={() => changeStatus('unpublish')}>
+              Despublicar
+            </button>
+          ) : (
+            <button className="btn" onClick={() => changeStatus('publish')}>
+              Publicar
+            </button>
+          )}
           <button className="btn btn-primary" onClick={save} disabled={!!titleError}>
             Guardar
           </button>
@@ -397,6 +509,96 @@ export default function ArtworkEditPage() {
           </label>
         </div>
 
+        <div className="row" style={{ marginTop: 16 }}>
+          <div style={{ flex: 2, minWidth: 240 }}>
+            <label>Tecnica</label>
+            <input
+              value={form.technique}
+              onChange={(e) => setForm({ ...form, technique: e.target.value })}
+              placeholder="Ej: Oleo sobre algodon"
+              style={{ marginTop: 8, marginBottom: 12 }}
+            />
+          </div>
+          {(['height', 'width', 'depth'] as const).map((dim) => (
+            <div key={dim} style={{ flex: 1, minWidth: 90 }}>
+              <label>{dim === 'height' ? 'Alto' : dim === 'width' ? 'Ancho' : 'Prof.'}</label>
+              <input
+                type="number"
+                min={0}
+                value={form.dimensions?.[dim] ?? ''}
+                onChange={(e) => {
+                  const dimensions: Dimensions = { ...form.dimensions }
+                  dimensions[dim] = e.target.value === '' ? undefined : Number(e.target.value)
+                  setForm({ ...form, dimensions })
+                }}
+                style={{ marginTop: 8, marginBottom: 12 }}
+              />
+            </div>
+          ))}
+          <div style={{ flex: 1, minWidth: 80 }}>
+            <label>Unidad</label>
+            <select
+              value={form.dimensions?.unit || 'cm'}
+              onChange={(e) =>
+                setForm({ ...form, dimensions: { ...form.dimensions, unit: e.target.value as 'cm' | 'in' } })
+              }
+              style={{ marginTop: 8, marginBottom: 12 }}
+            >
+              <option value="cm">cm</option>
+              <option value="in">in</option>
+            </select>
+          </div>
+        </div>
+
+        <div className="row">
+          <div style={{ flex: 1, minWidth: 200 }}>
+            <label>Disponibilidad</label>
+            <select
+              value={form.availability}
+              onChange={(e) => setForm({ ...form, availability: e.target.value as Availability | '' })}
+              style={{ marginTop: 8, marginBottom: 12 }}
+            >
+              <option value="">Sin definir</option>
+              <option value="available">Disponible</option>
+              <option value="reserved">Reservada</option>
+              <option value="sold">Vendida</option>
+              <option value="not_for_sale">No a la venta</option>
+              <option value="private_collection">Coleccion privada</option>
+            </select>
+          </div>
+          <div style={{ flex: 1, minWidth: 140 }}>
+            <label>Precio</label>
+            <input
+              type="number"
+              min={0}
+              step="0.01"
+              value={form.price?.amount ?? ''}
+              onChange={(e) =>
+                setForm({
+                  ...form,
+                  price:
+                    e.target.value === ''
+                      ? null
+                      : { amount: e.target.value, currency: form.price?.currency || 'EUR' },
+                })
+              }
+              style={{ marginTop: 8, marginBottom: 12 }}
+            />
+          </div>
+          <div style={{ flex: 1, minWidth: 100 }}>
+            <label>Moneda</label>
+            <input
+              value={form.price?.currency || 'EUR'}
+              onChange={(e) =>
+                form.price && setForm({ ...form, price: { ...form.price, currency: e.target.value.toUpperCase() } })
+              }
+              maxLength={3}
+              disabled={!form.price}
+              style={{ marginTop: 8, marginBottom: 12 }}
+            />
+          </div>
+        </div>
+
         <div style={{ marginTop: 16 }}>
           <label>Detalle (explicacion general)</label>
           <textarea
@@ -407,16 +609,60 @@ export default function ArtworkEditPage() {
           />
         </div>
 
+      </div>
+
+      {/* Bitacora: entradas fechadas, se guardan una a una */}
+      <div className="card" style={{ marginTop: 16, padding: 16 }}>
+        <label>Bitacora</label>
+        {(artwork?.bitacoraEntries || []).map((saved) => {
+          const entry = (saved.id && entryDrafts[saved.id]) || saved
+          const edit = (changes: Partial<BitacoraEntry>) =>
+            saved.id && setEntryDrafts({ ...entryDrafts, [saved.id]: { ...entry, ...changes } })
+          return (
+            <div key={saved.id} style={{ marginTop: 12 }}>
+              <div className="row" style={{ alignItems: 'center', gap: 10 }}>
+                <input
+                  value={entry.date}
+                  onChange={(e) => edit({ date: e.target.value })}
+                  placeholder="2025-12-01"
+                  style={{ maxWidth: 160 }}
+                />
+                <button className="btn" onClick={() => saveEntry(entry)} disabled={!saved.id || !entryDrafts[saved.id]}>
+                  Guardar entrada
+                </button>
+                <button className="btn" onClick={() => saved.id && deleteEntry(saved.id)}>
+                  Eliminar
+                </button>
+              </div>
+              <textarea
+                value={entry.text}
+                onChange={(e) => edit({ text: e.target.value })}
+                rows={4}
+                style={{ marginTop: 8 }}
+              />
+            </div>
+          )
+        })}
         <div style={{ marginTop: 16 }}>
-          <label>Bitacora</label>
+          <div className="row" style={{ alignItems: 'center', gap: 10 }}>
+            <input
+              value={newEntry.date}
+              onChange={(e) => setNewEntry({ ...newEntry, date: e.target.value })}
+              placeholder="Fecha (YYYY-MM-DD)"
+              style={{ maxWidth: 160 }}
+            />
+            <button className="btn btn-primary" onClick={() => saveEntry(newEntry)} disabled={!newEntry.date || !newEntry.text.trim()}>
+              Agregar entrada
+            </button>
+          </div>
           <textarea
-            value={form.bitacora}
-            onChange={(e) => setForm({ ...form, bitacora: e.target.value })}
-            rows={8}
+            value={newEntry.text}
+            onChange={(e) => setNewEntry({ ...newEntry, text: e.target.value })}
+            placeholder="Nueva entrada de la bitacora"
+            rows={4}
             style={{ marginTop: 8 }}
           />
         </div>
-      </div>
     </div>
   )
 }
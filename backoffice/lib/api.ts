@@ -4,14 +4,40 @@ export type Artwork = {
   images: string[]
   videos?: string[]
   detalle?: string
-  bitacora?: string
+  bitacoraEntries?: BitacoraEntry[]
   paintedLocation?: string
   startDate?: string
   endDate?: string
   inProgress?: boolean
   primaryImage?: string
+  status?: 'draft' | 'published' | 'archived'
+  publishAt?: string
+  technique?: string
+  dimensions?: Dimensions
+  price?: Price
+  availability?: Availability | ''
 }
 
+// One dated step of the making of an artwork, oldest first. Edited through
+// /api/v1/admin/artworks/{id}/bitacora, not through AdminUpdate.
+export type BitacoraEntry = {
+  id?: number
+  date: string
+  text: string
+  media?: { file: string; type: 'image' | 'video'; url: string }[]
+}
+
+export type Dimensions = { height?: number; width?: number; depth?: number; unit?: 'cm' | 'in' }
+
+// amount is a decimal string with two decimals, e.g. "1234.10".
+export type Price = { amount: string; currency: string }
+
+export type Availability = 'available' | 'reserved' | 'sold' | 'not_for_sale' | 'private_collection'
+
+export type FieldError = { field: string; code: string; message: string }
+
+export type ErrorResponse = { error: string; fields?: FieldError[] }
+
 export type ArtworkListResponse = { artworks: Artwork[]; total: number }
 
 export type AdminUpdate = {
@@ -21,8 +47,11 @@ export type AdminUpdate = {
   endDate: string
   inProgress: boolean
   detalle: string
-  bitacora: string
   primaryImage: string
+  technique: string
+  dimensions: Dimensions | null
+  price: Price | null
+  availability: Availability | ''
 }
 
 export type AdminCreate = {
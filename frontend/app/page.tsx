@@ -17,10 +17,18 @@ export interface Artwork {
   startDate?: string
   endDate?: string
   inProgress?: boolean
-  bitacora?: string
+  bitacoraEntries?: BitacoraEntry[]
   primaryImage?: string
 }
 
+// One dated step of the making of an artwork, oldest first.
+export interface BitacoraEntry {
+  id?: number
+  date: string
+  text: string
+  media?: { file: string; type: 'image' | 'video'; url: string }[]
+}
+
 const HERO_ARTWORK_IDS = ['ciego', 'cisne', 'eland', 'indigenas', 'indio'] as const
 
 export default function Home() {
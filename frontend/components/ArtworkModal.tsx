@@ -219,12 +219,22 @@ export default function ArtworkModal({ artwork, onClose }: ArtworkModalProps) {
             </div>
           ) : null}
 
-          {artwork.bitacora && (
+          {artwork.bitacoraEntries && artwork.bitacoraEntries.length > 0 && (
             <div className={styles.bitacora}>
               <h3 className={styles.bitacoraTitle}>{t.artwork.bitacoraTitle}</h3>
-              <div className={styles.bitacoraContent}>
-                {artwork.bitacora}
-              </div>
+              {artwork.bitacoraEntries.map((entry, index) => (
+                <div key={entry.id ?? index} className={styles.bitacoraEntry}>
+                  <div className={styles.bitacoraDate}>{entry.date}</div>
+                  <div className={styles.bitacoraContent}>{entry.text}</div>
+                  {(entry.media || []).map((m) =>
+                    m.type === 'image' ? (
+                      <img key={m.file} src={m.url} alt={m.file} className={styles.bitacoraMedia} />
+                    ) : (
+                      <video key={m.file} src={m.url} controls className={styles.bitacoraMedia} />
+                    )
+                  )}
+                </div>
+              ))}
             </div>
           )}
         </div>
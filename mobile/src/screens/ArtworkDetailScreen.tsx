@@ -74,10 +74,25 @@ const ArtworkDetailScreen = () => {
         ))}
       </View>
 
-      {artwork.bitacora && (
+      {artwork.bitacoraEntries && artwork.bitacoraEntries.length > 0 && (
         <View style={styles.bitacora}>
           <Text style={styles.bitacoraTitle}>Bitácora</Text>
-          <Text style={styles.bitacoraContent}>{artwork.bitacora}</Text>
+          {artwork.bitacoraEntries.map((entry, index) => (
+            <View key={entry.id ?? index} style={styles.bitacoraEntry}>
+              <Text style={styles.bitacoraDate}>{entry.date}</Text>
+              <Text style={styles.bitacoraContent}>{entry.text}</Text>
+              {(entry.media || [])
+                .filter(m => m.type === 'image')
+                .map(m => (
+                  <Image
+                    key={m.file}
+                    source={{uri: getImageUrl(artwork.id, m.file)}}
+                    style={styles.bitacoraImage}
+                    resizeMode="contain"
+                  />
+                ))}
+            </View>
+          ))}
         </View>
       )}
     </ScrollView>
@@ -132,6 +147,21 @@ const styles = StyleSheet.create({
     lineHeight: 24,
     color: '#666',
   },
+  bitacoraEntry: {
+    marginBottom: 20,
+  },
+  bitacoraDate: {
+    fontSize: 14,
+    fontWeight: '600',
+    color: '#1a1a1a',
+    marginBottom: 6,
+  },
+  bitacoraImage: {
+    width: width - 70,
+    height: width - 70,
+    marginTop: 10,
+    borderRadius: 8,
+  },
 });
 
 export default ArtworkDetailScreen;
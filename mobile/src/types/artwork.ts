@@ -3,7 +3,15 @@ export interface Artwork {
   title: string;
   images: string[];
   videos?: string[];
-  bitacora?: string;
+  bitacoraEntries?: BitacoraEntry[];
+}
+
+// One dated step of the making of an artwork, oldest first.
+export interface BitacoraEntry {
+  id?: number;
+  date: string;
+  text: string;
+  media?: {file: string; type: 'image' | 'video'; url: string}[];
 }
 
 export interface ArtworkListResponse {